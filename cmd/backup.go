@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/backup"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/util"
+	"github.com/markkurossi/tabulate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Back up and restore the vault",
+		Long:  "Back up and restore the vault. Backups are checksummed archives of the vault files, stored in backup.dir of the configuration, $XDG_DATA_HOME/enpass/backups by default.",
+	}
+	backupCreateCmd = &cobra.Command{
+		Use:          "create",
+		Short:        "Back up the vault",
+		Long:         "Back up vault.enpassdb, vault.json and the attachments of the vault into a new archive. The backup fails while Enpass is writing to the vault.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          backupCreateRunCmd,
+		SilenceUsage: true,
+	}
+	backupListCmd = &cobra.Command{
+		Use:          "list",
+		Short:        "List the backups of the vault",
+		Long:         "List the backups of the vault, newest first.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          backupListRunCmd,
+		SilenceUsage: true,
+	}
+	backupRestoreCmd = &cobra.Command{
+		Use:          "restore BACKUP",
+		Short:        "Restore the vault from a backup",
+		Long:         "Restore the vault from BACKUP, a path or the name of a backup as shown by backup list. The backup is checked and opened with the credentials of the vault before anything is replaced, and the current vault is backed up first.",
+		Args:         cobra.ExactArgs(1),
+		PreRun:       vaultPreRunCmd,
+		Run:          backupRestoreRunCmd,
+		SilenceUsage: true,
+	}
+	backupPruneCmd = &cobra.Command{
+		Use:          "prune",
+		Short:        "Remove the backups the retention rules do not keep",
+		Long:         "Remove the backups of the vault the retention rules do not keep. The rules are backup.keep_last, backup.keep_daily and backup.keep_weekly of the configuration, 7 daily and 4 weekly backups when none is set. A backup is kept when any rule keeps it.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          backupPruneRunCmd,
+		SilenceUsage: true,
+	}
+	flagBackupAll        bool
+	flagBackupDryRun     bool
+	flagBackupKeepDaily  int
+	flagBackupKeepLast   int
+	flagBackupKeepWeekly int
+)
+
+func init() {
+	GetBackupListFlags(backupListCmd)
+	GetBackupPruneFlags(backupPruneCmd)
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupRestoreCmd, backupPruneCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+func backupCreateRunCmd(cmd *cobra.Command, args []string) {
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	path, err := backup.Create(vaultPath, backupDir())
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	fmt.Println(path)
+}
+
+func backupListRunCmd(cmd *cobra.Command, args []string) {
+	archives := listBackups()
+	if len(archives) == 0 {
+		logger.Infof("no backups in %s", backupDir())
+		return
+	}
+
+	tab := tabulate.New(tabulate.Simple)
+	for _, header := range []string{"name", "vault", "created", "size"} {
+		tab.Header(header).SetAlign(tabulate.ML)
+	}
+	for _, archive := range archives {
+		row := tab.Row()
+		row.Column(archive.Name())
+		row.Column(archive.Vault)
+		row.Column(archive.Created.Local().Format(time.RFC3339))
+		row.Column(fmt.Sprintf("%d", archive.Size))
+	}
+	tab.Print(os.Stdout)
+}
+
+func backupRestoreRunCmd(cmd *cobra.Command, args []string) {
+	if flagEnablePin {
+		logger.Error("restoring a backup requires the vault password, not the PIN")
+		logger.Exit(2)
+	}
+
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	path, err := backup.Resolve(args[0], backupDir())
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	keyFilePath := flagKeyFilePath
+	if keyFilePath == "" && globals.GetConfig().KeyFile != "" {
+		keyFilePath = util.ExpandPath(globals.GetConfig().KeyFile)
+	}
+	credentials := enpass.AssembleVaultCredentials(logger, vaultPath, keyFilePath, flagNonInteractive, nil)
+	current, err := backup.Restore(path, vaultPath, backupDir(), backup.CheckVault(credentials, logLevel, flagNoColor))
+	if current != "" {
+		logger.Infof("backed up the current vault to %s", current)
+	}
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	fmt.Printf("Restored %s from %s\n", vaultPath, path)
+}
+
+func backupPruneRunCmd(cmd *cobra.Command, args []string) {
+	config := globals.GetConfig().Backup
+	policy := backup.Policy{KeepLast: config.KeepLast, KeepDaily: config.KeepDaily, KeepWeekly: config.KeepWeekly}
+	if policy.IsZero() {
+		policy = backup.DefaultPolicy
+	}
+	if cmd.Flags().Changed("keep-last") {
+		policy.KeepLast = flagBackupKeepLast
+	}
+	if cmd.Flags().Changed("keep-daily") {
+		policy.KeepDaily = flagBackupKeepDaily
+	}
+	if cmd.Flags().Changed("keep-weekly") {
+		policy.KeepWeekly = flagBackupKeepWeekly
+	}
+	if policy.IsZero() {
+		logger.Error("the retention rules keep no backup, refusing to remove them all")
+		logger.Exit(2)
+	}
+
+	// Each vault keeps its own backups
+	byVault := map[string][]backup.Archive{}
+	vaults := []string{}
+	for _, archive := range listBackups() {
+		if _, ok := byVault[archive.Vault]; !ok {
+			vaults = append(vaults, archive.Vault)
+		}
+		byVault[archive.Vault] = append(byVault[archive.Vault], archive)
+	}
+
+	removed := 0
+	for _, vault := range vaults {
+		_, remove := backup.Prune(byVault[vault], policy)
+		for _, archive := range remove {
+			if flagBackupDryRun {
+				fmt.Printf("would remove %s\n", archive.Path)
+				continue
+			}
+			if err := os.Remove(archive.Path); err != nil {
+				logger.Errorf("failed to remove %s: %s", archive.Path, err)
+				logger.Exit(2)
+			}
+			logger.Debugf("removed %s", archive.Path)
+			removed++
+		}
+	}
+	if !flagBackupDryRun {
+		fmt.Printf("Removed %d backups\n", removed)
+	}
+}
+
+// listBackups : the backups of the vault, or of every vault with --all
+func listBackups() []backup.Archive {
+	vault := ""
+	if !flagBackupAll {
+		vault = backup.VaultName(enpass.DetermineVaultPath(logger, flagVaultPath))
+	}
+	archives, err := backup.List(backupDir(), vault)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	return archives
+}
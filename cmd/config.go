@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/dsl"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/output"
+	"github.com/gdanko/enpass/util"
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+	"gopkg.in/yaml.v3"
+)
+
+const maskedValue = "********"
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect, validate and edit the configuration file",
+		Long:  "Inspect, validate and edit the configuration file",
+	}
+	configShowCmd = &cobra.Command{
+		Use:          "show",
+		Short:        "Print the effective configuration, after the profile and environment overrides",
+		Long:         "Print the effective configuration, after the profile and environment overrides. Passwords are masked.",
+		Args:         cobra.NoArgs,
+		PreRun:       configPreRunCmd,
+		Run:          configShowRunCmd,
+		SilenceUsage: true,
+	}
+	configValidateCmd = &cobra.Command{
+		Use:          "validate",
+		Short:        "Check the configuration file for unknown keys and invalid values",
+		Long:         "Check the configuration file for unknown keys and invalid values",
+		Args:         cobra.NoArgs,
+		PreRun:       configPreRunCmd,
+		Run:          configValidateRunCmd,
+		SilenceUsage: true,
+	}
+	configSetCmd = &cobra.Command{
+		Use:          "set KEY VALUE",
+		Short:        "Set a key in the configuration file",
+		Long:         "Set a key in the configuration file, e.g. colors.key_color or profiles.work.vault_path. Lists are comma-separated.",
+		Args:         cobra.ExactArgs(2),
+		PreRun:       configPreRunCmd,
+		Run:          configSetRunCmd,
+		SilenceUsage: true,
+	}
+	configPathCmd = &cobra.Command{
+		Use:          "path",
+		Short:        "Print the path of the configuration file",
+		Long:         "Print the path of the configuration file",
+		Args:         cobra.NoArgs,
+		PreRun:       configPreRunCmd,
+		Run:          configPathRunCmd,
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	configCmd.AddCommand(configShowCmd, configValidateCmd, configSetCmd, configPathCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func configPreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func configShowRunCmd(cmd *cobra.Command, args []string) {
+	enpassConfig := globals.GetConfig()
+	if enpassConfig.VaultPassword != "" {
+		enpassConfig.VaultPassword = maskedValue
+	}
+	if len(enpassConfig.Profiles) > 0 {
+		profiles := map[string]globals.Profile{}
+		for name, profile := range enpassConfig.Profiles {
+			if profile.VaultPassword != "" {
+				profile.VaultPassword = maskedValue
+			}
+			profiles[name] = profile
+		}
+		enpassConfig.Profiles = profiles
+	}
+
+	data, err := yaml.Marshal(enpassConfig)
+	if err != nil {
+		logger.Errorf("failed to render the configuration, %s", err)
+		logger.Exit(2)
+	}
+	fmt.Print(string(data))
+}
+
+func configValidateRunCmd(cmd *cobra.Command, args []string) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		logger.Errorf("failed to read the config file %s: %s", configPath, err)
+		logger.Exit(2)
+	}
+
+	problems := validateConfigData(data)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", configPath, problem)
+		}
+		logger.Exit(1)
+	}
+	fmt.Printf("%s is valid\n", configPath)
+}
+
+func configSetRunCmd(cmd *cobra.Command, args []string) {
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Errorf("failed to read the config file %s: %s", configPath, err)
+		logger.Exit(2)
+	}
+
+	updated, err := util.SetConfigKey(data, args[0], args[1])
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	// Refuse to write a file that would not validate
+	if problems := validateConfigData(updated); len(problems) > 0 {
+		for _, problem := range problems {
+			logger.Error(problem)
+		}
+		logger.Exit(2)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		logger.Errorf("failed to create the directory of %s: %s", configPath, err)
+		logger.Exit(2)
+	}
+
+	// Write to a temporary file first so an interrupted write never truncates the configuration
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), ".enpass-config-*")
+	if err != nil {
+		logger.Errorf("failed to write the config file %s: %s", configPath, err)
+		logger.Exit(2)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(updated); err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), configPath)
+	}
+	if err != nil {
+		logger.Errorf("failed to write the config file %s: %s", configPath, err)
+		logger.Exit(2)
+	}
+	logger.Debugf("set %s in %s", args[0], configPath)
+}
+
+func configPathRunCmd(cmd *cobra.Command, args []string) {
+	fmt.Println(configPath)
+}
+
+// validateConfigData : return every problem found in the YAML configuration
+func validateConfigData(data []byte) []string {
+	var enpassConfig globals.EnpassConfig
+	if err := yaml.Unmarshal(data, &enpassConfig); err != nil {
+		return []string{fmt.Sprintf("failed to parse: %s", err)}
+	}
+
+	problems := []string{}
+	for _, key := range util.UnknownConfigKeys(data) {
+		problems = append(problems, fmt.Sprintf("unknown key %q", key))
+	}
+
+	sections := map[string]globals.Profile{
+		"": {
+			Colors:      enpassConfig.Colors,
+			OrderBy:     enpassConfig.OrderBy,
+			OutputStyle: enpassConfig.OutputStyle,
+		},
+	}
+	for name, profile := range enpassConfig.Profiles {
+		sections[fmt.Sprintf("profiles.%s.", name)] = profile
+	}
+
+	for prefix, section := range sections {
+		for key, value := range map[string]string{
+			"colors.alias_color":  section.Colors.AliasColor,
+			"colors.anchor_color": section.Colors.AnchorColor,
+			"colors.bool_color":   section.Colors.BoolColor,
+			"colors.key_color":    section.Colors.KeyColor,
+			"colors.null_color":   section.Colors.NullColor,
+			"colors.number_color": section.Colors.NumberColor,
+			"colors.string_color": section.Colors.StringColor,
+		} {
+			if value != "" && !output.IsValidColor(value) {
+				problems = append(problems, fmt.Sprintf("%s%s: invalid color %q, valid: %s", prefix, key, value, strings.Join(output.ColorNames(), ", ")))
+			}
+		}
+		problems = append(problems, validateOrderBy(prefix+"orderby", section.OrderBy)...)
+		if section.OutputStyle != "" && !funk.ContainsString(output.Names(), section.OutputStyle) {
+			problems = append(problems, fmt.Sprintf("%soutput_style: invalid style %q, valid: %s", prefix, section.OutputStyle, strings.Join(output.Names(), ", ")))
+		}
+	}
+
+	for command, fields := range enpassConfig.Fields {
+		if !funk.ContainsString(output.FieldCommands, command) {
+			problems = append(problems, fmt.Sprintf("fields.%s: unknown command, valid: %s", command, strings.Join(output.FieldCommands, ", ")))
+		} else if err := output.ValidateFields(command, fields); err != nil {
+			problems = append(problems, fmt.Sprintf("fields.%s: %s", command, err))
+		}
+	}
+
+	for name, text := range enpassConfig.Templates {
+		if _, err := output.ParseTemplate(text); err != nil {
+			problems = append(problems, fmt.Sprintf("templates.%s: %s", name, err))
+		}
+	}
+
+	if enpassConfig.DefaultProfile != "" {
+		if _, ok := enpassConfig.Profiles[enpassConfig.DefaultProfile]; !ok {
+			problems = append(problems, fmt.Sprintf("default_profile: the profile %q is not defined", enpassConfig.DefaultProfile))
+		}
+	}
+
+	for name, search := range enpassConfig.Searches {
+		prefix := fmt.Sprintf("searches.%s.", name)
+		problems = append(problems, validateOrderBy(prefix+"orderby", search.OrderBy)...)
+		if search.Match != "" && !funk.ContainsString(enpass.ValidMatchModes, search.Match) {
+			problems = append(problems, fmt.Sprintf("%smatch: invalid match mode %q, valid: %s", prefix, search.Match, strings.Join(enpass.ValidMatchModes, ", ")))
+		}
+		if search.Query != "" {
+			if _, err := dsl.Compile(search.Query, false); err != nil {
+				problems = append(problems, fmt.Sprintf("%squery: %s", prefix, err))
+			}
+		}
+	}
+
+	for key, value := range map[string]int{
+		"backup.keep_daily":  enpassConfig.Backup.KeepDaily,
+		"backup.keep_last":   enpassConfig.Backup.KeepLast,
+		"backup.keep_weekly": enpassConfig.Backup.KeepWeekly,
+	} {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s: must not be negative", key))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+func validateOrderBy(key string, fields []string) []string {
+	problems := []string{}
+	for _, field := range fields {
+		if !funk.ContainsString(validOrderBy, field) {
+			problems = append(problems, fmt.Sprintf("%s: invalid field %q, valid: %s", key, field, strings.Join(validOrderBy, ", ")))
+		}
+	}
+	return problems
+}
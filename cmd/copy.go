@@ -7,12 +7,13 @@ import (
 	"github.com/gdanko/enpass/pkg/clipboard"
 	"github.com/gdanko/enpass/pkg/enpass"
 	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
 	copyCmd = &cobra.Command{
-		Use:          "copy",
+		Use:          "copy [@search] [query]",
 		Short:        "Copy the password of a vault entry to the clipboard",
 		Long:         "Copy the password of a vault entry to the clipboard",
 		PreRun:       copyPreRunCmd,
@@ -32,6 +33,11 @@ func copyPreRunCmd(cmd *cobra.Command, args []string) {
 }
 
 func copyRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, false) {
+		return
+	}
+
 	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
 	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
 	if err != nil {
@@ -47,10 +53,16 @@ func copyRunCmd(cmd *cobra.Command, args []string) {
 		logger.Exit(2)
 	}
 	logger.Debug("opened vault")
+	vault.MatchMode = flagMatchMode
+	vault.Query = queryFromArgs(args)
+	vault.Search = flagSearch
 
-	card, err := vault.GetEntry(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, true)
+	card, err := getUniqueEntry(vault)
 	if err != nil {
 		logger.Error(err)
+		if errors.Is(err, enpass.ErrCardNotFound) {
+			logSuggestions(vault)
+		}
 		logger.Exit(2)
 	}
 
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/gdanko/enpass/pkg/diff"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffCmd = &cobra.Command{
+		Use:          "diff --vault A [--vault B]",
+		Short:        "Compare two vaults or backups",
+		Long:         "Compare the items of the vault A with those of the vault B, the current vault when only one --vault is given. Either may be a backup archive. Items are matched by uuid and reported as added, removed, trashed, restored, renamed, moved to other folders or changed. Secret values are compared by hash and only shown with --reveal. Both vaults are opened with the same credentials, the password of B is prompted for when they do not open it.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          diffRunCmd,
+		SilenceUsage: true,
+	}
+	flagDiffOutput string
+	flagDiffReveal bool
+	flagDiffVaults []string
+)
+
+func init() {
+	GetDiffFlags(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
+
+func diffRunCmd(cmd *cobra.Command, args []string) {
+	checkReportOutput(flagDiffOutput)
+
+	paths := []string{}
+	for _, path := range flagDiffVaults {
+		paths = append(paths, enpass.DetermineVaultPath(logger, path))
+	}
+	switch len(paths) {
+	case 1:
+		paths = append(paths, enpass.DetermineVaultPath(logger, ""))
+	case 2:
+	default:
+		logger.Error("give the vaults to compare with --vault A --vault B, or --vault A to compare with the current vault")
+		logger.Exit(2)
+	}
+
+	vaultA, credentials, err := enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, paths[0], flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	defer vaultA.Close()
+	// Keep the credentials before A derives its key from them, B may have another salt
+	credentialsB := *credentials
+	if err := vaultA.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Errorf("%s: %s", paths[0], err)
+		logger.Exit(2)
+	}
+
+	vaultB := openOtherVault(paths[1], credentialsB, "", "")
+	defer vaultB.Close()
+
+	report := diff.Report{
+		From:    paths[0],
+		To:      paths[1],
+		Changes: diff.Compare(diffSide(vaultA, paths[0]), diffSide(vaultB, paths[1]), flagDiffReveal),
+	}
+
+	printReport(flagDiffOutput, report, func(w io.Writer) error {
+		return diff.WriteText(w, report)
+	})
+}
+
+// diffSide : every item of the vault, trashed ones included, and its folders
+func diffSide(vault *enpass.Vault, path string) diff.Vault {
+	items, err := vault.GetItems([]string{}, []string{}, []string{}, []string{}, []string{}, false, true, []string{}, validOrderBy)
+	if err != nil {
+		logger.Errorf("%s: %s", path, err)
+		logger.Exit(2)
+	}
+	folders, err := vault.GetFolders()
+	if err != nil {
+		logger.Errorf("%s: %s", path, err)
+		logger.Exit(2)
+	}
+	return diff.Vault{Items: items, Folders: folders}
+}
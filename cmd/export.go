@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/export"
+	"github.com/gdanko/enpass/pkg/picker"
+	"github.com/gdanko/enpass/util"
+	"github.com/miquella/ask"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:          "export [@search] [query]",
+		Short:        "Export whole vault items to a file",
+		Long:         "Export whole vault items, with every field, to a file or stdout. Secrets are only exported with --include-secrets, except to encrypted formats and with --encrypt where they are exported unless --include-secrets=false is given.",
+		PreRun:       exportPreRunCmd,
+		Run:          exportRunCmd,
+		SilenceUsage: true,
+	}
+	flagExportEncrypt bool
+	flagExportFile    string
+	flagExportFormat  string
+	flagExportKeyFile string
+)
+
+func init() {
+	GetExportFlags(exportCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+func exportPreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func exportRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, true) {
+		return
+	}
+
+	exporter, err := export.Lookup(flagExportFormat)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	exportOptions := export.Options{IncludeSecrets: flagIncludeSecrets}
+	// An encrypted export is a copy of the vault, secrets are left out only on request
+	if (export.IsEncrypted(flagExportFormat) || flagExportEncrypt) && !cmd.Flags().Changed("include-secrets") {
+		exportOptions.IncludeSecrets = true
+	}
+	if flagExportEncrypt && !export.CanEncrypt(flagExportFormat) {
+		logger.Errorf("the %s format cannot be encrypted", flagExportFormat)
+		logger.Exit(2)
+	}
+	if flagExportKeyFile != "" && flagExportFormat != "kdbx" {
+		logger.Error("--kdbx-keyfile only applies to the kdbx format")
+		logger.Exit(2)
+	}
+	if export.IsBinary(flagExportFormat) && flagExportFile == "" && picker.IsTerminal(os.Stdout) {
+		logger.Errorf("the %s format is binary, write it to a file with --output", flagExportFormat)
+		logger.Exit(2)
+	}
+	if export.IsEncrypted(flagExportFormat) || flagExportEncrypt {
+		exportOptions.Password, exportOptions.KeyFile = exportKey()
+	}
+
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	defer func() {
+		vault.Close()
+	}()
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("opened vault")
+	vault.MatchMode = flagMatchMode
+	vault.Query = queryFromArgs(args)
+	vault.Search = flagSearch
+
+	items, err := vault.GetItems(flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagTrashed, flagOrderBy, validOrderBy)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	if len(items) <= 0 {
+		logSuggestions(vault)
+	}
+	warnAttachments(items)
+
+	folders, err := vault.GetFolders()
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	exportOptions.Folders = folders
+	if flagExportFile != "" && flagExportFile != "-" {
+		err = writeExportFile(exporter, util.ExpandPath(flagExportFile), items, exportOptions)
+	} else {
+		err = exporter.Export(os.Stdout, items, exportOptions)
+	}
+	if err != nil {
+		logger.Errorf("failed to export the items: %s", err)
+		logger.Exit(2)
+	}
+	logger.Debugf("exported %d items as %s", len(items), flagExportFormat)
+}
+
+// writeExportFile : export the items to a temporary file next to path and rename it over path once the export
+// succeeded, a failed export leaves path as it was. The temporary file is private to the user, exports may hold
+// secrets.
+func writeExportFile(exporter export.Exporter, path string, items []enpass.Item, opts export.Options) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".enpass-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = exporter.Export(tmp, items, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// warnAttachments : warn about every item whose attachments are left out of the export. Only the kdbx format
+// keeps attachments, and only those stored in the vault itself.
+func warnAttachments(items []enpass.Item) {
+	for _, item := range items {
+		left := 0
+		for _, attachment := range item.Attachments {
+			if flagExportFormat != "kdbx" || attachment.External {
+				left++
+			}
+		}
+		if left > 0 {
+			logger.Warningf("%q is exported without %d of its attachments", item.Title, left)
+		}
+	}
+}
+
+// exportKey : the password and key file protecting an encrypted export. The password comes from
+// $ENPASS_EXPORT_PASSWORD or is prompted for twice, it may be empty when a key file is given.
+func exportKey() (string, []byte) {
+	var keyFile []byte
+	if flagExportKeyFile != "" {
+		var err error
+		if keyFile, err = os.ReadFile(util.ExpandPath(flagExportKeyFile)); err != nil {
+			logger.Errorf("failed to read the key file %s: %s", flagExportKeyFile, err)
+			logger.Exit(2)
+		}
+	}
+
+	password := newPassword("ENPASS_EXPORT_PASSWORD", "the password of the export")
+
+	if password == "" && len(keyFile) <= 0 {
+		if flagExportFormat == "kdbx" {
+			logger.Errorf("the %s format requires a password ($ENPASS_EXPORT_PASSWORD) or --kdbx-keyfile", flagExportFormat)
+		} else {
+			logger.Errorf("the %s format requires a password ($ENPASS_EXPORT_PASSWORD)", flagExportFormat)
+		}
+		logger.Exit(2)
+	}
+
+	return password, keyFile
+}
+
+// newPassword : a new password, read from the environment variable or prompted for twice. It is empty when the
+// variable is not set and prompts are disabled.
+func newPassword(envName, what string) string {
+	if password, ok := os.LookupEnv(envName); ok {
+		return password
+	}
+	if flagNonInteractive {
+		return ""
+	}
+
+	password, err := ask.HiddenAsk(fmt.Sprintf("Enter %s: ", what))
+	if err != nil {
+		logger.Errorf("could not prompt for %s: %s", what, err)
+		logger.Exit(2)
+	}
+	confirmation, err := ask.HiddenAsk(fmt.Sprintf("Repeat %s: ", what))
+	if err != nil {
+		logger.Errorf("could not prompt for %s: %s", what, err)
+		logger.Exit(2)
+	}
+	if password != confirmation {
+		logger.Error("the passwords do not match")
+		logger.Exit(2)
+	}
+
+	return password
+}
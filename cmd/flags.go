@@ -5,6 +5,10 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/export"
+	"github.com/gdanko/enpass/pkg/merge"
+	"github.com/gdanko/enpass/pkg/output"
 	"github.com/gdanko/enpass/util"
 	"github.com/spf13/cobra"
 )
@@ -15,37 +19,130 @@ func GetflagLists(cmd *cobra.Command) {
 
 func GetShowFlags(cmd *cobra.Command) {
 	getListShowFlags(cmd)
+	cmd.Flags().BoolVar(&flagIncludeSecrets, "include-secrets", false, "Include passwords, one-time codes and sensitive fields in the csv and tsv output.")
+}
+
+func GetExportFlags(cmd *cobra.Command) {
+	getQueryFlags(cmd)
+	cmd.Flags().StringVarP(&flagExportFormat, "format", "f", "csv", fmt.Sprintf("The export format. Valid: %s", strings.Join(export.Names(), ", ")))
+	cmd.Flags().StringVarP(&flagExportFile, "output", "o", "", "Write the export to this file instead of stdout. The file is created with mode 0600.")
+	cmd.Flags().BoolVar(&flagIncludeSecrets, "include-secrets", false, "Include passwords, one-time codes and sensitive fields. Defaults to true for encrypted formats and with --encrypt.")
+	cmd.Flags().BoolVar(&flagExportEncrypt, "encrypt", false, "Protect the bitwarden-json export with a password, kdbx exports are always protected.")
+	cmd.Flags().StringVar(&flagExportKeyFile, "kdbx-keyfile", "", "Protect the kdbx export with this KeePass key file, alone or with a password.")
+	cmd.Flags().BoolVar(&flagTrashed, "trashed", false, "Export trashed items.")
+	cmd.Flags().StringArrayVar(&flagOrderBy, "orderby", []string{}, fmt.Sprintf("Specify fields to sort by. Can be used multiple times. Valid: %s", strings.Join(sort.StringSlice(validOrderBy), ", ")))
+}
+
+func GetImportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagImportFormat, "format", "f", "enpass-json", fmt.Sprintf("The format of the file. Valid: %s", strings.Join(export.ImporterNames(), ", ")))
+	cmd.Flags().BoolVar(&flagImportDryRun, "dry-run", false, "With a browser format, report the logins that would be added and skipped without writing the vault.")
+	cmd.Flags().BoolVar(&flagImportShow, "show", false, "Display the values of the fields, like show.")
+	cmd.Flags().BoolVar(&flagIncludeSecrets, "include-secrets", false, "With --show, include passwords, one-time codes and sensitive fields in the csv and tsv output.")
+	cmd.Flags().BoolVar(&flagTrashed, "trashed", false, "Show trashed items.")
+	getOutputFlags(cmd)
+}
+
+func GetVaultCreateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagVaultCreatePath, "path", "", "The directory of the new vault, created when missing. Defaults to --vault.")
+	cmd.Flags().StringVar(&flagVaultCreateName, "name", "", "The name of the new vault. Defaults to the name of the directory.")
+}
+
+func GetVaultRekeyFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&flagVaultKDFIter, "kdf-iter", 0, "The PBKDF2 iterations of the new key. Defaults to the current iterations of the vault.")
+}
+
+func GetKeyfileGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flagKeyfileOutput, "output", "o", "", "Write the keyfile to this file instead of stdout. The file is created with mode 0600 and never overwritten.")
+}
+
+func GetDiffFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&flagDiffVaults, "vault", "v", []string{}, "The vaults to compare, given twice. A single one is compared with the current vault.")
+	cmd.Flags().StringVar(&flagDiffOutput, "output", "text", fmt.Sprintf("The output format. Valid: %s", strings.Join(reportOutputs, ", ")))
+	cmd.Flags().BoolVar(&flagDiffReveal, "reveal", false, "Show the values of changed secret fields.")
+}
+
+func GetMergeFlags(cmd *cobra.Command) {
+	getQueryFlags(cmd)
+	cmd.Flags().StringVar(&flagMergeFrom, "from", "", "The vault to merge items from, a directory or a backup archive.")
+	cmd.Flags().StringVar(&flagMergeInto, "into", "", "The vault to merge items into. Defaults to --vault, then the configured vault.")
+	cmd.Flags().StringVar(&flagMergeKeyFile, "from-keyfile", "", "The keyfile of the --from vault. Defaults to --keyfile when the vault has a keyfile.")
+	cmd.Flags().StringVar(&flagMergeStrategy, "strategy", merge.NewestWins, fmt.Sprintf("How items both vaults hold with different content are merged. Valid: %s", strings.Join(merge.Strategies, ", ")))
+	cmd.Flags().BoolVar(&flagMergeDryRun, "dry-run", false, "Print the plan of the merge without writing the vault.")
+}
+
+func GetVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagVerifyOutput, "output", "text", fmt.Sprintf("The output format. Valid: %s", strings.Join(reportOutputs, ", ")))
+}
+
+func GetBackupListFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagBackupAll, "all", false, "List the backups of every vault, not only the current one.")
+}
+
+func GetBackupPruneFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagBackupAll, "all", false, "Prune the backups of every vault, not only the current one.")
+	cmd.Flags().BoolVar(&flagBackupDryRun, "dry-run", false, "Print the backups that would be removed without removing them.")
+	cmd.Flags().IntVar(&flagBackupKeepLast, "keep-last", 0, "Keep the newest N backups. Overrides backup.keep_last.")
+	cmd.Flags().IntVar(&flagBackupKeepDaily, "keep-daily", 0, "Keep the newest backup of each of the last N days. Overrides backup.keep_daily.")
+	cmd.Flags().IntVar(&flagBackupKeepWeekly, "keep-weekly", 0, "Keep the newest backup of each of the last N weeks. Overrides backup.keep_weekly.")
+}
+
+func getQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagExplain, "explain", false, "Print the SQL statement run for the query and exit.")
 }
 
 func getListShowFlags(cmd *cobra.Command) {
+	getQueryFlags(cmd)
 	cmd.Flags().BoolVar(&flagTrashed, "trashed", false, "Show trashed items.")
 	cmd.Flags().StringArrayVarP(&flagOrderBy, "orderby", "o", []string{}, fmt.Sprintf("Specify fields to sort by. Can be used multiple times. Valid: %s", strings.Join(sort.StringSlice(validOrderBy), ", ")))
+	cmd.Flags().StringVar(&flagFormat, "format", "", "Render each record with a Go template, e.g. '{{.Title}}\\t{{.Subtitle}}', or the name of a template from the configuration.")
+	getOutputFlags(cmd)
+}
+
+func getOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagCsv, "csv", false, "Output one CSV row per record with every field as a column.")
+	cmd.Flags().StringSliceVar(&flagFields, "fields", []string{}, fmt.Sprintf("Comma-separated fields to display, in order. Valid: %s", strings.Join(output.ValidFields, ", ")))
+	cmd.Flags().StringVar(&flagOutput, "output", "", fmt.Sprintf("The output format. Valid: %s", strings.Join(output.Names(), ", ")))
+	cmd.Flags().BoolVar(&flagJson, "json", false, "Output the data as JSON.")
+	cmd.Flags().BoolVar(&flagJsonl, "jsonl", false, "Output the data as JSON Lines, one record per line.")
 	cmd.Flags().BoolVar(&flagList, "list", false, "Output the data as list, similar to SQLite line mode.")
 	cmd.Flags().BoolVar(&flagYaml, "yaml", false, "Output the data as YAML.")
 	cmd.Flags().BoolVar(&flagTable, "table", false, "Output the data as a table.")
+	cmd.Flags().BoolVar(&flagTsv, "tsv", false, "Output one TSV row per record with every field as a column.")
 }
 
 func GetPersistenFlags(cmd *cobra.Command) {
-	cmd.PersistentFlags().StringVarP(&flagVaultPath, "vault", "v", "", "Path to your Enpass vault.")
+	cmd.PersistentFlags().StringVarP(&flagVaultPath, "vault", "v", "", "Path to your Enpass vault, or to a .tar.gz, .tar or .zip archive such as a backup holding one, opened read-only.")
 	cmd.PersistentFlags().StringVar(&flagCardType, "type", "password", "The type of your card. (password, ...)")
 	cmd.PersistentFlags().StringArrayVarP(&flagRecordTitle, "title", "t", []string{}, "Filter based on record title. Wildcards (%) are allowed. Can be used multiple times.")
 	cmd.PersistentFlags().StringArrayVarP(&flagRecordCategory, "category", "c", []string{}, "Filter based on record category. Wildcards (%) are allowed. Can be used multiple times.")
 	cmd.PersistentFlags().StringArrayVarP(&flagRecordLogin, "login", "l", []string{}, "Filter based on record login. Wildcards (%) are allowed. Can be used multiple times.")
 	cmd.PersistentFlags().StringArrayVarP(&flagLabel, "label", "y", []string{}, "Filter based on record field label. Can be used multiple times")
 	cmd.PersistentFlags().StringArrayVarP(&flagRecordUuid, "uuid", "u", []string{}, "Filter based on record uuid. Can be used multiple times.")
-	cmd.PersistentFlags().StringVarP(&flagKeyFilePath, "keyfile", "k", "", "Path to your Enpass vault keyfile.")
+	cmd.PersistentFlags().StringVarP(&flagKeyFilePath, "keyfile", "k", "", "Path to your Enpass vault keyfile, or - (stdin), fd:N (file descriptor), env:NAME (base64 variable), cmd:COMMAND (command output).")
 	cmd.PersistentFlags().StringVar(&logLevelStr, "log", defaultLogLevel, fmt.Sprintf("The log level, one of: %s", util.ReturnLogLevels(logLevelMap)))
 	cmd.PersistentFlags().BoolVarP(&flagNonInteractive, "non-interactive", "n", false, "Disable prompts and fail instead.")
 	cmd.PersistentFlags().BoolVar(&flagCaseSensitive, "sensitive", false, "Force category and title searches to be case-sensitive.")
+	cmd.PersistentFlags().StringVar(&flagMatchMode, "match", enpass.MatchLike, fmt.Sprintf("How the category, title, login and uuid filters match. Valid: %s", strings.Join(enpass.ValidMatchModes, ", ")))
+	cmd.PersistentFlags().StringVar(&flagSearch, "search", "", "Search the title, login, note, URL and non-sensitive fields of every record. Results are ranked by relevance.")
 	cmd.PersistentFlags().BoolVar(&flagNoColor, "nocolor", false, "Disable colorized output and logging.")
 	cmd.PersistentFlags().BoolVarP(&flagEnablePin, "pin", "p", false, "Enable PIN.")
+	cmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "The configuration profile to use. Defaults to $ENPASS_PROFILE, then default_profile.")
 }
 
 func GetCopyFlags(cmd *cobra.Command) {
+	getQueryFlags(cmd)
 	cmd.Flags().BoolVar(&flagClipboardPrimary, "flagClipboardPrimary", false, "Use primary X selection instead of clipboard.")
 	cmd.Flags().StringArrayVarP(&flagOrderBy, "orderby", "o", []string{"title"}, "Specify fields to sort by. Can be used multiple times.")
 }
 
 func GetPassFlags(cmd *cobra.Command) {
+	getQueryFlags(cmd)
 	cmd.Flags().StringArrayVarP(&flagOrderBy, "orderby", "o", []string{"title"}, "Specify fields to sort by. Can be used multiple times.")
 }
+
+func GetSSHAgentFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagSSHAgentSocket, "socket", "", "Path of the agent socket. Defaults to $XDG_RUNTIME_DIR/enpass-agent.sock, or a private directory in the temporary directory.")
+	cmd.Flags().BoolVar(&flagSSHAgentConfirm, "confirm", false, "Ask for confirmation on the terminal before every signature.")
+	cmd.Flags().StringArrayVar(&flagSSHKeyCategory, "key-category", []string{}, "Load keys from items in this category. Wildcards (%) are allowed. Can be used multiple times.")
+	cmd.Flags().StringArrayVar(&flagSSHKeyLabel, "key-label", []string{}, "Load keys from fields with this label. Wildcards (%) are allowed. Can be used multiple times.")
+}
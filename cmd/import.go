@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/export"
+	"github.com/gdanko/enpass/util"
+	"github.com/markkurossi/tabulate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importCmd = &cobra.Command{
+		Use:          "import FILE",
+		Short:        "Add browser passwords to the vault, or display an exported vault",
+		Long:         "Add the logins of a browser password export (chrome-csv, firefox-csv, safari-csv) to the vault, skipping the logins it already holds. Other formats are exported vaults, their entries are displayed like list, or like show with --show. FILE may be - for stdin.",
+		Args:         cobra.ExactArgs(1),
+		PreRun:       importPreRunCmd,
+		Run:          importRunCmd,
+		SilenceUsage: true,
+	}
+	flagImportDryRun bool
+	flagImportFormat string
+	flagImportShow   bool
+)
+
+func init() {
+	GetImportFlags(importCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func importPreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func importRunCmd(cmd *cobra.Command, args []string) {
+	importer, err := export.LookupImporter(flagImportFormat)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	if flagImportDryRun && !export.IsLoginSource(flagImportFormat) {
+		logger.Error("--dry-run only applies to the browser formats")
+		logger.Exit(2)
+	}
+
+	cmdType := "list"
+	if flagImportShow {
+		cmdType = "show"
+	}
+	format, opts := outputOptions(cmd, cmdType)
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(util.ExpandPath(args[0]))
+		if err != nil {
+			logger.Errorf("failed to open %s: %s", args[0], err)
+			logger.Exit(2)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	items, err := importer.Import(r)
+	if err != nil {
+		logger.Errorf("failed to import %s: %s", args[0], err)
+		logger.Exit(2)
+	}
+	logger.Debugf("read %d items from %s", len(items), args[0])
+
+	if export.IsLoginSource(flagImportFormat) {
+		importLogins(items)
+		return
+	}
+
+	labels := flagLabel
+	if len(labels) <= 0 {
+		labels = globals.GetConfig().DefaultLabels
+	}
+
+	cards := []enpass.Card{}
+	for _, card := range enpass.CardsFromItems(items) {
+		if card.Type == flagCardType {
+			cards = append(cards, card)
+		}
+	}
+	cards, err = enpass.FilterCards(cards, flagMatchMode, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, labels, flagCaseSensitive)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	byUUID := map[string]enpass.Item{}
+	for _, item := range items {
+		byUUID[item.UUID] = item
+	}
+	renderCards(func(uuids []string) ([]enpass.Item, error) {
+		selected := []enpass.Item{}
+		for _, uuid := range uuids {
+			selected = append(selected, byUUID[uuid])
+		}
+		return selected, nil
+	}, format, cards, opts)
+}
+
+// importLogins : add the logins that are neither in the vault nor earlier in the file, compared by website and
+// login. With --dry-run only the report is printed.
+func importLogins(items []enpass.Item) {
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	defer func() {
+		vault.Close()
+	}()
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("opened vault")
+
+	existing, err := vault.GetItems([]string{}, []string{}, []string{}, []string{}, []string{}, false, false, []string{}, validOrderBy)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	seen := map[string]bool{}
+	for _, item := range existing {
+		if key := export.LoginKey(item); key != "" {
+			seen[key] = true
+		}
+	}
+
+	added := []enpass.Item{}
+	tab := tabulate.New(tabulate.Simple)
+	for _, header := range []string{"action", "title", "login", "url"} {
+		tab.Header(header).SetAlign(tabulate.ML)
+	}
+	for _, item := range items {
+		action := "add"
+		key := export.LoginKey(item)
+		if key != "" && seen[key] {
+			action = "skip (duplicate)"
+		} else {
+			if key != "" {
+				seen[key] = true
+			}
+			added = append(added, item)
+		}
+		website, _ := item.Field("url")
+		row := tab.Row()
+		row.Column(action)
+		row.Column(item.Title)
+		row.Column(item.Subtitle)
+		row.Column(website.Value)
+	}
+
+	if flagImportDryRun {
+		if len(items) > 0 {
+			tab.Print(os.Stdout)
+		}
+		fmt.Printf("%d to add, %d duplicates\n", len(added), len(items)-len(added))
+		return
+	}
+
+	if err := vault.AddItems(added); err != nil {
+		logger.Errorf("failed to add the logins: %s", err)
+		logger.Exit(2)
+	}
+	fmt.Printf("Added %d logins, skipped %d duplicates\n", len(added), len(items)-len(added))
+}
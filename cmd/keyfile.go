@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/picker"
+	"github.com/gdanko/enpass/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyfileCmd = &cobra.Command{
+		Use:   "keyfile",
+		Short: "Generate Enpass keyfiles",
+		Long:  "Generate Enpass keyfiles",
+	}
+	keyfileGenerateCmd = &cobra.Command{
+		Use:          "generate",
+		Short:        "Generate a new random keyfile",
+		Long:         "Generate a new random keyfile in the Enpass format. Protect a vault with it with vault create --keyfile or vault keyfile add.",
+		Args:         cobra.NoArgs,
+		PreRun:       keyfilePreRunCmd,
+		Run:          keyfileGenerateRunCmd,
+		SilenceUsage: true,
+	}
+	flagKeyfileOutput string
+)
+
+func init() {
+	GetKeyfileGenerateFlags(keyfileGenerateCmd)
+	keyfileCmd.AddCommand(keyfileGenerateCmd)
+	rootCmd.AddCommand(keyfileCmd)
+}
+
+func keyfilePreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func keyfileGenerateRunCmd(cmd *cobra.Command, args []string) {
+	data, err := enpass.GenerateKeyFile()
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	if flagKeyfileOutput == "" || flagKeyfileOutput == "-" {
+		if picker.IsTerminal(os.Stdout) {
+			logger.Warn("the keyfile is a secret, consider writing it to a file with --output")
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	path := util.ExpandPath(flagKeyfileOutput)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Errorf("failed to create the keyfile %s: %s", flagKeyfileOutput, err)
+		logger.Exit(2)
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		logger.Errorf("failed to write the keyfile %s: %s", flagKeyfileOutput, err)
+		logger.Exit(2)
+	}
+	logger.Debugf("wrote the keyfile %s", path)
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"github.com/gdanko/enpass/pkg/enpass"
-	"github.com/gdanko/enpass/pkg/output"
 	"github.com/gdanko/enpass/util"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -10,7 +9,7 @@ import (
 
 var (
 	listCmd = &cobra.Command{
-		Use:          "list",
+		Use:          "list [@search] [query]",
 		Short:        "List vault entries without displaying the password",
 		Long:         "List vault entries without displaying the password",
 		PreRun:       listPreRunCmd,
@@ -31,6 +30,12 @@ func listPreRunCmd(cmd *cobra.Command, args []string) {
 }
 
 func listRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, false) {
+		return
+	}
+	format, opts := outputOptions(cmd, "list")
+
 	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
 	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
 	if err != nil {
@@ -46,12 +51,18 @@ func listRunCmd(cmd *cobra.Command, args []string) {
 		logger.Exit(2)
 	}
 	logger.Debug("opened vault")
+	vault.MatchMode = flagMatchMode
+	vault.Query = queryFromArgs(args)
+	vault.Search = flagSearch
 
 	cards, err := vault.GetEntries(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy)
 	if err != nil {
 		logger.Error(err)
 		logger.Exit(2)
 	}
+	if len(cards) <= 0 {
+		logSuggestions(vault)
+	}
 
-	output.GenerateOutput(logger, "list", flagList, flagTable, flagTrashed, flagYaml, flagNoColor, &cards)
+	renderCards(vault.GetItemsByUUID, format, cards, opts)
 }
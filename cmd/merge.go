@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/backup"
+	"github.com/gdanko/enpass/pkg/diff"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/merge"
+	"github.com/markkurossi/tabulate"
+	"github.com/miquella/ask"
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+)
+
+var (
+	mergeCmd = &cobra.Command{
+		Use:          "merge --from VAULT [--into VAULT] [@search] [query]",
+		Short:        "Merge the items of another vault into the vault",
+		Long:         "Merge the items of the vault --from, or those matching the filters, into the vault --into, the current vault by default. Items are matched by uuid, new items are added with their folders and the items both vaults hold with different content are settled by --strategy. Values are re-encrypted with new item keys of the destination, attachments are not merged. The destination is backed up first, --dry-run only prints the plan. The password of --from is read from $ENPASS_FROM_PASSWORD, else the credentials of the destination are tried, then it is prompted for.",
+		PreRun:       vaultPreRunCmd,
+		Run:          mergeRunCmd,
+		SilenceUsage: true,
+	}
+	flagMergeDryRun   bool
+	flagMergeFrom     string
+	flagMergeInto     string
+	flagMergeKeyFile  string
+	flagMergeStrategy string
+)
+
+func init() {
+	GetMergeFlags(mergeCmd)
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func mergeRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, true) {
+		return
+	}
+	if !funk.ContainsString(merge.Strategies, flagMergeStrategy) {
+		logger.Errorf("invalid strategy %q, valid: %s", flagMergeStrategy, strings.Join(merge.Strategies, ", "))
+		logger.Exit(2)
+	}
+	if flagMergeStrategy == merge.Interactive && !isInteractive() {
+		logger.Errorf("the %s strategy needs a terminal", merge.Interactive)
+		logger.Exit(2)
+	}
+	if flagMergeFrom == "" {
+		logger.Error("give the vault to merge with --from")
+		logger.Exit(2)
+	}
+
+	intoPath := flagMergeInto
+	if intoPath == "" {
+		intoPath = flagVaultPath
+	}
+	intoPath = enpass.DetermineVaultPath(logger, intoPath)
+	fromPath := enpass.DetermineVaultPath(logger, flagMergeFrom)
+	if evalSymlinks(fromPath) == evalSymlinks(intoPath) {
+		logger.Error("--from and --into are the same vault")
+		logger.Exit(2)
+	}
+
+	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, intoPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	defer vault.Close()
+	if vault.IsReadOnly() && !flagMergeDryRun {
+		logger.Error("the destination was opened from an archive, items cannot be merged into it")
+		logger.Exit(2)
+	}
+	// Keep the credentials before the destination derives its key from them, the source has another salt
+	fromCredentials := *credentials
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Errorf("%s: %s", intoPath, err)
+		logger.Exit(2)
+	}
+
+	from := openOtherVault(fromPath, fromCredentials, "ENPASS_FROM_PASSWORD", flagMergeKeyFile)
+	defer from.Close()
+	from.MatchMode = flagMatchMode
+	from.Query = queryFromArgs(args)
+	from.Search = flagSearch
+
+	sourceItems, err := from.GetItems(flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, false, []string{}, validOrderBy)
+	if err != nil {
+		logger.Errorf("%s: %s", fromPath, err)
+		logger.Exit(2)
+	}
+	source := diffSide(from, fromPath)
+	source.Items = sourceItems
+	destination := diffSide(vault, intoPath)
+
+	plan, err := merge.NewPlan(source, destination, flagMergeStrategy, resolveMergeConflict)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	sourceUUIDs := []string{}
+	for _, item := range sourceItems {
+		sourceUUIDs = append(sourceUUIDs, item.UUID)
+	}
+	attachments, err := from.CountAttachments(sourceUUIDs)
+	if err != nil {
+		logger.Errorf("%s: %s", fromPath, err)
+		logger.Exit(2)
+	}
+	if marked := plan.NoteAttachments(attachments); marked > 0 {
+		logger.Warningf("attachments are not merged, %d items are merged without theirs", marked)
+	}
+	printMergePlan(plan)
+
+	if flagMergeDryRun {
+		fmt.Println("Dry run, nothing was written")
+		return
+	}
+	if len(plan.Items()) == 0 {
+		fmt.Println("Nothing to merge")
+		return
+	}
+
+	backupPath, err := backup.Create(intoPath, backupDir())
+	if err != nil {
+		logger.Errorf("failed to back up the vault, nothing was merged: %s", err)
+		logger.Exit(2)
+	}
+	logger.Infof("backed up the vault to %s", backupPath)
+
+	if err := vault.AddFolders(plan.Folders); err != nil {
+		logger.Errorf("%s, the vault can be restored from %s", err, backupPath)
+		logger.Exit(2)
+	}
+	if err := vault.ReplaceItems(plan.Items()); err != nil {
+		logger.Errorf("%s, the vault can be restored from %s", err, backupPath)
+		logger.Exit(2)
+	}
+
+	counts := plan.Count()
+	fmt.Printf("Merged %d items: %d added, %d replaced, %d copied, %d kept, %d unchanged\n",
+		len(plan.Items()), counts[merge.Add], counts[merge.Replace], counts[merge.Copy], counts[merge.Keep], counts[merge.Same])
+}
+
+// printMergePlan : print the actions of the plan and the folders it creates
+func printMergePlan(plan *merge.Plan) {
+	tab := tabulate.New(tabulate.Simple)
+	for _, header := range []string{"action", "title", "uuid", "reason"} {
+		tab.Header(header).SetAlign(tabulate.ML)
+	}
+	for _, action := range plan.Actions {
+		row := tab.Row()
+		row.Column(action.Action)
+		row.Column(action.Title)
+		row.Column(action.UUID)
+		row.Column(action.Reason)
+	}
+	tab.Print(os.Stdout)
+
+	for _, folder := range plan.Folders {
+		fmt.Printf("create folder %q\n", folder.Title)
+	}
+}
+
+// resolveMergeConflict : show how the item of the source differs from the one of the destination and ask what to do
+func resolveMergeConflict(source, destination enpass.Item) (string, error) {
+	fmt.Fprintf(os.Stderr, "\nConflict on %q (%s)\n", source.Title, source.UUID)
+	fmt.Fprintf(os.Stderr, "  destination updated %s\n", time.Unix(destination.Updated, 0).Format(time.RFC3339))
+	fmt.Fprintf(os.Stderr, "  source      updated %s\n", time.Unix(source.Updated, 0).Format(time.RFC3339))
+	changes := diff.Compare(diff.Vault{Items: []enpass.Item{destination}}, diff.Vault{Items: []enpass.Item{source}}, false)
+	diff.WriteText(os.Stderr, diff.Report{From: "destination", To: "source", Changes: changes})
+
+	for {
+		answer, err := ask.Ask("[r]eplace with the source, [k]eep the destination, keep [b]oth? ")
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r", "replace":
+			return merge.Replace, nil
+		case "k", "keep":
+			return merge.Keep, nil
+		case "b", "both":
+			return merge.Copy, nil
+		}
+	}
+}
+
+// evalSymlinks : the path with its symbolic links resolved, the path itself when it does not exist
+func evalSymlinks(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+	"gopkg.in/yaml.v3"
+)
+
+// outputOptions : resolve the output flags before the vault is opened. The format is the first of --output,
+// --json, --jsonl, --list, --table and --yaml, then output_style, then the default format.
+func outputOptions(cmd *cobra.Command, cmdType string) (string, output.Options) {
+	opts := output.Options{
+		CmdType:        cmdType,
+		Fields:         outputFields(cmd, cmdType),
+		IncludeSecrets: flagIncludeSecrets && cmdType == "show",
+		NoColor:        flagNoColor,
+		Template:       outputTemplate(),
+		Trashed:        flagTrashed,
+	}
+
+	selected := []string{}
+	if flagOutput != "" {
+		selected = append(selected, flagOutput)
+	}
+	for _, shortcut := range []struct {
+		name  string
+		value bool
+	}{
+		{"csv", flagCsv},
+		{"json", flagJson},
+		{"jsonl", flagJsonl},
+		{"list", flagList},
+		{"table", flagTable},
+		{"tsv", flagTsv},
+		{"yaml", flagYaml},
+	} {
+		if shortcut.value {
+			selected = append(selected, shortcut.name)
+		}
+	}
+	if len(selected) > 1 {
+		logger.Errorf("only one output format can be selected, got: %s", strings.Join(selected, ", "))
+		logger.Exit(2)
+	}
+
+	format := globals.GetConfig().OutputStyle
+	if len(selected) > 0 {
+		format = selected[0]
+	}
+	if format == "" {
+		format = output.DefaultFormat
+	}
+	if _, err := output.Lookup(format); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	return format, opts
+}
+
+// renderCards : render the cards, or the items they belong to when the format renders whole items. loadItems
+// returns the items of the uuids, usually Vault.GetItemsByUUID.
+func renderCards(loadItems func(uuids []string) ([]enpass.Item, error), format string, cards []enpass.Card, opts output.Options) {
+	if opts.Template != nil || !output.RendersItems(format) {
+		if err := output.Render(os.Stdout, format, cards, opts); err != nil {
+			logger.Error(err)
+			logger.Exit(2)
+		}
+		return
+	}
+
+	uuids := []string{}
+	seen := map[string]bool{}
+	for _, card := range output.PrepareCards(cards, opts) {
+		if !seen[card.UUID] {
+			seen[card.UUID] = true
+			uuids = append(uuids, card.UUID)
+		}
+	}
+	items, err := loadItems(uuids)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	if err := output.RenderItems(os.Stdout, format, items, opts); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+}
+
+// outputFields : the fields to display, --fields wins over the "fields" section of the configuration. An empty
+// list keeps the default fields of each output style.
+func outputFields(cmd *cobra.Command, cmdType string) []string {
+	fields := globals.GetConfig().Fields[cmdType]
+	if cmd.Flags().Changed("fields") {
+		fields = flagFields
+	}
+
+	if err := output.ValidateFields(cmdType, fields); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	return fields
+}
+
+// outputTemplate : parse --format. A value without "{{" names a template of the "templates" section of the
+// configuration. It returns nil when --format is not given.
+func outputTemplate() *template.Template {
+	if flagFormat == "" {
+		return nil
+	}
+
+	text := flagFormat
+	if !strings.Contains(text, "{{") {
+		templates := globals.GetConfig().Templates
+		saved, ok := templates[text]
+		if !ok {
+			names := make([]string, 0, len(templates))
+			for name := range templates {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) > 0 {
+				logger.Errorf("the template %q is not defined, defined templates: %s", text, strings.Join(names, ", "))
+			} else {
+				logger.Errorf("the template %q is not defined, add it under \"templates\" in %s", text, configPath)
+			}
+			logger.Exit(2)
+		}
+		logger.Debugf("using template %s", text)
+		text = saved
+	}
+
+	tmpl, err := output.ParseTemplate(text)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	return tmpl
+}
+
+// reportOutputs : the --output formats of the commands printing a report, such as diff and verify
+var reportOutputs = []string{"text", "json", "yaml"}
+
+// checkReportOutput : fail on an invalid --output of a report
+func checkReportOutput(format string) {
+	if !funk.ContainsString(reportOutputs, format) {
+		logger.Errorf("invalid output %q, valid: %s", format, strings.Join(reportOutputs, ", "))
+		logger.Exit(2)
+	}
+}
+
+// printReport : print the report as JSON or YAML, or with text for the text format
+func printReport(format string, report interface{}, text func(w io.Writer) error) {
+	var err error
+	switch format {
+	case "json":
+		var data []byte
+		if data, err = json.MarshalIndent(report, "", "  "); err == nil {
+			fmt.Println(string(data))
+		}
+	case "yaml":
+		var data []byte
+		if data, err = yaml.Marshal(report); err == nil {
+			fmt.Print(string(data))
+		}
+	default:
+		err = text(os.Stdout)
+	}
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+}
@@ -5,12 +5,13 @@ import (
 
 	"github.com/gdanko/enpass/pkg/enpass"
 	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
 	passCmd = &cobra.Command{
-		Use:          "pass",
+		Use:          "pass [@search] [query]",
 		Short:        "Print the password of a vault entry to STDOUT",
 		Long:         "Print the password of a vault entry to STDOUT",
 		PreRun:       passPreRunCmd,
@@ -30,6 +31,11 @@ func passPreRunCmd(cmd *cobra.Command, args []string) {
 }
 
 func passRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, false) {
+		return
+	}
+
 	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
 	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
 	if err != nil {
@@ -45,10 +51,16 @@ func passRunCmd(cmd *cobra.Command, args []string) {
 		logger.Exit(2)
 	}
 	logger.Debug("opened vault")
+	vault.MatchMode = flagMatchMode
+	vault.Query = queryFromArgs(args)
+	vault.Search = flagSearch
 
-	card, err := vault.GetEntry(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, true)
+	card, err := getUniqueEntry(vault)
 	if err != nil {
 		logger.Error(err)
+		if errors.Is(err, enpass.ErrCardNotFound) {
+			logSuggestions(vault)
+		}
 		logger.Exit(2)
 	}
 	fmt.Println(card.DecryptedValue)
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/dsl"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/spf13/cobra"
+)
+
+// applySavedSearch : when the first argument is @name, load the saved search from the configuration. Filter flags
+// given on the command line replace the saved values of the same filter, the others are added to the saved
+// search. A query after @name is AND-ed with the saved query. It returns the remaining query arguments.
+func applySavedSearch(cmd *cobra.Command, args []string) []string {
+	if len(args) <= 0 || !strings.HasPrefix(args[0], "@") {
+		return args
+	}
+
+	name := strings.TrimPrefix(args[0], "@")
+	searches := globals.GetConfig().Searches
+	saved, ok := searches[name]
+	if !ok {
+		names := make([]string, 0, len(searches))
+		for searchName := range searches {
+			names = append(names, "@"+searchName)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			logger.Errorf("the saved search %q is not defined, defined searches: %s", args[0], strings.Join(names, ", "))
+		} else {
+			logger.Errorf("the saved search %q is not defined, add it under \"searches\" in %s", args[0], configPath)
+		}
+		logger.Exit(2)
+	}
+	logger.Debugf("using saved search %s", args[0])
+
+	for _, filter := range []struct {
+		flag  string
+		value *[]string
+		saved []string
+	}{
+		{"category", &flagRecordCategory, saved.Category},
+		{"label", &flagLabel, saved.Label},
+		{"login", &flagRecordLogin, saved.Login},
+		{"orderby", &flagOrderBy, saved.OrderBy},
+		{"title", &flagRecordTitle, saved.Title},
+		{"uuid", &flagRecordUuid, saved.Uuid},
+	} {
+		if len(filter.saved) > 0 && !cmd.Flags().Changed(filter.flag) {
+			*filter.value = filter.saved
+		}
+	}
+
+	if saved.Match != "" && !cmd.Flags().Changed("match") {
+		flagMatchMode = saved.Match
+	}
+	if saved.Search != "" && !cmd.Flags().Changed("search") {
+		flagSearch = saved.Search
+	}
+
+	rest := args[1:]
+	if saved.Query == "" {
+		return rest
+	} else if len(rest) <= 0 {
+		return []string{saved.Query}
+	}
+	return []string{fmt.Sprintf("(%s) AND (%s)", saved.Query, queryFromArgs(rest))}
+}
+
+// queryFromArgs : the positional arguments form a single query, so quoting the whole query is optional
+func queryFromArgs(args []string) string {
+	return strings.TrimSpace(strings.Join(args, " "))
+}
+
+// prepareQuery : validate the query before the vault is opened and print the SQL statement the command runs when
+// --explain is given, allFields for the commands reading whole items. It returns false when the command should
+// stop.
+func prepareQuery(args []string, allFields bool) bool {
+	query := queryFromArgs(args)
+	if query == "" {
+		if flagExplain {
+			logger.Error("--explain requires a query")
+			logger.Exit(2)
+		}
+		return true
+	}
+
+	compiled, err := dsl.Compile(query, flagCaseSensitive)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	if flagExplain {
+		statement, args, err := enpass.ExplainEntries(flagMatchMode, query, flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, allFields, logLevel, flagNoColor)
+		if err != nil {
+			logger.Error(err)
+			logger.Exit(2)
+		}
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			if value, ok := arg.(string); ok {
+				quoted[i] = fmt.Sprintf("%q", value)
+			} else {
+				quoted[i] = fmt.Sprint(arg)
+			}
+		}
+		fmt.Printf("SQL   %s\n", statement)
+		fmt.Printf("ARGS  [%s]\n", strings.Join(quoted, ", "))
+		if flagMatchMode == enpass.MatchRegex || flagMatchMode == enpass.MatchFuzzy || flagSearch != "" {
+			fmt.Println("the rows are filtered and ranked afterwards for --match and --search")
+		}
+		return false
+	}
+
+	// Asking for trashed items in the query implies showing them
+	if compiled.Uses("trashed") {
+		flagTrashed = true
+	}
+
+	return true
+}
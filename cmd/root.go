@@ -1,9 +1,9 @@
 package cmd
 
 import (
-	"path/filepath"
+	"errors"
+	"os"
 	"runtime"
-	"strings"
 
 	"github.com/gdanko/enpass/globals"
 	"github.com/gdanko/enpass/pkg/enpass"
@@ -16,24 +16,40 @@ var (
 	flagCardType         string
 	flagCaseSensitive    bool
 	flagClipboardPrimary bool
+	flagCsv              bool
 	configPath           string
 	credentials          *enpass.VaultCredentials
 	defaultLogLevel      = "info"
 	enpassConfig         globals.EnpassConfig
 	err                  error
 	flagEnablePin        bool
+	flagExplain          bool
+	flagFields           []string
+	flagFormat           string
+	flagIncludeSecrets   bool
+	flagJson             bool
+	flagJsonl            bool
 	flagKeyFilePath      string
 	flagLabel            []string
 	flagList             bool
+	flagMatchMode        string
 	flagNoColor          bool
+	flagOutput           string
 	flagNonInteractive   bool
 	flagOrderBy          []string
+	flagProfile          string
 	flagRecordCategory   []string
 	flagRecordLogin      []string
 	flagRecordTitle      []string
 	flagRecordUuid       []string
+	flagSearch           string
+	flagSSHAgentConfirm  bool
+	flagSSHAgentSocket   string
+	flagSSHKeyCategory   []string
+	flagSSHKeyLabel      []string
 	flagTable            bool
 	flagTrashed          bool
+	flagTsv              bool
 	flagVaultPath        string
 	flagYaml             bool
 	logLevel             logrus.Level
@@ -63,6 +79,7 @@ func Execute() error {
 }
 
 func init() {
+	cobra.OnInitialize(initConfig)
 	GetPersistenFlags(rootCmd)
 	logLevel = logLevelMap[logLevelStr]
 	logger = util.ConfigureLogger(logLevel, flagNoColor)
@@ -73,16 +90,28 @@ func init() {
 		logger.Error(err)
 		logger.Exit(2)
 	}
+}
+
+// initConfig : once the flags are parsed, load the configuration. The config file is overridden by the
+// selected profile, which is overridden by the ENPASS_* environment variables.
+func initConfig() {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
 
 	// Parse the config file and set the config object in globals
-	configPath = filepath.Join(globals.GetHomeDirectory(), ".enpass.yml")
+	configPath = util.ConfigPath()
+	logger.Debugf("using the config file %s", configPath)
 	enpassConfig, err = util.ParseConfig(configPath)
 	if err == nil {
-		globals.SetConfig(enpassConfig)
+		if data, err := os.ReadFile(configPath); err == nil {
+			for _, key := range util.UnknownConfigKeys(data) {
+				logger.Warningf("ignoring the unknown key %q in %s", key, configPath)
+			}
+		}
 	} else {
-		if strings.Contains(err.Error(), "does not exist") {
-			logger.Infof("%s, using the default configuration", err)
-		} else if strings.Contains(err.Error(), "failed to read") || strings.Contains(err.Error(), "failed to parse") {
+		if errors.Is(err, util.ErrConfigNotFound) {
+			logger.Debugf("%s, using the default configuration", err)
+		} else {
 			logger.Warningf("%s, using the default configuration", err)
 		}
 
@@ -93,6 +122,32 @@ func init() {
 		} else if runtime.GOOS == "linux" {
 			enpassConfig.VaultPath = "~/Documents/Enpass/Vaults/primary"
 		}
-		globals.SetConfig(enpassConfig)
 	}
+
+	// --profile wins over ENPASS_PROFILE, which wins over default_profile. The ENPASS_DEFAULT_PROFILE override of
+	// default_profile must be read here, the other overrides are applied after the profile.
+	profileName := flagProfile
+	if profileName == "" {
+		profileName = os.Getenv("ENPASS_PROFILE")
+	}
+	if profileName == "" {
+		profileName = enpassConfig.DefaultProfile
+		if value, ok := os.LookupEnv(util.EnvOverrideName("default_profile")); ok {
+			profileName = value
+		}
+	}
+	if profileName != "" {
+		enpassConfig, err = util.ApplyProfile(enpassConfig, profileName)
+		if err != nil {
+			logger.Error(err)
+			logger.Exit(2)
+		}
+		logger.Debugf("using profile %s", profileName)
+	}
+
+	for _, name := range util.ApplyEnvOverrides(&enpassConfig) {
+		logger.Debugf("configuration overridden by $%s", name)
+	}
+
+	globals.SetConfig(enpassConfig)
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/picker"
+	"github.com/pkg/errors"
+)
+
+// isInteractive : prompts are allowed and a user is sitting at the terminal
+func isInteractive() bool {
+	return !flagNonInteractive && picker.IsTerminal(os.Stdin)
+}
+
+// hasRecordFilters : at least one record filter was given on the command line
+func hasRecordFilters() bool {
+	return len(flagRecordCategory) > 0 || len(flagRecordTitle) > 0 || len(flagRecordLogin) > 0 || len(flagRecordUuid) > 0 || flagSearch != "" || vault.Query != ""
+}
+
+// logSuggestions : when nothing matched, point the user at titles close to what they searched for
+func logSuggestions(vault *enpass.Vault) {
+	terms := flagRecordTitle
+	if flagSearch != "" {
+		terms = append([]string{flagSearch}, terms...)
+	}
+	if len(terms) <= 0 {
+		return
+	}
+
+	suggestions, err := vault.Suggest(terms, 5)
+	if err != nil {
+		logger.WithError(err).Debug("could not compute suggestions")
+		return
+	}
+	if len(suggestions) <= 0 {
+		return
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		quoted[i] = fmt.Sprintf("%q", suggestion)
+	}
+	logger.Infof("did you mean %s?", strings.Join(quoted, ", "))
+}
+
+// getUniqueEntry : return the single matching entry, letting the user pick one when the filters are
+// ambiguous or missing and the session is interactive. Scripts keep failing on ambiguous matches.
+func getUniqueEntry(vault *enpass.Vault) (*enpass.Card, error) {
+	if !isInteractive() {
+		return vault.GetEntry(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, true)
+	}
+
+	cards, err := vault.GetEntries(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve cards")
+	}
+
+	candidates := []enpass.Card{}
+	for _, card := range cards {
+		if card.IsTrashed() || card.IsDeleted() {
+			continue
+		}
+		candidates = append(candidates, card)
+	}
+
+	if len(candidates) <= 0 {
+		return nil, enpass.ErrCardNotFound
+	} else if len(candidates) == 1 && hasRecordFilters() {
+		return &candidates[0], nil
+	}
+
+	return pickEntry(candidates)
+}
+
+// pickEntry : run the fuzzy finder over the cards, the preview never contains decrypted values
+func pickEntry(cards []enpass.Card) (*enpass.Card, error) {
+	items := make([]picker.Item, len(cards))
+	for i, card := range cards {
+		items[i] = picker.Item{
+			Fields: []string{strings.TrimSpace(card.Title), card.Subtitle, card.Category},
+			Preview: []string{
+				fmt.Sprintf("title:     %s", card.Title),
+				fmt.Sprintf("login:     %s", card.Subtitle),
+				fmt.Sprintf("category:  %s", card.Category),
+				fmt.Sprintf("label:     %s", card.Label),
+				fmt.Sprintf("uuid:      %s", card.UUID),
+				fmt.Sprintf("created:   %s", card.Created),
+				fmt.Sprintf("updated:   %s", card.Updated),
+				fmt.Sprintf("last_used: %s", card.LastUsed),
+				fmt.Sprintf("trashed:   %v", card.IsTrashed()),
+			},
+		}
+	}
+
+	index, err := picker.Pick(items, "> ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &cards[index], nil
+}
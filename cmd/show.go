@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/gdanko/enpass/pkg/enpass"
-	"github.com/gdanko/enpass/pkg/output"
+	"github.com/gdanko/enpass/pkg/picker"
 	"github.com/gdanko/enpass/util"
 	"github.com/spf13/cobra"
 )
 
 var (
 	showCmd = &cobra.Command{
-		Use:          "show",
+		Use:          "show [@search] [query]",
 		Short:        "List vault entries, displaying the password",
 		Long:         "List vault entries, displaying the password",
 		PreRun:       showPreRunCmd,
@@ -29,6 +31,12 @@ func showPreRunCmd(cmd *cobra.Command, args []string) {
 }
 
 func showRunCmd(cmd *cobra.Command, args []string) {
+	args = applySavedSearch(cmd, args)
+	if !prepareQuery(args, false) {
+		return
+	}
+	format, opts := outputOptions(cmd, "show")
+
 	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
 	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
 	if err != nil {
@@ -44,12 +52,37 @@ func showRunCmd(cmd *cobra.Command, args []string) {
 		logger.Exit(2)
 	}
 	logger.Debug("opened vault")
+	vault.MatchMode = flagMatchMode
+	vault.Query = queryFromArgs(args)
+	vault.Search = flagSearch
 
 	cards, err := vault.GetEntries(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy)
 	if err != nil {
 		logger.Error(err)
 		logger.Exit(2)
 	}
+	if len(cards) <= 0 {
+		logSuggestions(vault)
+	}
+
+	// An ambiguous or missing filter in an interactive session shows the single picked entry
+	if isInteractive() && picker.IsTerminal(os.Stdout) {
+		candidates := []enpass.Card{}
+		for _, card := range cards {
+			if card.IsDeleted() || (card.IsTrashed() && !flagTrashed) {
+				continue
+			}
+			candidates = append(candidates, card)
+		}
+		if len(candidates) > 1 || (len(candidates) == 1 && !hasRecordFilters()) {
+			card, err := pickEntry(candidates)
+			if err != nil {
+				logger.Error(err)
+				logger.Exit(2)
+			}
+			cards = []enpass.Card{*card}
+		}
+	}
 
-	output.GenerateOutput(logger, "show", flagList, flagTable, flagTrashed, flagYaml, flagNoColor, &cards)
+	renderCards(vault.GetItemsByUUID, format, cards, opts)
 }
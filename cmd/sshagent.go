@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/sshagent"
+	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sshAgentCmd = &cobra.Command{
+		Use:          "ssh-agent",
+		Short:        "Serve the SSH private keys stored in the vault through the ssh-agent protocol",
+		Long:         "Serve the SSH private keys stored in the vault through the ssh-agent protocol. Keys are only kept in memory.",
+		PreRun:       sshAgentPreRunCmd,
+		Run:          sshAgentRunCmd,
+		SilenceUsage: true,
+	}
+	defaultSSHKeyCategories = []string{"sshkey"}
+	defaultSSHKeyLabels     = []string{"Private Key"}
+)
+
+func init() {
+	GetSSHAgentFlags(sshAgentCmd)
+	rootCmd.AddCommand(sshAgentCmd)
+}
+
+func sshAgentPreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func sshAgentRunCmd(cmd *cobra.Command, args []string) {
+	var (
+		agentConfig = globals.GetConfig().SSHAgent
+		categories  = defaultSSHKeyCategories
+		labels      = defaultSSHKeyLabels
+		socketPath  = flagSSHAgentSocket
+	)
+
+	if len(flagSSHKeyCategory) > 0 {
+		categories = flagSSHKeyCategory
+	} else if len(agentConfig.Categories) > 0 {
+		categories = agentConfig.Categories
+	}
+
+	if len(flagSSHKeyLabel) > 0 {
+		labels = flagSSHKeyLabel
+	} else if len(agentConfig.Labels) > 0 {
+		labels = agentConfig.Labels
+	}
+
+	if socketPath == "" {
+		socketPath = agentConfig.Socket
+	}
+	if socketPath == "" {
+		socketPath, err = defaultSSHAgentSocket()
+		if err != nil {
+			logger.Error(err)
+			logger.Exit(2)
+		}
+	}
+	socketPath = util.ExpandPath(socketPath)
+
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	defer func() {
+		vault.Close()
+	}()
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("opened vault")
+
+	fields, err := vault.GetSSHKeyCandidates(categories, labels, flagCaseSensitive)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	agent := sshagent.NewAgent(logger, flagSSHAgentConfirm)
+	loaded := 0
+	for _, field := range fields {
+		if !strings.Contains(field.DecryptedValue, "PRIVATE KEY") {
+			continue
+		}
+		comment := fmt.Sprintf("%s (%s)", strings.TrimSpace(field.Title), field.Label)
+		if err := agent.AddKey([]byte(field.DecryptedValue), comment); err != nil {
+			logger.WithError(err).Warningf("skipping the %q field of %q", field.Label, field.Title)
+			continue
+		}
+		logger.Debugf("loaded key %s", comment)
+		loaded++
+	}
+
+	if loaded <= 0 {
+		logger.Errorf("no usable private keys found in categories [%s] or labels [%s]", strings.Join(categories, ", "), strings.Join(labels, ", "))
+		logger.Exit(2)
+	}
+	logger.Infof("loaded %d private key(s)", loaded)
+
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			logger.Errorf("%s exists and is not a socket", socketPath)
+			logger.Exit(2)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			logger.WithError(err).Errorf("could not remove the stale socket %s", socketPath)
+			logger.Exit(2)
+		}
+	}
+
+	listener, err := listenPrivate(socketPath)
+	if err != nil {
+		logger.WithError(err).Error("could not listen on the agent socket")
+		logger.Exit(2)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		sig := <-signals
+		logger.Debugf("received %s, shutting down", sig)
+		listener.Close()
+	}()
+
+	fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", socketPath)
+	fmt.Printf("echo Agent pid %d;\n", os.Getpid())
+
+	if err := agent.Serve(listener); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("agent stopped")
+}
+
+// defaultSSHAgentSocket : prefer the per-user runtime directory, fall back to a private directory in the
+// temporary directory, which is shared with the other users
+func defaultSSHAgentSocket() (string, error) {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "enpass-agent.sock"), nil
+	}
+
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("enpass-agent-%d", os.Getuid()))
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", errors.Wrapf(err, "could not create the agent directory %s", dir)
+	}
+	// The name is predictable, make sure another user did not create it first
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not check the agent directory %s", dir)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || info.Mode().Perm() != 0700 || !ok || int(stat.Uid) != os.Getuid() {
+		return "", errors.Errorf("%s must be a directory owned by the current user with mode 0700", dir)
+	}
+	return filepath.Join(dir, "agent.sock"), nil
+}
+
+// listenPrivate : listen on the unix socket, creating it with mode 0600. The umask is set around the listen, so
+// the socket is never reachable by other users, not even before a chmod.
+func listenPrivate(socketPath string) (net.Listener, error) {
+	mask := syscall.Umask(0177)
+	defer syscall.Umask(mask)
+
+	return net.Listen("unix", socketPath)
+}
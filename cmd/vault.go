@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/backup"
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/util"
+	"github.com/miquella/ask"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Create and maintain vaults",
+		Long:  "Create and maintain vaults",
+	}
+	vaultCreateCmd = &cobra.Command{
+		Use:          "create",
+		Short:        "Create a new, empty vault",
+		Long:         "Create a new, empty vault protected by a password, read from $MASTERPW or prompted for twice, and by the keyfile given with --keyfile.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          vaultCreateRunCmd,
+		SilenceUsage: true,
+	}
+	vaultPasswdCmd = &cobra.Command{
+		Use:          "passwd",
+		Short:        "Change the password of the vault",
+		Long:         "Change the password of the vault. The new password is read from $ENPASS_NEW_PASSWORD or prompted for twice. The vault is backed up first.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          vaultPasswdRunCmd,
+		SilenceUsage: true,
+	}
+	vaultKeyfileCmd = &cobra.Command{
+		Use:   "keyfile",
+		Short: "Add or remove the keyfile of the vault",
+		Long:  "Add or remove the keyfile of the vault",
+	}
+	vaultKeyfileAddCmd = &cobra.Command{
+		Use:          "add KEYFILE",
+		Short:        "Protect the vault with a keyfile as well as the password",
+		Long:         "Protect the vault with the keyfile KEYFILE as well as the password. KEYFILE is a path or cmd:COMMAND, the other sources of --keyfile do not keep the keyfile. A keyfile already protecting the vault, given with --keyfile, is replaced. The vault is backed up first.",
+		Args:         cobra.ExactArgs(1),
+		PreRun:       vaultPreRunCmd,
+		Run:          vaultKeyfileAddRunCmd,
+		SilenceUsage: true,
+	}
+	vaultKeyfileRemoveCmd = &cobra.Command{
+		Use:          "remove",
+		Short:        "Stop protecting the vault with a keyfile",
+		Long:         "Stop protecting the vault with the keyfile given with --keyfile, only the password is needed afterwards. The vault is backed up first.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          vaultKeyfileRemoveRunCmd,
+		SilenceUsage: true,
+	}
+	flagVaultCreateName string
+	flagVaultCreatePath string
+	flagVaultKDFIter    int
+)
+
+func init() {
+	GetVaultCreateFlags(vaultCreateCmd)
+	for _, cmd := range []*cobra.Command{vaultPasswdCmd, vaultKeyfileAddCmd, vaultKeyfileRemoveCmd} {
+		GetVaultRekeyFlags(cmd)
+	}
+	vaultKeyfileCmd.AddCommand(vaultKeyfileAddCmd, vaultKeyfileRemoveCmd)
+	vaultCmd.AddCommand(vaultCreateCmd, vaultPasswdCmd, vaultKeyfileCmd)
+	rootCmd.AddCommand(vaultCmd)
+}
+
+func vaultPreRunCmd(cmd *cobra.Command, args []string) {
+	logLevel = logLevelMap[logLevelStr]
+	logger = util.ConfigureLogger(logLevel, flagNoColor)
+}
+
+func vaultCreateRunCmd(cmd *cobra.Command, args []string) {
+	vaultPath := flagVaultCreatePath
+	if vaultPath == "" {
+		vaultPath = flagVaultPath
+	}
+	if vaultPath == "" {
+		logger.Error("specify the directory of the new vault with --path")
+		logger.Exit(2)
+	}
+	vaultPath = util.ExpandPath(vaultPath)
+
+	name := flagVaultCreateName
+	if name == "" {
+		name = filepath.Base(vaultPath)
+	}
+
+	keyFilePath := ""
+	if flagKeyFilePath != "" {
+		keyFilePath = util.ExpandPath(flagKeyFilePath)
+	}
+
+	password := newPassword("MASTERPW", "the password of the new vault")
+	if password == "" {
+		logger.Error("the vault requires a password, set $MASTERPW or allow the prompt")
+		logger.Exit(2)
+	}
+
+	if err := enpass.CreateVault(vaultPath, name, password, keyFilePath, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	fmt.Printf("Created the vault %q in %s\n", name, vaultPath)
+}
+
+func vaultPasswdRunCmd(cmd *cobra.Command, args []string) {
+	rekeyVault(func(current *enpass.VaultCredentials) (string, string) {
+		newPassword := newPassword("ENPASS_NEW_PASSWORD", "the new password of the vault")
+		if newPassword == "" {
+			logger.Error("the vault requires a password, set $ENPASS_NEW_PASSWORD or allow the prompt")
+			logger.Exit(2)
+		}
+		return newPassword, current.KeyFilePath()
+	})
+	fmt.Println("Changed the password of the vault")
+}
+
+func vaultKeyfileAddRunCmd(cmd *cobra.Command, args []string) {
+	if enpass.IsEphemeralKeyFile(args[0]) {
+		logger.Errorf("%s only hands the keyfile to this run, add the path of a keyfile or cmd:COMMAND", args[0])
+		logger.Exit(2)
+	}
+	keyFilePath := util.ExpandPath(args[0])
+	if _, err := enpass.ReadKeyFile(keyFilePath); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	rekeyVault(func(current *enpass.VaultCredentials) (string, string) {
+		return current.Password, keyFilePath
+	})
+	fmt.Println("The vault is now protected by the keyfile, keep a copy of it in a safe place")
+}
+
+func vaultKeyfileRemoveRunCmd(cmd *cobra.Command, args []string) {
+	rekeyVault(func(current *enpass.VaultCredentials) (string, string) {
+		if !vault.HasKeyfile() {
+			logger.Error("the vault is not protected by a keyfile")
+			logger.Exit(2)
+		}
+		return current.Password, ""
+	})
+	fmt.Println("The vault is no longer protected by a keyfile")
+}
+
+// rekeyVault : open the vault with the current credentials, back it up, then re-key it with the password and
+// keyfile returned by newCredentials, which gets the current credentials. The vault is opened again with the new
+// credentials to check the result.
+func rekeyVault(newCredentials func(current *enpass.VaultCredentials) (string, string)) {
+	if flagEnablePin {
+		logger.Error("changing the credentials requires the vault password, not the PIN")
+		logger.Exit(2)
+	}
+
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	vault, credentials, err = enpass.OpenVault(logger, false, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	if vault.IsReadOnly() {
+		logger.Error("the vault was opened from an archive, its credentials cannot be changed")
+		logger.Exit(2)
+	}
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("opened vault")
+
+	password, keyFilePath := newCredentials(credentials)
+
+	backupPath, err := backup.Create(vaultPath, backupDir())
+	if err != nil {
+		logger.Errorf("failed to back up the vault, nothing was changed: %s", err)
+		logger.Exit(2)
+	}
+	logger.Infof("backed up the vault to %s", backupPath)
+
+	if err := vault.Rekey(password, keyFilePath, flagVaultKDFIter); err != nil {
+		logger.Errorf("%s, the vault can be restored from %s", err, backupPath)
+		logger.Exit(2)
+	}
+
+	check, err := enpass.NewVault(vaultPath, logLevel, flagNoColor)
+	if err == nil {
+		err = check.Open(enpass.NewVaultCredentials(password, keyFilePath), logLevel, flagNoColor)
+	}
+	if err != nil {
+		logger.Errorf("the vault does not open with the new credentials (%s), restore it from %s", err, backupPath)
+		logger.Exit(2)
+	}
+	check.Close()
+}
+
+// openOtherVault : open the second vault of a command with the password of $envName when it is set, else with
+// first, the credentials of the first vault taken before it was opened. The keyfile is keyFilePath, or the one of
+// first when the vault has a keyfile. The password is prompted for when the credentials do not open the vault.
+func openOtherVault(path string, first enpass.VaultCredentials, envName, keyFilePath string) *enpass.Vault {
+	other, err := enpass.NewVault(path, logLevel, flagNoColor)
+	if err != nil {
+		logger.Errorf("%s: %s", path, err)
+		logger.Exit(2)
+	}
+	if keyFilePath == "" && other.HasKeyfile() {
+		keyFilePath = first.KeyFilePath()
+	}
+
+	credentials := &first
+	if password, ok := os.LookupEnv(envName); ok && envName != "" {
+		credentials = enpass.NewVaultCredentials(password, keyFilePath)
+	} else if keyFilePath != first.KeyFilePath() {
+		credentials = enpass.NewVaultCredentials(first.Password, keyFilePath)
+	}
+	if err = other.Open(credentials, logLevel, flagNoColor); err == nil {
+		return other
+	}
+
+	if !isInteractive() {
+		logger.Errorf("%s: %s", path, err)
+		logger.Exit(2)
+	}
+	logger.Debugf("the credentials do not open %s: %s", path, err)
+	password, err := ask.HiddenAsk(fmt.Sprintf("Enter the vault password of %s: ", path))
+	if err != nil {
+		logger.Errorf("could not prompt for the vault password: %s", err)
+		logger.Exit(2)
+	}
+	if err := other.Open(enpass.NewVaultCredentials(password, keyFilePath), logLevel, flagNoColor); err != nil {
+		logger.Errorf("%s: %s", path, err)
+		logger.Exit(2)
+	}
+	return other
+}
+
+// backupDir : the directory of the vault backups, backup.dir of the configuration or the default one
+func backupDir() string {
+	if dir := globals.GetConfig().Backup.Dir; dir != "" {
+		return util.ExpandPath(dir)
+	}
+	return backup.DefaultDir()
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyCmd = &cobra.Command{
+		Use:          "verify",
+		Short:        "Check the integrity of the vault",
+		Long:         "Check the integrity of the vault: every password field of every item is decrypted, item keys must hold 44 bytes, fields must belong to an item, item uuids must be unique and vault_items_count of vault.json must match the database. Every problem is reported, the exit status is 1 when there are some and 2 when the vault does not open.",
+		Args:         cobra.NoArgs,
+		PreRun:       vaultPreRunCmd,
+		Run:          verifyRunCmd,
+		SilenceUsage: true,
+	}
+	flagVerifyOutput string
+)
+
+func init() {
+	GetVerifyFlags(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func verifyRunCmd(cmd *cobra.Command, args []string) {
+	checkReportOutput(flagVerifyOutput)
+
+	vaultPath := enpass.DetermineVaultPath(logger, flagVaultPath)
+	vault, credentials, err = enpass.OpenVault(logger, flagEnablePin, flagNonInteractive, vaultPath, flagKeyFilePath, logLevel, flagNoColor)
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	defer vault.Close()
+	if err := vault.Open(credentials, logLevel, flagNoColor); err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+	logger.Debug("opened vault")
+
+	report, err := vault.Verify()
+	if err != nil {
+		logger.Error(err)
+		logger.Exit(2)
+	}
+
+	printReport(flagVerifyOutput, report, func(w io.Writer) error {
+		for _, problem := range report.Problems {
+			subject := ""
+			if problem.Title != "" {
+				subject = fmt.Sprintf("%s (%s): ", problem.Title, problem.UUID)
+			} else if problem.UUID != "" {
+				subject = problem.UUID + ": "
+			}
+			if _, err := fmt.Fprintf(w, "%-15s %s%s\n", problem.Check, subject, problem.Message); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s: %d items, %d fields, %d passwords decrypted, %d problems\n", vaultPath, report.Items, report.Fields, report.Decrypted, len(report.Problems))
+		return err
+	})
+
+	if len(report.Problems) > 0 {
+		logger.Exit(1)
+	}
+}
@@ -6,6 +6,13 @@ import (
 	"sync"
 )
 
+type Backup struct {
+	Dir        string `yaml:"dir"`
+	KeepDaily  int    `yaml:"keep_daily"`
+	KeepLast   int    `yaml:"keep_last"`
+	KeepWeekly int    `yaml:"keep_weekly"`
+}
+
 type Colors struct {
 	AliasColor  string `yaml:"alias_color"`
 	AnchorColor string `yaml:"anchor_color"`
@@ -16,13 +23,53 @@ type Colors struct {
 	StringColor string `yaml:"string_color"`
 }
 
+type Profile struct {
+	Colors          Colors   `yaml:"colors"`
+	DefaultLabels   []string `yaml:"default_labels"`
+	KeyFile         string   `yaml:"keyfile"`
+	OrderBy         []string `yaml:"orderby"`
+	OutputStyle     string   `yaml:"output_style"`
+	PasswordCommand string   `yaml:"password_command"`
+	PasswordEnv     string   `yaml:"password_env"`
+	VaultPassword   string   `yaml:"vault_password"`
+	VaultPath       string   `yaml:"vault_path"`
+}
+
+type SavedSearch struct {
+	Category []string `yaml:"category"`
+	Label    []string `yaml:"label"`
+	Login    []string `yaml:"login"`
+	Match    string   `yaml:"match"`
+	OrderBy  []string `yaml:"orderby"`
+	Query    string   `yaml:"query"`
+	Search   string   `yaml:"search"`
+	Title    []string `yaml:"title"`
+	Uuid     []string `yaml:"uuid"`
+}
+
+type SSHAgent struct {
+	Categories []string `yaml:"categories"`
+	Labels     []string `yaml:"labels"`
+	Socket     string   `yaml:"socket"`
+}
+
 type EnpassConfig struct {
-	Colors        Colors   `yaml:"colors"`
-	DefaultLabels []string `yaml:"default_labels"`
-	OrderBy       []string `yaml:"orderby"`
-	OutputStyle   string   `yaml:"output_style"`
-	VaultPassword string   `yaml:"vault_password"`
-	VaultPath     string   `yaml:"vault_path"`
+	Backup          Backup                 `yaml:"backup"`
+	Colors          Colors                 `yaml:"colors"`
+	DefaultLabels   []string               `yaml:"default_labels"`
+	DefaultProfile  string                 `yaml:"default_profile"`
+	Fields          map[string][]string    `yaml:"fields"`
+	KeyFile         string                 `yaml:"keyfile"`
+	OrderBy         []string               `yaml:"orderby"`
+	OutputStyle     string                 `yaml:"output_style"`
+	PasswordCommand string                 `yaml:"password_command"`
+	PasswordEnv     string                 `yaml:"password_env"`
+	Profiles        map[string]Profile     `yaml:"profiles"`
+	Searches        map[string]SavedSearch `yaml:"searches"`
+	SSHAgent        SSHAgent               `yaml:"ssh_agent"`
+	Templates       map[string]string      `yaml:"templates"`
+	VaultPassword   string                 `yaml:"vault_password"`
+	VaultPath       string                 `yaml:"vault_path"`
 }
 
 var (
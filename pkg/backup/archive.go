@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive : a backup archive of the backup directory
+type Archive struct {
+	Path    string
+	Vault   string
+	Created time.Time
+	Size    int64
+}
+
+// Name : the file name of the archive
+func (a Archive) Name() string {
+	return filepath.Base(a.Path)
+}
+
+// List : the archives of the vault in dir, every archive when vault is empty, newest first. Files not named like
+// an archive are ignored, a missing directory holds no archives.
+func List(dir, vault string) ([]Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Archive{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the backup directory %s: %s", dir, err)
+	}
+
+	archives := []Archive{}
+	for _, entry := range entries {
+		archive, ok := parseName(entry.Name())
+		if !ok || entry.IsDir() || (vault != "" && archive.Vault != vault) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		archive.Path = filepath.Join(dir, entry.Name())
+		archive.Size = info.Size()
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		if !archives[i].Created.Equal(archives[j].Created) {
+			return archives[i].Created.After(archives[j].Created)
+		}
+		return archives[i].Path > archives[j].Path
+	})
+
+	return archives, nil
+}
+
+// parseName : split <vault>-<timestamp>.tar.gz
+func parseName(name string) (Archive, bool) {
+	if !strings.HasSuffix(name, archiveSuffix) {
+		return Archive{}, false
+	}
+	stem := strings.TrimSuffix(name, archiveSuffix)
+	if len(stem) < len(timeLayout)+2 || stem[len(stem)-len(timeLayout)-1] != '-' {
+		return Archive{}, false
+	}
+	created, err := time.Parse(timeLayout, stem[len(stem)-len(timeLayout):])
+	if err != nil {
+		return Archive{}, false
+	}
+	return Archive{Vault: stem[:len(stem)-len(timeLayout)-1], Created: created}, true
+}
+
+// Verify : check that the archive holds a manifest and every file it lists, with the recorded size and checksum
+func Verify(path string) (*Manifest, error) {
+	return readArchive(path, "")
+}
+
+// Extract : extract the files of the archive into dir, checking them against the manifest. Only the files listed
+// in the manifest are written.
+func Extract(path, dir string) (*Manifest, error) {
+	return readArchive(path, dir)
+}
+
+func readArchive(path, dir string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the backup %s: %s", path, err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a backup: %s", path, err)
+	}
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil || header.Name != ManifestName {
+		return nil, fmt.Errorf("%s is not a backup, it does not start with a manifest", path)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(archive, 1<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("the manifest of %s is invalid: %s", path, err)
+	}
+
+	expected := map[string]ManifestFile{}
+	for _, entry := range manifest.Files {
+		// Names are plain file names, anything else could escape the extraction directory
+		if entry.Name != filepath.Base(entry.Name) || entry.Name == "." || entry.Name == ".." {
+			return nil, fmt.Errorf("the manifest of %s lists an invalid file %q", path, entry.Name)
+		}
+		expected[entry.Name] = entry
+	}
+
+	found := map[string]bool{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read the backup %s: %s", path, err)
+		}
+		entry, ok := expected[header.Name]
+		if !ok || found[header.Name] || header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("the backup %s holds the unexpected entry %q", path, header.Name)
+		}
+		found[header.Name] = true
+
+		var w io.Writer = io.Discard
+		var out *os.File
+		if dir != "" {
+			if out, err = os.OpenFile(filepath.Join(dir, entry.Name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %s", entry.Name, err)
+			}
+			w = out
+		}
+		hash := sha256.New()
+		size, err := io.Copy(io.MultiWriter(w, hash), archive)
+		if out != nil {
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %s", entry.Name, err)
+		}
+		if size != entry.Size || hex.EncodeToString(hash.Sum(nil)) != entry.SHA256 {
+			return nil, fmt.Errorf("%s in the backup %s does not match its checksum", entry.Name, path)
+		}
+	}
+
+	for _, entry := range manifest.Files {
+		if !found[entry.Name] {
+			return nil, fmt.Errorf("%s is missing from the backup %s", entry.Name, path)
+		}
+	}
+	for _, file := range vaultFiles {
+		if !found[file] {
+			return nil, fmt.Errorf("the backup %s holds no %s", path, file)
+		}
+	}
+
+	return &manifest, nil
+}
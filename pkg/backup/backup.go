@@ -0,0 +1,230 @@
+// Package backup : snapshot vaults into timestamped archives holding a manifest with the checksum of every file
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/util"
+)
+
+const (
+	// ManifestName : the name of the manifest in the archive, it is always the first entry
+	ManifestName = "manifest.json"
+	// manifestVersion : the version of the manifest format
+	manifestVersion = 1
+	// timeLayout : the timestamp in the archive names, sortable and free of colons
+	timeLayout = "20060102T150405Z"
+	// archiveSuffix : the extension of the archives
+	archiveSuffix = ".tar.gz"
+)
+
+// vaultFiles : the files of a vault, attachments are matched by their extension
+var vaultFiles = []string{"vault.enpassdb", "vault.json"}
+
+// Manifest : the description of an archive
+type Manifest struct {
+	Version int            `json:"version"`
+	Vault   string         `json:"vault"`
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile : a file of the vault in the archive
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DefaultDir : $XDG_DATA_HOME/enpass/backups, ~/.local/share/enpass/backups by default
+func DefaultDir() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(globals.GetHomeDirectory(), ".local", "share")
+	}
+	return filepath.Join(util.ExpandPath(dataHome), "enpass", "backups")
+}
+
+// Create : snapshot the vault into a new archive in dir, named after the vault directory and the time. The vault
+// must not be written to meanwhile: a -wal or -journal file next to the database fails the backup, and so does a
+// file changing while it is read. It returns the path of the archive.
+func Create(vaultPath, dir string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(vaultPath); err == nil {
+		vaultPath = resolved
+	}
+	files, err := snapshotFiles(vaultPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create the backup directory %s: %s", dir, err)
+	}
+
+	name := VaultName(vaultPath)
+	now := time.Now().UTC()
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", name, now.Format(timeLayout), archiveSuffix))
+	if exists, _ := util.FileOrDirectoryExists(path); exists {
+		// The names have a one second resolution, wait for the next one
+		time.Sleep(time.Until(now.Truncate(time.Second).Add(time.Second)))
+		now = time.Now().UTC()
+		path = filepath.Join(dir, fmt.Sprintf("%s-%s%s", name, now.Format(timeLayout), archiveSuffix))
+		if exists, _ := util.FileOrDirectoryExists(path); exists {
+			return "", fmt.Errorf("the backup %s already exists", path)
+		}
+	}
+
+	manifest := Manifest{Version: manifestVersion, Vault: name, Created: now.Truncate(time.Second)}
+	stats := map[string]os.FileInfo{}
+	for _, file := range files {
+		info, sum, err := checksum(filepath.Join(vaultPath, file))
+		if err != nil {
+			return "", err
+		}
+		stats[file] = info
+		manifest.Files = append(manifest.Files, ManifestFile{Name: file, Size: info.Size(), SHA256: sum})
+	}
+
+	tmp, err := os.CreateTemp(dir, ".enpass-backup-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the backup: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeArchive(tmp, vaultPath, manifest); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write the backup: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write the backup: %s", err)
+	}
+
+	// Compare with the state before the copy, a change means the snapshot may be torn
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(vaultPath, file))
+		if err != nil || info.Size() != stats[file].Size() || !info.ModTime().Equal(stats[file].ModTime()) {
+			return "", fmt.Errorf("%s changed during the backup, close Enpass and try again", file)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write the backup: %s", err)
+	}
+	return path, nil
+}
+
+// VaultName : the name of the archives of the vault, its directory name
+func VaultName(vaultPath string) string {
+	if resolved, err := filepath.EvalSymlinks(vaultPath); err == nil {
+		vaultPath = resolved
+	}
+	return filepath.Base(vaultPath)
+}
+
+// snapshotFiles : the files to back up, relative to the vault directory
+func snapshotFiles(vaultPath string) ([]string, error) {
+	for _, suffix := range []string{"-wal", "-journal"} {
+		if exists, _ := util.FileOrDirectoryExists(filepath.Join(vaultPath, vaultFiles[0]+suffix)); exists {
+			return nil, fmt.Errorf("%s has a %s file, the vault is being written to, close Enpass and try again", vaultPath, suffix)
+		}
+	}
+
+	files := []string{}
+	for _, file := range vaultFiles {
+		if exists, _ := util.FileOrDirectoryExists(filepath.Join(vaultPath, file)); !exists {
+			return nil, fmt.Errorf("%s is not a vault, %s is missing", vaultPath, file)
+		}
+		files = append(files, file)
+	}
+
+	attachments, err := filepath.Glob(filepath.Join(vaultPath, "*.enpassattach"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(attachments)
+	for _, attachment := range attachments {
+		files = append(files, filepath.Base(attachment))
+	}
+
+	return files, nil
+}
+
+func checksum(path string) (os.FileInfo, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	return info, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeArchive(w io.Writer, vaultPath string, manifest Manifest) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: ManifestName, Mode: 0600, Size: int64(len(data)), ModTime: manifest.Created}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := archive.Write(data); err != nil {
+		return err
+	}
+
+	for _, file := range manifest.Files {
+		if err := addFile(archive, filepath.Join(vaultPath, file.Name), file); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addFile : copy the file into the archive, checking it still matches its manifest entry
+func addFile(archive *tar.Writer, path string, file ManifestFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := &tar.Header{Name: file.Name, Mode: 0600, Size: file.Size, ModTime: time.Now()}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(archive, io.TeeReader(io.LimitReader(f, file.Size), hash)); err != nil {
+		return err
+	}
+	if !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), file.SHA256) {
+		return fmt.Errorf("%s changed during the backup, close Enpass and try again", file.Name)
+	}
+	return nil
+}
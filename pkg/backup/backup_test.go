@@ -0,0 +1,389 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+	"github.com/sirupsen/logrus"
+)
+
+// writeVault : a directory with the files of a vault
+func writeVault(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readVault : the files of a directory and their content
+func readVault(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+// writeRawArchive : an archive with the manifest and the entries as given, to build broken backups
+func writeRawArchive(t *testing.T, path string, manifest Manifest, entries map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+
+	data, _ := json.Marshal(manifest)
+	add := func(name, content string) {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(ManifestName, string(data))
+	for _, file := range manifest.Files {
+		if content, ok := entries[file.Name]; ok {
+			add(file.Name, content)
+		}
+	}
+	for name, content := range entries {
+		if !strings.Contains(string(data), `"`+name+`"`) {
+			add(name, content)
+		}
+	}
+	archive.Close()
+	gz.Close()
+}
+
+func manifestFile(name, content string) ManifestFile {
+	sum := sha256.Sum256([]byte(content))
+	return ManifestFile{Name: name, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+func TestCreateVerifyExtract(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "work")
+	files := map[string]string{"vault.enpassdb": "database", "vault.json": "{}", "a.enpassattach": "attachment"}
+	writeVault(t, vault, files)
+	dir := t.TempDir()
+
+	path, err := Create(vault, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, ok := parseName(filepath.Base(path))
+	if !ok || archive.Vault != "work" {
+		t.Fatalf("unexpected archive name %s", path)
+	}
+
+	manifest, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range manifest.Files {
+		names = append(names, file.Name)
+	}
+	if want := []string{"vault.enpassdb", "vault.json", "a.enpassattach"}; !reflect.DeepEqual(names, want) || manifest.Vault != "work" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+
+	extracted := t.TempDir()
+	if _, err := Extract(path, extracted); err != nil {
+		t.Fatal(err)
+	}
+	if got := readVault(t, extracted); !reflect.DeepEqual(got, files) {
+		t.Errorf("extracted %v, want %v", got, files)
+	}
+
+	archives, err := List(dir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 1 || archives[0].Path != path {
+		t.Errorf("unexpected archives %+v", archives)
+	}
+}
+
+func TestCreateRefusesOpenVault(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "work")
+	writeVault(t, vault, map[string]string{"vault.enpassdb": "database", "vault.json": "{}", "vault.enpassdb-wal": "wal"})
+	if _, err := Create(vault, t.TempDir()); err == nil {
+		t.Error("a vault with a -wal file was backed up")
+	}
+	if _, err := Create(t.TempDir(), t.TempDir()); err == nil {
+		t.Error("a directory without a vault was backed up")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name  string
+		vault string
+		ok    bool
+	}{
+		{"work-20240102T030405Z.tar.gz", "work", true},
+		{"my-vault-20240102T030405Z.tar.gz", "my-vault", true},
+		{"-20240102T030405Z.tar.gz", "", false},
+		{"work-20240102T030405Z.tgz", "", false},
+		{"work_20240102T030405Z.tar.gz", "", false},
+		{"work-20241302T030405Z.tar.gz", "", false},
+		{"work-2024-01-02.tar.gz", "", false},
+		{"20240102T030405Z.tar.gz", "", false},
+	}
+	for _, test := range tests {
+		archive, ok := parseName(test.name)
+		if ok != test.ok || archive.Vault != test.vault {
+			t.Errorf("%s: got %q, %t, want %q, %t", test.name, archive.Vault, ok, test.vault, test.ok)
+		}
+		if ok && !archive.Created.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("%s: created %s", test.name, archive.Created)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	at := func(s string) Archive {
+		created, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Archive{Path: s, Created: created}
+	}
+	// Newest first, as List returns them. 2024-01-08 is a Monday, the start of ISO week 2.
+	archives := []Archive{
+		at("2024-01-10T18:00:00Z"),
+		at("2024-01-10T09:00:00Z"),
+		at("2024-01-09T12:00:00Z"),
+		at("2024-01-08T12:00:00Z"),
+		at("2024-01-07T12:00:00Z"),
+		at("2024-01-03T12:00:00Z"),
+		at("2023-12-20T12:00:00Z"),
+	}
+	paths := func(archives []Archive) []string {
+		list := []string{}
+		for _, archive := range archives {
+			list = append(list, archive.Path)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		keep   []string
+	}{
+		{"last", Policy{KeepLast: 2}, []string{"2024-01-10T18:00:00Z", "2024-01-10T09:00:00Z"}},
+		{"daily keeps the newest of each day", Policy{KeepDaily: 3}, []string{"2024-01-10T18:00:00Z", "2024-01-09T12:00:00Z", "2024-01-08T12:00:00Z"}},
+		{"weekly keeps the newest of each week", Policy{KeepWeekly: 3}, []string{"2024-01-10T18:00:00Z", "2024-01-07T12:00:00Z", "2023-12-20T12:00:00Z"}},
+		{"rules add up", Policy{KeepLast: 1, KeepDaily: 1, KeepWeekly: 2}, []string{"2024-01-10T18:00:00Z", "2024-01-07T12:00:00Z"}},
+		{"more than there are", Policy{KeepDaily: 30}, []string{
+			"2024-01-10T18:00:00Z", "2024-01-09T12:00:00Z", "2024-01-08T12:00:00Z", "2024-01-07T12:00:00Z",
+			"2024-01-03T12:00:00Z", "2023-12-20T12:00:00Z",
+		}},
+		{"zero policy", Policy{}, []string{}},
+	}
+	for _, test := range tests {
+		keep, remove := Prune(archives, test.policy)
+		if got := paths(keep); !reflect.DeepEqual(got, test.keep) {
+			t.Errorf("%s: kept %v, want %v", test.name, got, test.keep)
+		}
+		if len(keep)+len(remove) != len(archives) {
+			t.Errorf("%s: %d kept and %d removed of %d", test.name, len(keep), len(remove), len(archives))
+		}
+	}
+	if !(Policy{}).IsZero() || DefaultPolicy.IsZero() {
+		t.Error("IsZero is wrong")
+	}
+}
+
+func TestReadArchiveRejects(t *testing.T) {
+	database, info := "database", "{}"
+	valid := []ManifestFile{manifestFile("vault.enpassdb", database), manifestFile("vault.json", info)}
+	entries := map[string]string{"vault.enpassdb": database, "vault.json": info}
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+		entries  map[string]string
+		message  string
+	}{
+		{"parent directory", Manifest{Files: append(valid, manifestFile("../evil", "x"))}, map[string]string{"vault.enpassdb": database, "vault.json": info, "../evil": "x"}, "invalid file"},
+		{"absolute path", Manifest{Files: append(valid, manifestFile("/tmp/evil", "x"))}, map[string]string{"vault.enpassdb": database, "vault.json": info, "/tmp/evil": "x"}, "invalid file"},
+		{"dot dot", Manifest{Files: append(valid, manifestFile("..", "x"))}, entries, "invalid file"},
+		{"unlisted entry", Manifest{Files: valid}, map[string]string{"vault.enpassdb": database, "vault.json": info, "extra": "x"}, "unexpected entry"},
+		{"bad checksum", Manifest{Files: []ManifestFile{manifestFile("vault.enpassdb", "other"), manifestFile("vault.json", info)}}, entries, "checksum"},
+		{"missing file", Manifest{Files: append(valid, manifestFile("a.enpassattach", "x"))}, entries, "missing"},
+		{"no database", Manifest{Files: valid[1:]}, map[string]string{"vault.json": info}, "holds no vault.enpassdb"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "work-20240102T030405Z.tar.gz")
+		writeRawArchive(t, path, test.manifest, test.entries)
+		dir := t.TempDir()
+		_, err := Extract(path, dir)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: got %v, want an error about %q", test.name, err, test.message)
+		}
+		if _, statErr := os.Stat(filepath.Join(filepath.Dir(dir), "evil")); statErr == nil {
+			t.Errorf("%s: a file was written outside the extraction directory", test.name)
+		}
+	}
+
+	notArchive := filepath.Join(t.TempDir(), "work-20240102T030405Z.tar.gz")
+	os.WriteFile(notArchive, []byte("plain text"), 0600)
+	if _, err := Verify(notArchive); err == nil {
+		t.Error("a file that is not an archive was verified")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	root := t.TempDir()
+	vault := filepath.Join(root, "work")
+	old := map[string]string{"vault.enpassdb": "old database", "vault.json": "{}", "a.enpassattach": "kept"}
+	writeVault(t, vault, old)
+	dir := filepath.Join(root, "backups")
+	path, err := Create(vault, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vault changes after the backup
+	current := map[string]string{"vault.enpassdb": "new database", "vault.json": `{"new":true}`, "b.enpassattach": "stale", "vault.enpassdb-shm": "shm"}
+	os.Remove(filepath.Join(vault, "a.enpassattach"))
+	writeVault(t, vault, current)
+
+	checked := ""
+	previous, err := Restore(path, vault, dir, func(extracted string) error {
+		checked = readVault(t, extracted)["vault.enpassdb"]
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != "old database" {
+		t.Errorf("check saw %q", checked)
+	}
+	if got := readVault(t, vault); !reflect.DeepEqual(got, old) {
+		t.Errorf("restored %v, want %v", got, old)
+	}
+	if previous == "" {
+		t.Fatal("the current vault was not backed up")
+	}
+	manifest, err := Verify(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Files[0].SHA256 != manifestFile("vault.enpassdb", "new database").SHA256 {
+		t.Error("the backup of the current vault holds another database")
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(root, ".enpass-restore-*")); len(leftovers) > 0 {
+		t.Errorf("the extraction directory was left behind: %v", leftovers)
+	}
+}
+
+func TestRestoreCheckFails(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "work")
+	writeVault(t, vault, map[string]string{"vault.enpassdb": "database", "vault.json": "{}"})
+	dir := t.TempDir()
+	path, err := Create(vault, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeVault(t, vault, map[string]string{"vault.enpassdb": "changed"})
+
+	if _, err := Restore(path, vault, dir, func(string) error { return errors.New("wrong password") }); err == nil {
+		t.Fatal("the restore went on after the check failed")
+	}
+	if got := readVault(t, vault)["vault.enpassdb"]; got != "changed" {
+		t.Errorf("the vault was replaced: %q", got)
+	}
+	if archives, _ := List(dir, ""); len(archives) != 1 {
+		t.Errorf("the vault was backed up, %d archives", len(archives))
+	}
+}
+
+func TestRestoreCheckVault(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	dir := t.TempDir()
+	path, err := Create(fixture.Path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := readVault(t, fixture.Path)
+	if _, ok := before[enpasstest.AttachmentScan+".enpassattach"]; !ok {
+		t.Fatalf("the fixture has no attachment file: %v", before)
+	}
+
+	// The backup is opened for real, with the wrong password it is not restored
+	wrong := enpass.NewVaultCredentials("wrong password", "")
+	if _, err := Restore(path, fixture.Path, dir, CheckVault(wrong, logrus.ErrorLevel, true)); err == nil {
+		t.Fatal("a backup that does not open with the password was restored")
+	}
+	if archives, _ := List(dir, ""); len(archives) != 1 {
+		t.Errorf("the vault was backed up, %d archives", len(archives))
+	}
+
+	if _, err := Restore(path, fixture.Path, dir, CheckVault(fixture.Credentials(), logrus.ErrorLevel, true)); err != nil {
+		t.Fatal(err)
+	}
+	if after := readVault(t, fixture.Path); !reflect.DeepEqual(after, before) {
+		t.Error("the restored vault differs from the backup")
+	}
+	items, err := fixture.Open(t).GetItemsByUUID([]string{enpasstest.ItemGitHub})
+	if err != nil || len(items) != 1 || len(items[0].Attachments) != 2 {
+		t.Errorf("the restored vault lost the attachments: %+v, %v", items, err)
+	}
+}
+
+func TestRestoreNewVault(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "work")
+	files := map[string]string{"vault.enpassdb": "database", "vault.json": "{}"}
+	writeVault(t, source, files)
+	dir := t.TempDir()
+	path, err := Create(source, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The target does not exist yet, it must be created where it was asked for
+	target := filepath.Join(t.TempDir(), "restored")
+	previous, err := Restore(path, target, dir, func(string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "" {
+		t.Errorf("a vault that did not exist was backed up to %s", previous)
+	}
+	if got := readVault(t, target); !reflect.DeepEqual(got, files) {
+		t.Errorf("restored %v, want %v", got, files)
+	}
+}
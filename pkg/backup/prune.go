@@ -0,0 +1,65 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPolicy : the retention of the backups when none is configured
+var DefaultPolicy = Policy{KeepDaily: 7, KeepWeekly: 4}
+
+// Policy : how many archives prune keeps. An archive is kept when any rule keeps it.
+type Policy struct {
+	// KeepLast keeps the newest archives
+	KeepLast int
+	// KeepDaily keeps the newest archive of each of the last days with archives
+	KeepDaily int
+	// KeepWeekly keeps the newest archive of each of the last ISO weeks with archives
+	KeepWeekly int
+}
+
+// IsZero : report whether the policy keeps nothing
+func (p Policy) IsZero() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0
+}
+
+// Prune : split the archives, sorted newest first as List returns them, into the ones the policy keeps and the
+// ones it removes. Days and weeks are counted in UTC.
+func Prune(archives []Archive, policy Policy) (keep []Archive, remove []Archive) {
+	kept := make([]bool, len(archives))
+	for i := 0; i < len(archives) && i < policy.KeepLast; i++ {
+		kept[i] = true
+	}
+
+	for _, rule := range []struct {
+		count  int
+		period func(time.Time) string
+	}{
+		{policy.KeepDaily, func(t time.Time) string { return t.UTC().Format("2006-01-02") }},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.UTC().ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+	} {
+		seen := map[string]bool{}
+		for i, archive := range archives {
+			if len(seen) >= rule.count {
+				break
+			}
+			period := rule.period(archive.Created)
+			if !seen[period] {
+				seen[period] = true
+				kept[i] = true
+			}
+		}
+	}
+
+	for i, archive := range archives {
+		if kept[i] {
+			keep = append(keep, archive)
+		} else {
+			remove = append(remove, archive)
+		}
+	}
+	return keep, remove
+}
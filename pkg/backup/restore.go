@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/util"
+	"github.com/sirupsen/logrus"
+)
+
+// CheckVault : a check for Restore opening the extracted vault with the credentials, a backup of another vault
+// or of another password is not restored
+func CheckVault(credentials *enpass.VaultCredentials, logLevel logrus.Level, noColor bool) func(extracted string) error {
+	return func(extracted string) error {
+		vault, err := enpass.NewVault(extracted, logLevel, noColor)
+		if err != nil {
+			return err
+		}
+		defer vault.Close()
+		return vault.Open(credentials, logLevel, noColor)
+	}
+}
+
+// Restore : replace the files of the vault with the ones of the archive. The archive is extracted next to the
+// vault and handed to check, which should open it, nothing is replaced when it fails. The current vault is backed
+// up to dir first, its path is returned. Attachments missing from the archive are removed.
+func Restore(path, vaultPath, dir string, check func(extracted string) error) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(vaultPath); err == nil {
+		vaultPath = resolved
+	}
+	if info, err := os.Stat(vaultPath); err == nil && !info.IsDir() {
+		return "", fmt.Errorf("%s is not a vault directory, backups are restored to one", vaultPath)
+	}
+	if exists, _ := util.FileOrDirectoryExists(filepath.Join(vaultPath, vaultFiles[0]+"-wal")); exists {
+		return "", fmt.Errorf("%s has a -wal file, the vault is being written to, close Enpass and try again", vaultPath)
+	}
+
+	// Extract on the same file system so the files can be renamed into place
+	extracted, err := os.MkdirTemp(filepath.Dir(vaultPath), ".enpass-restore-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create the restore directory: %s", err)
+	}
+	defer os.RemoveAll(extracted)
+
+	manifest, err := Extract(path, extracted)
+	if err != nil {
+		return "", err
+	}
+	if err := check(extracted); err != nil {
+		return "", fmt.Errorf("the backup does not open, nothing was restored: %s", err)
+	}
+
+	current := ""
+	if exists, _ := util.FileOrDirectoryExists(filepath.Join(vaultPath, vaultFiles[0])); exists {
+		if current, err = Create(vaultPath, dir); err != nil {
+			return "", fmt.Errorf("failed to back up the current vault, nothing was restored: %s", err)
+		}
+	} else if err := os.MkdirAll(vaultPath, 0700); err != nil {
+		return "", fmt.Errorf("failed to create the vault directory: %s", err)
+	}
+
+	restored := map[string]bool{}
+	for _, file := range manifest.Files {
+		restored[file.Name] = true
+		if err := os.Rename(filepath.Join(extracted, file.Name), filepath.Join(vaultPath, file.Name)); err != nil {
+			return current, fmt.Errorf("failed to restore %s, the vault may be incomplete: %s", file.Name, err)
+		}
+	}
+
+	attachments, _ := filepath.Glob(filepath.Join(vaultPath, "*.enpassattach"))
+	for _, attachment := range attachments {
+		if !restored[filepath.Base(attachment)] {
+			os.Remove(attachment)
+		}
+	}
+	for _, suffix := range []string{"-shm", "-journal"} {
+		os.Remove(filepath.Join(vaultPath, vaultFiles[0]+suffix))
+	}
+
+	return current, nil
+}
+
+// Resolve : the archive named by the argument, a path or the name of an archive of dir
+func Resolve(name, dir string) (string, error) {
+	for _, candidate := range []string{util.ExpandPath(name), filepath.Join(dir, name), filepath.Join(dir, name+archiveSuffix)} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+		if strings.ContainsRune(name, filepath.Separator) {
+			break
+		}
+	}
+	return "", fmt.Errorf("the backup %s does not exist", name)
+}
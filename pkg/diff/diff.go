@@ -0,0 +1,257 @@
+// Package diff : compare the items of two vaults, matched by uuid
+package diff
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// The kinds of item changes, in the order they are reported
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Trashed  = "trashed"
+	Restored = "restored"
+	Renamed  = "renamed"
+	Moved    = "moved"
+	Changed  = "changed"
+)
+
+// The kinds of field changes
+const (
+	FieldAdded     = "added"
+	FieldRemoved   = "removed"
+	FieldChanged   = "changed"
+	FieldRelabeled = "relabeled"
+)
+
+var kindOrder = []string{Added, Removed, Trashed, Restored, Renamed, Moved, Changed}
+
+// Report : the changes from the vault From to the vault To
+type Report struct {
+	From    string   `json:"from" yaml:"from"`
+	To      string   `json:"to" yaml:"to"`
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+// Change : a change of an item. An item renamed and moved has a change of each kind.
+type Change struct {
+	UUID     string `json:"uuid" yaml:"uuid"`
+	Title    string `json:"title" yaml:"title"`
+	Category string `json:"category" yaml:"category"`
+	Kind     string `json:"change" yaml:"change"`
+	// From and To are the titles of a renamed item and the folders of a moved one
+	From   string        `json:"from,omitempty" yaml:"from,omitempty"`
+	To     string        `json:"to,omitempty" yaml:"to,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FieldChange : a change of a field of a changed item. From and To are the values, or the labels of a relabeled
+// field. The values of secret fields are left out unless revealed.
+type FieldChange struct {
+	Label  string `json:"label" yaml:"label"`
+	Type   string `json:"type" yaml:"type"`
+	Kind   string `json:"change" yaml:"change"`
+	Secret bool   `json:"secret" yaml:"secret"`
+	From   string `json:"from,omitempty" yaml:"from,omitempty"`
+	To     string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// Vault : the items and folders of a side of the comparison
+type Vault struct {
+	Items   []enpass.Item
+	Folders []enpass.Folder
+}
+
+// Compare : the changes from the vault a to the vault b. Items are matched by uuid, their fields by uid. Secret
+// values are compared by hash, the one the vaults keep for passwords or their SHA-256, and only reported with
+// reveal.
+func Compare(a, b Vault, reveal bool) []Change {
+	before := map[string]enpass.Item{}
+	for _, item := range a.Items {
+		before[item.UUID] = item
+	}
+	after := map[string]enpass.Item{}
+	for _, item := range b.Items {
+		after[item.UUID] = item
+	}
+	pathsA := enpass.FolderPaths(a.Folders)
+	pathsB := enpass.FolderPaths(b.Folders)
+
+	changes := []Change{}
+	for _, item := range b.Items {
+		if _, ok := before[item.UUID]; !ok {
+			changes = append(changes, newChange(item, Added))
+		}
+	}
+	for _, old := range a.Items {
+		item, ok := after[old.UUID]
+		if !ok {
+			changes = append(changes, newChange(old, Removed))
+			continue
+		}
+
+		if !old.Trashed && item.Trashed {
+			changes = append(changes, newChange(item, Trashed))
+		} else if old.Trashed && !item.Trashed {
+			changes = append(changes, newChange(item, Restored))
+		}
+		if old.Title != item.Title {
+			change := newChange(item, Renamed)
+			change.From, change.To = old.Title, item.Title
+			changes = append(changes, change)
+		}
+		if from, to := folders(old, pathsA), folders(item, pathsB); from != to {
+			change := newChange(item, Moved)
+			change.From, change.To = from, to
+			changes = append(changes, change)
+		}
+		if fields := compareFields(old, item, reveal); len(fields) > 0 {
+			change := newChange(item, Changed)
+			change.Fields = fields
+			changes = append(changes, change)
+		}
+	}
+
+	rank := map[string]int{}
+	for i, kind := range kindOrder {
+		rank[kind] = i
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return rank[changes[i].Kind] < rank[changes[j].Kind]
+		}
+		if !strings.EqualFold(changes[i].Title, changes[j].Title) {
+			return strings.ToLower(changes[i].Title) < strings.ToLower(changes[j].Title)
+		}
+		return changes[i].UUID < changes[j].UUID
+	})
+	return changes
+}
+
+func newChange(item enpass.Item, kind string) Change {
+	return Change{UUID: item.UUID, Title: item.Title, Category: item.Category, Kind: kind}
+}
+
+// folders : the sorted, comma-separated folder paths of the item
+func folders(item enpass.Item, paths map[string]string) string {
+	names := []string{}
+	for _, uuid := range item.Folders {
+		if path, ok := paths[uuid]; ok {
+			names = append(names, path)
+		} else {
+			names = append(names, uuid)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// compareFields : the changes of the note and of the fields, in the order of the fields of b then the removed ones
+func compareFields(a, b enpass.Item, reveal bool) []FieldChange {
+	changes := []FieldChange{}
+	if a.Note != b.Note {
+		changes = append(changes, FieldChange{Label: "Note", Type: "note", Kind: FieldChanged, From: a.Note, To: b.Note})
+	}
+
+	before := map[int]enpass.ItemField{}
+	for _, field := range a.Fields {
+		before[field.UID] = field
+	}
+	seen := map[int]bool{}
+	for _, field := range b.Fields {
+		seen[field.UID] = true
+		old, ok := before[field.UID]
+		secret := field.IsSecret() || (ok && old.IsSecret())
+		switch {
+		case !ok:
+			if field.Value != "" {
+				changes = append(changes, fieldChange(field, FieldAdded, secret, "", field.Value, reveal))
+			}
+		case !sameValue(old, field, secret):
+			changes = append(changes, fieldChange(field, FieldChanged, secret, old.Value, field.Value, reveal))
+		}
+		if ok && old.Label != field.Label {
+			changes = append(changes, FieldChange{Label: field.Label, Type: field.Type, Kind: FieldRelabeled, Secret: secret, From: old.Label, To: field.Label})
+		}
+	}
+	for _, field := range a.Fields {
+		if !seen[field.UID] && field.Value != "" {
+			changes = append(changes, fieldChange(field, FieldRemoved, field.IsSecret(), field.Value, "", reveal))
+		}
+	}
+	return changes
+}
+
+func fieldChange(field enpass.ItemField, kind string, secret bool, from, to string, reveal bool) FieldChange {
+	change := FieldChange{Label: field.Label, Type: field.Type, Kind: kind, Secret: secret}
+	if !secret || reveal {
+		change.From, change.To = from, to
+	}
+	return change
+}
+
+// sameValue : compare the values of two fields, secret ones by hash. The hashes the vaults keep are compared when
+// both fields have one.
+func sameValue(a, b enpass.ItemField, secret bool) bool {
+	if !secret {
+		return a.Value == b.Value
+	}
+	if a.Hash != "" && b.Hash != "" {
+		return a.Hash == b.Hash
+	}
+	return sha256.Sum256([]byte(a.Value)) == sha256.Sum256([]byte(b.Value))
+}
+
+// WriteText : write the report as one line per change, followed by the changed fields
+func WriteText(w io.Writer, report Report) error {
+	if len(report.Changes) == 0 {
+		_, err := fmt.Fprintf(w, "No changes from %s to %s\n", report.From, report.To)
+		return err
+	}
+
+	for _, change := range report.Changes {
+		line := fmt.Sprintf("%-8s %s (%s, %s)", change.Kind, change.Title, change.Category, change.UUID)
+		switch change.Kind {
+		case Renamed:
+			line = fmt.Sprintf("%-8s %q -> %q (%s, %s)", change.Kind, change.From, change.To, change.Category, change.UUID)
+		case Moved:
+			line += fmt.Sprintf(": %s -> %s", orNone(change.From), orNone(change.To))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		for _, field := range change.Fields {
+			detail := ""
+			switch {
+			case field.Kind == FieldRelabeled:
+				detail = fmt.Sprintf("%q -> %q", field.From, field.To)
+			case field.Secret && field.From == "" && field.To == "":
+				detail = "(secret, use --reveal to show)"
+			case field.Kind == FieldAdded:
+				detail = fmt.Sprintf("%q", field.To)
+			case field.Kind == FieldRemoved:
+				detail = fmt.Sprintf("%q", field.From)
+			default:
+				detail = fmt.Sprintf("%q -> %q", field.From, field.To)
+			}
+			if _, err := fmt.Fprintf(w, "    %s %s: %s\n", field.Label, field.Kind, detail); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func orNone(folders string) string {
+	if folders == "" {
+		return "(no folder)"
+	}
+	return folders
+}
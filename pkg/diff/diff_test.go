@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func login(uuid, title string, fields ...enpass.ItemField) enpass.Item {
+	return enpass.Item{UUID: uuid, Title: title, Category: "login", Fields: fields}
+}
+
+func TestCompare(t *testing.T) {
+	folders := []enpass.Folder{{UUID: "f1", Title: "Work"}, {UUID: "f2", Title: "Home"}}
+	username := enpass.ItemField{UID: 1, Label: "Username", Type: "username", Value: "octocat"}
+	password := enpass.ItemField{UID: 2, Label: "Password", Type: "password", Value: "old"}
+
+	github := login("u1", "GitHub", username, password)
+	github.Folders = []string{"f1"}
+	renamed := login("u2", "Bank", username)
+	trashed := login("u3", "Mail", username)
+	removed := login("u4", "Forum", username)
+	unchanged := login("u5", "Wiki", username, password)
+	restored := login("u6", "Shop", username)
+	restored.Trashed = true
+	a := Vault{Items: []enpass.Item{github, renamed, trashed, removed, unchanged, restored}, Folders: folders}
+
+	github2 := login("u1", "GitHub",
+		enpass.ItemField{UID: 1, Label: "Login", Type: "username", Value: "octocat"},
+		enpass.ItemField{UID: 2, Label: "Password", Type: "password", Value: "new"},
+		enpass.ItemField{UID: 3, Label: "Website", Type: "url", Value: "https://github.com"},
+		enpass.ItemField{UID: 4, Label: "Empty", Type: "text"},
+	)
+	github2.Folders = []string{"f2"}
+	github2.Note = "moved"
+	renamed2 := login("u2", "My Bank", username)
+	trashed2 := login("u3", "Mail", username)
+	trashed2.Trashed = true
+	restored2 := login("u6", "Shop", username)
+	added := login("u7", "New", username)
+	b := Vault{Items: []enpass.Item{github2, renamed2, trashed2, unchanged, restored2, added}, Folders: folders}
+
+	want := []Change{
+		{UUID: "u7", Title: "New", Category: "login", Kind: Added},
+		{UUID: "u4", Title: "Forum", Category: "login", Kind: Removed},
+		{UUID: "u3", Title: "Mail", Category: "login", Kind: Trashed},
+		{UUID: "u6", Title: "Shop", Category: "login", Kind: Restored},
+		{UUID: "u2", Title: "My Bank", Category: "login", Kind: Renamed, From: "Bank", To: "My Bank"},
+		{UUID: "u1", Title: "GitHub", Category: "login", Kind: Moved, From: "Work", To: "Home"},
+		{UUID: "u1", Title: "GitHub", Category: "login", Kind: Changed, Fields: []FieldChange{
+			{Label: "Note", Type: "note", Kind: FieldChanged, To: "moved"},
+			{Label: "Login", Type: "username", Kind: FieldRelabeled, From: "Username", To: "Login"},
+			{Label: "Password", Type: "password", Kind: FieldChanged, Secret: true},
+			{Label: "Website", Type: "url", Kind: FieldAdded, To: "https://github.com"},
+		}},
+	}
+	if got := Compare(a, b, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+
+	revealed := Compare(a, b, true)
+	fields := revealed[len(revealed)-1].Fields
+	if fields[2].From != "old" || fields[2].To != "new" || !fields[2].Secret {
+		t.Errorf("the password change was not revealed: %+v", fields[2])
+	}
+
+	if got := Compare(a, a, false); len(got) != 0 {
+		t.Errorf("a vault differs from itself: %+v", got)
+	}
+}
+
+func TestCompareSecrets(t *testing.T) {
+	tests := []struct {
+		name   string
+		before enpass.ItemField
+		after  enpass.ItemField
+		secret bool
+	}{
+		{"password", enpass.ItemField{UID: 1, Type: "password", Value: "a"}, enpass.ItemField{UID: 1, Type: "password", Value: "b"}, true},
+		{"sensitive text", enpass.ItemField{UID: 1, Type: "text", Value: "a", Sensitive: true}, enpass.ItemField{UID: 1, Type: "text", Value: "b", Sensitive: true}, true},
+		{"card code", enpass.ItemField{UID: 1, Type: "ccCvc", Value: "123"}, enpass.ItemField{UID: 1, Type: "ccCvc", Value: "456"}, true},
+		{"pin", enpass.ItemField{UID: 1, Type: "pin", Value: "0000"}, enpass.ItemField{UID: 1, Type: "pin", Value: "1111"}, true},
+		{"no longer sensitive", enpass.ItemField{UID: 1, Type: "text", Value: "a", Sensitive: true}, enpass.ItemField{UID: 1, Type: "text", Value: "b"}, true},
+		{"plain text", enpass.ItemField{UID: 1, Type: "text", Value: "a"}, enpass.ItemField{UID: 1, Type: "text", Value: "b"}, false},
+	}
+	for _, test := range tests {
+		changes := Compare(
+			Vault{Items: []enpass.Item{login("u1", "Item", test.before)}},
+			Vault{Items: []enpass.Item{login("u1", "Item", test.after)}},
+			false,
+		)
+		if len(changes) != 1 || len(changes[0].Fields) != 1 {
+			t.Fatalf("%s: unexpected changes %+v", test.name, changes)
+		}
+		field := changes[0].Fields[0]
+		if field.Secret != test.secret || (test.secret && (field.From != "" || field.To != "")) {
+			t.Errorf("%s: got %+v", test.name, field)
+		}
+	}
+
+	// The hashes the vaults keep are compared, not the values
+	sameHash := Compare(
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Value: "a", Hash: "86f7e437"})}},
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Value: "b", Hash: "86f7e437"})}},
+		false,
+	)
+	if len(sameHash) != 0 {
+		t.Errorf("passwords with the same hash differ: %+v", sameHash)
+	}
+	otherHash := Compare(
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Hash: "86f7e437"})}},
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Hash: "e9d71f5e"})}},
+		false,
+	)
+	if len(otherHash) != 1 || otherHash[0].Fields[0].Kind != FieldChanged {
+		t.Errorf("passwords with other hashes are the same: %+v", otherHash)
+	}
+	oneHash := Compare(
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Value: "a", Hash: "86f7e437"})}},
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Value: "a"})}},
+		false,
+	)
+	if len(oneHash) != 0 {
+		t.Errorf("a password without a stored hash differs: %+v", oneHash)
+	}
+
+	removed := Compare(
+		Vault{Items: []enpass.Item{login("u1", "Item", enpass.ItemField{UID: 1, Type: "password", Value: "a"})}},
+		Vault{Items: []enpass.Item{login("u1", "Item")}},
+		false,
+	)
+	if field := removed[0].Fields[0]; field.Kind != FieldRemoved || !field.Secret || field.From != "" {
+		t.Errorf("a removed password was shown: %+v", field)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteText(&out, Report{From: "a", To: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "No changes from a to b\n" {
+		t.Errorf("got %q", out.String())
+	}
+
+	out.Reset()
+	report := Report{Changes: []Change{
+		{UUID: "u1", Title: "GitHub", Category: "login", Kind: Moved, To: "Home"},
+		{UUID: "u1", Title: "GitHub", Category: "login", Kind: Changed, Fields: []FieldChange{
+			{Label: "Password", Type: "password", Kind: FieldChanged, Secret: true},
+			{Label: "Website", Type: "url", Kind: FieldAdded, To: "https://github.com"},
+		}},
+	}}
+	if err := WriteText(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"moved    GitHub (login, u1): (no folder) -> Home",
+		"changed  GitHub (login, u1)",
+		"    Password changed: (secret, use --reveal to show)",
+		`    Website added: "https://github.com"`,
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
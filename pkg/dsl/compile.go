@@ -0,0 +1,117 @@
+package dsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+const urlField = "url"
+
+var (
+	// fieldColumns : the fields usable as field:value and the column they are compared against
+	fieldColumns = map[string]string{
+		"category": "item.category",
+		"label":    "itemfield.label",
+		"login":    "item.subtitle",
+		"note":     "item.note",
+		"subtitle": "item.subtitle",
+		"title":    "item.title",
+		"type":     "itemfield.type",
+		"uuid":     "item.uuid",
+	}
+
+	// flagColumns : the bare words usable as flags and the condition they stand for
+	flagColumns = map[string]string{
+		"archived": "item.archived != 0",
+		"favorite": "item.favorite != 0",
+		"trashed":  "item.trashed != 0",
+	}
+)
+
+// Compiled : a query turned into a parameterized WHERE condition
+type Compiled struct {
+	SQL    string
+	Args   []interface{}
+	Fields []string
+}
+
+// Uses : report whether the query references the field or flag
+func (c *Compiled) Uses(name string) bool {
+	for _, field := range c.Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Compile : parse the query and turn it into a condition for the item/itemfield join. Values never end up in
+// the SQL text, they are passed as arguments. Matching is case-insensitive with LIKE unless flagCaseSensitive
+// is set, then GLOB is used.
+func Compile(query string, flagCaseSensitive bool) (*Compiled, error) {
+	node, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Compiled{}
+	c.SQL = c.build(node, flagCaseSensitive)
+	return c, nil
+}
+
+func (c *Compiled) build(node Node, flagCaseSensitive bool) string {
+	switch n := node.(type) {
+	case And:
+		return fmt.Sprintf("(%s AND %s)", c.build(n.Left, flagCaseSensitive), c.build(n.Right, flagCaseSensitive))
+	case Or:
+		return fmt.Sprintf("(%s OR %s)", c.build(n.Left, flagCaseSensitive), c.build(n.Right, flagCaseSensitive))
+	case Not:
+		return fmt.Sprintf("(NOT %s)", c.build(n.Operand, flagCaseSensitive))
+	case Flag:
+		c.use(n.Name)
+		return fmt.Sprintf("(%s)", flagColumns[n.Name])
+	case Match:
+		c.use(n.Field)
+		condition, arg := comparison(n.Value, flagCaseSensitive)
+		c.Args = append(c.Args, arg)
+		if n.Field == urlField {
+			return fmt.Sprintf("(EXISTS (SELECT 1 FROM itemfield AS urlfield WHERE urlfield.item_uuid = item.uuid AND urlfield.type = 'url' AND urlfield.deleted = 0 AND urlfield.value %s))", condition)
+		}
+		return fmt.Sprintf("(%s %s)", fieldColumns[n.Field], condition)
+	}
+	return ""
+}
+
+func (c *Compiled) use(name string) {
+	if !c.Uses(name) {
+		c.Fields = append(c.Fields, name)
+	}
+}
+
+// comparison : return the operator and argument for a value with * and ? wildcards
+func comparison(value string, flagCaseSensitive bool) (string, string) {
+	if flagCaseSensitive {
+		if !strings.ContainsAny(value, "*?") {
+			return "= ?", value
+		}
+		// GLOB has no escape character, brackets are escaped by wrapping them in a class
+		escaped := strings.NewReplacer("[", "[[]").Replace(value)
+		return "GLOB ?", escaped
+	}
+
+	var pattern strings.Builder
+	for _, r := range value {
+		switch r {
+		case '*':
+			pattern.WriteRune('%')
+		case '?':
+			pattern.WriteRune('_')
+		case '%', '_', '\\':
+			pattern.WriteRune('\\')
+			pattern.WriteRune(r)
+		default:
+			pattern.WriteRune(r)
+		}
+	}
+	return `LIKE ? ESCAPE '\'`, pattern.String()
+}
@@ -0,0 +1,83 @@
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		query         string
+		caseSensitive bool
+		sql           string
+		args          []interface{}
+		fields        []string
+	}{
+		{
+			query:  "title:git*",
+			sql:    `(item.title LIKE ? ESCAPE '\')`,
+			args:   []interface{}{"git%"},
+			fields: []string{"title"},
+		},
+		{
+			query:  "login:?ob title:50%_off",
+			sql:    `((item.subtitle LIKE ? ESCAPE '\') AND (item.title LIKE ? ESCAPE '\'))`,
+			args:   []interface{}{"_ob", `50\%\_off`},
+			fields: []string{"login", "title"},
+		},
+		{
+			query:         "title:Git",
+			caseSensitive: true,
+			sql:           "(item.title = ?)",
+			args:          []interface{}{"Git"},
+			fields:        []string{"title"},
+		},
+		{
+			query:         "title:[x]*",
+			caseSensitive: true,
+			sql:           "(item.title GLOB ?)",
+			args:          []interface{}{"[[]x]*"},
+			fields:        []string{"title"},
+		},
+		{
+			query:  "url:*github.com*",
+			sql:    `(EXISTS (SELECT 1 FROM itemfield AS urlfield WHERE urlfield.item_uuid = item.uuid AND urlfield.type = 'url' AND urlfield.deleted = 0 AND urlfield.value LIKE ? ESCAPE '\'))`,
+			args:   []interface{}{"%github.com%"},
+			fields: []string{"url"},
+		},
+		{
+			query:  "trashed OR !favorite OR trashed",
+			sql:    "(((item.trashed != 0) OR (NOT (item.favorite != 0))) OR (item.trashed != 0))",
+			fields: []string{"trashed", "favorite"},
+		},
+		{
+			query:  "category:login (type:password OR label:pin)",
+			sql:    `((item.category LIKE ? ESCAPE '\') AND ((itemfield.type LIKE ? ESCAPE '\') OR (itemfield.label LIKE ? ESCAPE '\')))`,
+			args:   []interface{}{"login", "password", "pin"},
+			fields: []string{"category", "type", "label"},
+		},
+	}
+
+	for _, test := range tests {
+		compiled, err := Compile(test.query, test.caseSensitive)
+		if err != nil {
+			t.Errorf("Compile(%q): %s", test.query, err)
+			continue
+		}
+		if compiled.SQL != test.sql {
+			t.Errorf("Compile(%q) SQL\n got %s\nwant %s", test.query, compiled.SQL, test.sql)
+		}
+		if !reflect.DeepEqual(compiled.Args, test.args) {
+			t.Errorf("Compile(%q) args: got %q, want %q", test.query, compiled.Args, test.args)
+		}
+		if !reflect.DeepEqual(compiled.Fields, test.fields) {
+			t.Errorf("Compile(%q) fields: got %v, want %v", test.query, compiled.Fields, test.fields)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	if _, err := Compile("title:a OR", false); err == nil {
+		t.Error("Compile accepted an incomplete query")
+	}
+}
@@ -0,0 +1,159 @@
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenTerm
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of query"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	}
+	return "term"
+}
+
+type token struct {
+	kind  tokenKind
+	pos   int
+	field string
+	value string
+	text  string
+}
+
+// SyntaxError : a query that could not be parsed, rendered with a caret under the offending column
+type SyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at column %d: %s\n  %s\n  %s^", e.Pos+1, e.Msg, e.Query, strings.Repeat(" ", e.Pos))
+}
+
+// lex : split the query into tokens. Terms are either bare words (flags) or field:value pairs, values may be
+// double-quoted to include spaces and parentheses, a backslash escapes the next character inside quotes.
+func lex(query string) ([]token, error) {
+	var (
+		tokens []token
+		runes  = []rune(query)
+		i      int
+	)
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: i, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: i, text: ")"})
+			i++
+		case r == '!':
+			// ! negates the term it is glued to, e.g. !trashed
+			tokens = append(tokens, token{kind: tokenNot, pos: i, text: "!"})
+			i++
+		default:
+			start := i
+			var (
+				word   strings.Builder
+				field  string
+				quoted bool
+			)
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] == ':' && field == "" && !quoted {
+					field = word.String()
+					word.Reset()
+					i++
+					if field == "" {
+						return nil, &SyntaxError{Query: query, Pos: start, Msg: "missing field name before ':'"}
+					}
+					continue
+				}
+				if runes[i] == '"' {
+					quoted = true
+					end, value, err := lexQuoted(query, runes, i)
+					if err != nil {
+						return nil, err
+					}
+					word.WriteString(value)
+					i = end
+					continue
+				}
+				word.WriteRune(runes[i])
+				i++
+			}
+
+			text := string(runes[start:i])
+			tok := token{kind: tokenTerm, pos: start, text: text}
+			if field != "" {
+				tok.field = strings.ToLower(field)
+				tok.value = word.String()
+				if tok.value == "" && !quoted {
+					return nil, &SyntaxError{Query: query, Pos: i, Msg: fmt.Sprintf("missing value after '%s:'", field)}
+				}
+			} else if !quoted {
+				switch strings.ToUpper(text) {
+				case "AND", "&&":
+					tok.kind = tokenAnd
+				case "OR", "||":
+					tok.kind = tokenOr
+				case "NOT":
+					tok.kind = tokenNot
+				default:
+					tok.value = strings.ToLower(text)
+				}
+			} else {
+				return nil, &SyntaxError{Query: query, Pos: start, Msg: "a quoted value needs a field, e.g. title:\"My Bank\""}
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// lexQuoted : read a double-quoted string starting at runes[start], returning the index after the closing quote
+func lexQuoted(query string, runes []rune, start int) (int, string, error) {
+	var value strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				value.WriteRune(runes[i])
+			}
+		case '"':
+			return i + 1, value.String(), nil
+		default:
+			value.WriteRune(runes[i])
+		}
+	}
+	return 0, "", &SyntaxError{Query: query, Pos: start, Msg: "unterminated quoted value"}
+}
@@ -0,0 +1,91 @@
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []token
+	}{
+		{
+			query: "title:git*",
+			want: []token{
+				{kind: tokenTerm, pos: 0, field: "title", value: "git*", text: "title:git*"},
+				{kind: tokenEOF, pos: 10},
+			},
+		},
+		{
+			query: "(Trashed || !favorite)",
+			want: []token{
+				{kind: tokenLParen, pos: 0, text: "("},
+				{kind: tokenTerm, pos: 1, value: "trashed", text: "Trashed"},
+				{kind: tokenOr, pos: 9, text: "||"},
+				{kind: tokenNot, pos: 12, text: "!"},
+				{kind: tokenTerm, pos: 13, value: "favorite", text: "favorite"},
+				{kind: tokenRParen, pos: 21, text: ")"},
+				{kind: tokenEOF, pos: 22},
+			},
+		},
+		{
+			query: `Title:"My (old) \"Bank\"" and`,
+			want: []token{
+				{kind: tokenTerm, pos: 0, field: "title", value: `My (old) "Bank"`, text: `Title:"My (old) \"Bank\""`},
+				{kind: tokenAnd, pos: 26, text: "and"},
+				{kind: tokenEOF, pos: 29},
+			},
+		},
+		{
+			query: `note:""`,
+			want: []token{
+				{kind: tokenTerm, pos: 0, field: "note", text: `note:""`},
+				{kind: tokenEOF, pos: 7},
+			},
+		},
+		{
+			query: "url:https://example.com",
+			want: []token{
+				{kind: tokenTerm, pos: 0, field: "url", value: "https://example.com", text: "url:https://example.com"},
+				{kind: tokenEOF, pos: 23},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := lex(test.query)
+		if err != nil {
+			t.Errorf("lex(%q): %s", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("lex(%q)\n got %+v\nwant %+v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{query: "title:", pos: 6, msg: "missing value after 'title:'"},
+		{query: "a AND :x", pos: 6, msg: "missing field name before ':'"},
+		{query: `title:"open`, pos: 6, msg: "unterminated quoted value"},
+		{query: `"My Bank"`, pos: 0, msg: `a quoted value needs a field, e.g. title:"My Bank"`},
+	}
+
+	for _, test := range tests {
+		_, err := lex(test.query)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("lex(%q): got %v, want a syntax error", test.query, err)
+			continue
+		}
+		if syntaxErr.Pos != test.pos || syntaxErr.Msg != test.msg {
+			t.Errorf("lex(%q): got %q at %d, want %q at %d", test.query, syntaxErr.Msg, syntaxErr.Pos, test.msg, test.pos)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package dsl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node : a node of the parsed query
+type Node interface {
+	node()
+}
+
+// And : both sides must match
+type And struct {
+	Left, Right Node
+}
+
+// Or : either side must match
+type Or struct {
+	Left, Right Node
+}
+
+// Not : the operand must not match
+type Not struct {
+	Operand Node
+}
+
+// Match : field:value, value may contain * and ? wildcards
+type Match struct {
+	Field string
+	Value string
+	Pos   int
+}
+
+// Flag : a bare word testing a boolean item attribute, e.g. trashed
+type Flag struct {
+	Name string
+	Pos  int
+}
+
+func (And) node()   {}
+func (Or) node()    {}
+func (Not) node()   {}
+func (Match) node() {}
+func (Flag) node()  {}
+
+type parser struct {
+	query  string
+	tokens []token
+	pos    int
+}
+
+// Parse : parse a query such as `category:login AND (title:git* OR url:*github.com*) AND NOT trashed`.
+// NOT binds tighter than AND, which binds tighter than OR. Adjacent terms without an operator are AND-ed.
+func Parse(query string) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{query: query, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, p.errorf(p.peek(), "empty query")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenRParen {
+			return nil, p.errorf(tok, "unbalanced ')'")
+		}
+		return nil, p.errorf(tok, "unexpected %s", describe(tok))
+	}
+
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Query: p.query, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenTerm, tokenNot, tokenLParen:
+			// implicit AND
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseNot() (Node, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')' to close the '(' at column %d, found %s", tok.pos+1, describe(closing))
+		}
+		p.next()
+		return node, nil
+	case tokenTerm:
+		if tok.field == "" {
+			if _, ok := flagColumns[tok.value]; !ok {
+				return nil, p.errorf(tok, "unknown flag %q, expected field:value or one of: %s", tok.text, strings.Join(sortedKeys(flagColumns), ", "))
+			}
+			return Flag{Name: tok.value, Pos: tok.pos}, nil
+		}
+		if _, ok := fieldColumns[tok.field]; !ok && tok.field != urlField {
+			return nil, p.errorf(tok, "unknown field %q, valid fields: %s", tok.field, strings.Join(validFields(), ", "))
+		}
+		return Match{Field: tok.field, Value: tok.value, Pos: tok.pos}, nil
+	}
+	return nil, p.errorf(tok, "expected a term, NOT or '(', found %s", describe(tok))
+}
+
+func describe(tok token) string {
+	if tok.kind == tokenTerm {
+		return fmt.Sprintf("%q", tok.text)
+	}
+	return tok.kind.String()
+}
+
+func validFields() []string {
+	return append(sortedKeys(fieldColumns), urlField)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
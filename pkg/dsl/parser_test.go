@@ -0,0 +1,107 @@
+package dsl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Node
+	}{
+		{
+			query: "category:login",
+			want:  Match{Field: "category", Value: "login", Pos: 0},
+		},
+		{
+			query: "trashed",
+			want:  Flag{Name: "trashed", Pos: 0},
+		},
+		{
+			// NOT binds tighter than AND, which binds tighter than OR
+			query: "title:1 OR login:2 AND NOT note:3",
+			want: Or{
+				Left: Match{Field: "title", Value: "1", Pos: 0},
+				Right: And{
+					Left:  Match{Field: "login", Value: "2", Pos: 11},
+					Right: Not{Operand: Match{Field: "note", Value: "3", Pos: 27}},
+				},
+			},
+		},
+		{
+			query: "(title:1 OR login:2) note:3",
+			want: And{
+				Left: Or{
+					Left:  Match{Field: "title", Value: "1", Pos: 1},
+					Right: Match{Field: "login", Value: "2", Pos: 12},
+				},
+				Right: Match{Field: "note", Value: "3", Pos: 21},
+			},
+		},
+		{
+			// operators of the same precedence associate to the left
+			query: "title:1 title:2 && title:3",
+			want: And{
+				Left: And{
+					Left:  Match{Field: "title", Value: "1", Pos: 0},
+					Right: Match{Field: "title", Value: "2", Pos: 8},
+				},
+				Right: Match{Field: "title", Value: "3", Pos: 19},
+			},
+		},
+		{
+			query: "NOT NOT favorite",
+			want:  Not{Operand: Not{Operand: Flag{Name: "favorite", Pos: 8}}},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q)\n got %#v\nwant %#v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{query: "", pos: 0, msg: "empty query"},
+		{query: "   ", pos: 3, msg: "empty query"},
+		{query: "title:a)", pos: 7, msg: "unbalanced ')'"},
+		{query: "(title:a", pos: 8, msg: "expected ')' to close the '(' at column 1, found end of query"},
+		{query: "title:a AND", pos: 11, msg: "expected a term, NOT or '(', found end of query"},
+		{query: "OR title:a", pos: 0, msg: "expected a term, NOT or '(', found OR"},
+		{query: "title:a ()", pos: 9, msg: "expected a term, NOT or '(', found ')'"},
+		{query: "title:a deleted", pos: 8, msg: `unknown flag "deleted", expected field:value or one of: archived, favorite, trashed`},
+		{query: "password:x", pos: 0, msg: `unknown field "password", valid fields: category, label, login, note, subtitle, title, type, uuid, url`},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.query)
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q): got %v, want a syntax error", test.query, err)
+			continue
+		}
+		if syntaxErr.Pos != test.pos || syntaxErr.Msg != test.msg {
+			t.Errorf("Parse(%q): got %q at %d, want %q at %d", test.query, syntaxErr.Msg, syntaxErr.Pos, test.msg, test.pos)
+		}
+	}
+}
+
+func TestSyntaxErrorCaret(t *testing.T) {
+	_, err := Parse("title:a )")
+	want := "query syntax error at column 9: unbalanced ')'\n  title:a )\n          ^"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
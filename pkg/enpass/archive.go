@@ -0,0 +1,179 @@
+package enpass
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// attachmentSuffix : the extension of the attachment files next to the database
+const attachmentSuffix = ".enpassattach"
+
+// archiveEntry : a file of an archive, opened on demand
+type archiveEntry struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// extractVaultArchive : extract the vault held by the .tar.gz, .tar or .zip archive at archivePath, e.g. an
+// Enpass backup, into a new private temporary directory and return it. The vault is the directory of the archive
+// holding vault.enpassdb, only the database, vault.json and the attachments are extracted. The caller removes the
+// directory.
+func extractVaultArchive(archivePath string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", errors.Wrap(err, "could not open the vault archive")
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	magic, _ := reader.Peek(4)
+
+	dir, err := os.MkdirTemp("", "enpass-vault-*")
+	if err != nil {
+		return "", errors.Wrap(err, "could not create a directory for the vault archive")
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		err = extractVaultZip(file, dir)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(reader); err == nil {
+			err = extractVaultTar(tar.NewReader(gz), dir)
+		}
+	default:
+		err = extractVaultTar(tar.NewReader(reader), dir)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", errors.Wrapf(err, "could not read the vault from %s", archivePath)
+	}
+	return dir, nil
+}
+
+func extractVaultZip(file *os.File, dir string) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	entries := []archiveEntry{}
+	for _, f := range archive.File {
+		if f.Mode().IsRegular() {
+			entries = append(entries, archiveEntry{name: f.Name, open: f.Open})
+		}
+	}
+	return extractVaultEntries(entries, dir)
+}
+
+func extractVaultTar(archive *tar.Reader, dir string) error {
+	// A tar archive is read once, keep the vault files of every directory until the vault is known
+	contents := map[string][]byte{}
+	entries := []archiveEntry{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "not a tar, tar.gz or zip archive")
+		}
+		if header.Typeflag != tar.TypeReg || !isVaultFile(path.Base(header.Name)) {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		name := header.Name
+		contents[name] = data
+		entries = append(entries, archiveEntry{name: name, open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(contents[name])), nil
+		}})
+	}
+	return extractVaultEntries(entries, dir)
+}
+
+// extractVaultEntries : write the vault files of the one directory of the archive holding a database into dir.
+// Only base names are written, the names of the archive never escape dir.
+func extractVaultEntries(entries []archiveEntry, dir string) error {
+	vaults := []string{}
+	for _, entry := range entries {
+		if path.Base(entry.name) == vaultFileName {
+			vaults = append(vaults, path.Dir(entry.name))
+		}
+	}
+	if len(vaults) == 0 {
+		return errors.New("the archive holds no " + vaultFileName)
+	} else if len(vaults) > 1 {
+		sort.Strings(vaults)
+		return errors.New("the archive holds several vaults: " + strings.Join(vaults, ", "))
+	}
+
+	for _, entry := range entries {
+		name := path.Base(entry.name)
+		if path.Dir(entry.name) != vaults[0] || !isVaultFile(name) {
+			continue
+		}
+		if err := extractVaultEntry(entry, dir+string(os.PathSeparator)+name); err != nil {
+			return errors.Wrapf(err, "could not extract %s", entry.name)
+		}
+	}
+	return nil
+}
+
+func extractVaultEntry(entry archiveEntry, target string) error {
+	in, err := entry.open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func isVaultFile(name string) bool {
+	return name == vaultFileName || name == vaultInfoFileName || (strings.HasSuffix(name, attachmentSuffix) && name != attachmentSuffix)
+}
+
+// IsReadOnly : report whether the vault was opened from an archive, its changes could not be kept
+func (v *Vault) IsReadOnly() bool {
+	return v.archivePath != ""
+}
+
+// checkWritable : fail when the vault was opened from an archive
+func (v *Vault) checkWritable() error {
+	if v.IsReadOnly() {
+		return errors.New("the vault was opened from the archive " + v.archivePath + " and is read-only")
+	}
+	return nil
+}
+
+// removeExtracted : remove the temporary directory of a vault opened from an archive
+func (v *Vault) removeExtracted() {
+	if v.extractedPath != "" {
+		os.RemoveAll(v.extractedPath)
+		v.extractedPath = ""
+	}
+}
@@ -0,0 +1,98 @@
+package enpass_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+	"github.com/sirupsen/logrus"
+)
+
+// archiveVault : write the files of the vault directory into a .tar.gz under a directory named like the vault
+func archiveVault(t *testing.T, vaultPath string) string {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "vault.tar.gz")
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	archive := tar.NewWriter(gz)
+
+	files, err := os.ReadDir(vaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(vaultPath, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := &tar.Header{Name: "primary/" + file.Name(), Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestOpenArchive(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	archivePath := archiveVault(t, fixture.Path)
+
+	vault, err := enpass.NewVault(archivePath, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vault.Close()
+	if err := vault.Open(fixture.Credentials(), logrus.ErrorLevel, true); err != nil {
+		t.Fatal(err)
+	}
+	if !vault.IsReadOnly() {
+		t.Error("a vault opened from an archive is writable")
+	}
+
+	items, err := vault.GetItemsByUUID([]string{enpasstest.ItemGitHub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || len(items[0].Attachments) != 2 || string(items[0].Attachments[0].Data) != "1111-2222\n3333-4444\n" {
+		t.Errorf("the attachments were not read from the archive: %+v", items)
+	}
+	if err := vault.AddItems([]enpass.Item{{Title: "New"}}); err == nil {
+		t.Error("an item was written to a vault opened from an archive")
+	}
+}
+
+func TestOpenVaultBrokenArchive(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	data, err := os.ReadFile(archiveVault(t, fixture.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "truncated.tar.gz")
+	if err := os.WriteFile(truncated, data[:len(data)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	vault, credentials, err := enpass.OpenVault(logger, false, true, truncated, "", logrus.ErrorLevel, true)
+	if err == nil || vault != nil || credentials != nil {
+		t.Errorf("a truncated archive was opened: %v", err)
+	}
+}
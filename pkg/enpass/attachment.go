@@ -0,0 +1,152 @@
+package enpass
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Attachment : a file attached to an item. Enpass keeps small attachments in the attachment table, encrypted with
+// a key of their own the way password fields are, and larger ones in <uuid>.enpassattach files next to the
+// vault. Only the first kind is read, the others are External and carry no Data.
+type Attachment struct {
+	UUID     string
+	Name     string
+	Mime     string
+	Size     int64
+	Data     []byte
+	External bool
+}
+
+type rawAttachment struct {
+	UUID     string
+	ItemUUID string
+	Name     string
+	Mime     string
+	Size     int64
+	Data     []byte
+	Key      []byte
+}
+
+// attachmentCipher : the AES-GCM cipher of an attachment key, which holds a 32 byte key and a 12 byte nonce
+func attachmentCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 44 {
+		return nil, errors.Errorf("the attachment key holds %d bytes instead of 44", len(key))
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not initialize attachment cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// attachmentAAD : the additional authenticated data of an attachment, its uuid without the dashes
+func attachmentAAD(uuid string) ([]byte, error) {
+	header, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode attachment hex AAD")
+	}
+	return header, nil
+}
+
+// decryptAttachment : the content of an attachment row, the data holds the ciphertext and the GCM tag
+func decryptAttachment(row rawAttachment) ([]byte, error) {
+	aesgcm, err := attachmentCipher(row.Key)
+	if err != nil {
+		return nil, err
+	}
+	header, err := attachmentAAD(row.UUID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aesgcm.Open(nil, row.Key[32:], row.Data, header)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decrypt attachment")
+	}
+	return data, nil
+}
+
+// getAttachments : the attachments of the items, keyed by item uuid, in the order they were added
+func (v *Vault) getAttachments(uuids []string) (map[string][]Attachment, error) {
+	attachments := map[string][]Attachment{}
+	if !v.db.Migrator().HasTable("attachment") {
+		return attachments, nil
+	}
+
+	for start := 0; start < len(uuids); start += itemQueryChunk {
+		chunk := uuids[start:min(start+itemQueryChunk, len(uuids))]
+
+		var rows []rawAttachment
+		err := v.db.Select("uuid", "item_uuid", "name", "mime", "size", "data", "key").
+			Table("attachment").
+			Where("item_uuid IN ?", chunk).
+			Order("ID").
+			Find(&rows).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "could not retrieve the attachments")
+		}
+
+		for _, row := range rows {
+			attachment := Attachment{UUID: row.UUID, Name: row.Name, Mime: row.Mime, Size: row.Size}
+			if len(row.Data) <= 0 {
+				// The content lives in a .enpassattach file, which is not read
+				attachment.External = true
+			} else {
+				data, err := decryptAttachment(row)
+				if err != nil {
+					return nil, errors.Wrapf(err, "could not decrypt the attachment %q", row.Name)
+				}
+				attachment.Data = data
+			}
+			attachments[row.ItemUUID] = append(attachments[row.ItemUUID], attachment)
+		}
+	}
+
+	return attachments, nil
+}
+
+// insertAttachment : write an attachment of the item into the attachment table, encrypted with a new key.
+// External attachments are written without content, their .enpassattach file is left alone. An attachment with the
+// uuid of an existing one replaces it.
+func insertAttachment(tx *gorm.DB, itemUUID string, attachment Attachment) error {
+	if attachment.UUID == "" {
+		attachment.UUID = NewUUID()
+	}
+	now := time.Now().Unix()
+
+	if attachment.External {
+		return tx.Exec(
+			`INSERT OR REPLACE INTO attachment (uuid, item_uuid, name, mime, hash, size, kind, data, key, extra, created_at, updated_at)
+			VALUES (?, ?, ?, ?, '', ?, 'file', NULL, NULL, '', ?, ?)`,
+			attachment.UUID, itemUUID, attachment.Name, attachment.Mime, attachment.Size, now, now,
+		).Error
+	}
+
+	key := make([]byte, 44)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	aesgcm, err := attachmentCipher(key)
+	if err != nil {
+		return err
+	}
+	header, err := attachmentAAD(attachment.UUID)
+	if err != nil {
+		return err
+	}
+	sum := sha1.Sum(attachment.Data)
+
+	return tx.Exec(
+		`INSERT OR REPLACE INTO attachment (uuid, item_uuid, name, mime, hash, size, kind, data, key, extra, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'inline', ?, ?, '', ?, ?)`,
+		attachment.UUID, itemUUID, attachment.Name, attachment.Mime, hex.EncodeToString(sum[:]), len(attachment.Data),
+		aesgcm.Seal(nil, key[32:], attachment.Data, header), key, now, now,
+	).Error
+}
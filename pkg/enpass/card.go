@@ -99,6 +99,11 @@ type Card struct {
 	Icon           string `yaml:"icon,omitempty"`
 	DecryptedValue string `yaml:"decrypted_value,omitempty"`
 
+	// unix timestamps behind Created, Updated and LastUsed
+	CreatedAt  int64 `yaml:"-"`
+	UpdatedAt  int64 `yaml:"-"`
+	LastUsedAt int64 `yaml:"-"`
+
 	// encrypted
 	RawValue string `yaml:"raw_value,omitempty"`
 	Key      []byte `yaml:"key,omitempty"`
@@ -123,22 +128,25 @@ func (c *Card) Decrypt() error {
 		return nil
 	}
 
+	// If you deleted an item from Enpass, it stays in the database, but the
+	// entries are cleared
+	if len(c.Key) == 0 {
+		return errors.New("this item has been deleted")
+	}
+
 	// The key object is saved in binary from and actually consists of the
 	// AES key (32 bytes) and a nonce (12 bytes) for GCM
+	if len(c.Key) != 44 {
+		return errors.Errorf("the item key holds %d bytes instead of 44", len(c.Key))
+	}
 	key := c.Key[:32]
 	nonce := c.Key[32:]
 
-	// If you deleted an item from Enpass, it stays in the database, but the
-	// entries are cleared
-	if len(nonce) == 0 {
-		errors.New("this item has been deleted")
-	}
-
 	// The value object holds the ciphertext (same length as plaintext) +
 	// (authentication) tag (16 bytes) and is stored in hex
 	ciphertextAndTag, err := hex.DecodeString(c.RawValue)
 	if err != nil {
-		errors.Wrap(err, "could not decode card hex cipherstring")
+		return errors.Wrap(err, "could not decode card hex cipherstring")
 	}
 
 	// As additional authenticated data (AAD) they use the UUID but without
@@ -169,3 +177,33 @@ func (c *Card) Decrypt() error {
 
 	return nil
 }
+
+// Encrypt : the reverse of Decrypt, store DecryptedValue of a password field in RawValue with the item key
+func (c *Card) Encrypt() error {
+	if c.Type != "password" || c.DecryptedValue == "" {
+		c.RawValue = c.DecryptedValue
+		return nil
+	}
+	if len(c.Key) != 44 {
+		return errors.New("the item key must hold a 32 byte key and a 12 byte nonce")
+	}
+
+	header, err := hex.DecodeString(strings.ReplaceAll(c.UUID, "-", ""))
+	if err != nil {
+		return errors.Wrap(err, "could not decode card hex AAD")
+	}
+
+	block, err := aes.NewCipher(c.Key[:32])
+	if err != nil {
+		return errors.Wrap(err, "could not initialize card cipher")
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return errors.Wrap(err, "could not initialize GCM block")
+	}
+
+	c.RawValue = hex.EncodeToString(aesgcm.Seal(nil, c.Key[32:], []byte(c.DecryptedValue), header))
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package enpass
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCardEncryptDecrypt(t *testing.T) {
+	key := append(bytes.Repeat([]byte{7}, 32), bytes.Repeat([]byte{9}, 12)...)
+	card := Card{UUID: "a2ec30c0-aeed-41f7-aed7-cc50e69ff506", Type: "password", Key: key, DecryptedValue: "s3cret"}
+
+	if err := card.Encrypt(); err != nil {
+		t.Fatal(err)
+	}
+	// The ciphertext is as long as the plaintext, followed by the 16 byte tag, in hex
+	if len(card.RawValue) != 2*(len("s3cret")+16) {
+		t.Fatalf("unexpected ciphertext %q", card.RawValue)
+	}
+
+	decrypted := Card{UUID: card.UUID, Type: "password", Key: key, RawValue: card.RawValue}
+	if err := decrypted.Decrypt(); err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.DecryptedValue != "s3cret" {
+		t.Errorf("got %q, want %q", decrypted.DecryptedValue, "s3cret")
+	}
+
+	// The uuid is the additional data, the value does not decrypt under another item
+	moved := Card{UUID: "b2ec30c0-aeed-41f7-aed7-cc50e69ff506", Type: "password", Key: key, RawValue: card.RawValue}
+	if err := moved.Decrypt(); err == nil {
+		t.Error("the value decrypted under another uuid")
+	}
+	wrongKey := append(bytes.Repeat([]byte{8}, 32), key[32:]...)
+	if err := (&Card{UUID: card.UUID, Type: "password", Key: wrongKey, RawValue: card.RawValue}).Decrypt(); err == nil {
+		t.Error("the value decrypted with another key")
+	}
+}
+
+func TestCardEncryptPlaintext(t *testing.T) {
+	for _, card := range []Card{
+		{Type: "username", DecryptedValue: "octocat"},
+		{Type: "password", DecryptedValue: ""},
+	} {
+		if err := card.Encrypt(); err != nil {
+			t.Fatal(err)
+		}
+		if card.RawValue != card.DecryptedValue {
+			t.Errorf("%s: got %q, want the plain value", card.Type, card.RawValue)
+		}
+	}
+
+	card := Card{Type: "text", RawValue: "plain"}
+	if err := card.Decrypt(); err != nil || card.DecryptedValue != "" {
+		t.Errorf("a plain field was decrypted: %q, %v", card.DecryptedValue, err)
+	}
+}
+
+func TestCardEncryptErrors(t *testing.T) {
+	if err := (&Card{UUID: "a2ec30c0", Type: "password", Key: []byte("short"), DecryptedValue: "x"}).Encrypt(); err == nil {
+		t.Error("a short key was accepted")
+	}
+	if err := (&Card{UUID: "a2ec30c0", Type: "password", RawValue: "00"}).Decrypt(); err == nil {
+		t.Error("a deleted item was decrypted")
+	}
+	if err := (&Card{UUID: "a2ec30c0", Type: "password", Key: make([]byte, 44), RawValue: "zz"}).Decrypt(); err == nil {
+		t.Error("a value that is not hex was decrypted")
+	}
+}
@@ -0,0 +1,133 @@
+package enpass
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultKDFIterations : the PBKDF2 iterations of new vaults
+	defaultKDFIterations = 100000
+	// vaultInfoVersion : the vault.json version of new vaults
+	vaultInfoVersion = 6
+)
+
+// vaultSchema : the tables of an Enpass 6 vault database, vault.json version 6. The item and itemfield columns are
+// the ones of a vault written by Enpass 6 in December 2020, listed at the top of card.go. The other tables only hold
+// the columns enpass reads and writes, the tables of other Enpass features are not created.
+var vaultSchema = []string{
+	`CREATE TABLE Identity (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, Version INTEGER, Signature TEXT, Sync_UUID TEXT, Hash TEXT, Info BLOB)`,
+	`CREATE TABLE item (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, uuid TEXT UNIQUE NOT NULL, created_at INTEGER, meta_updated_at INTEGER, field_updated_at INTEGER, title TEXT, subtitle TEXT, note TEXT, icon TEXT, favorite INTEGER DEFAULT 0, trashed INTEGER DEFAULT 0, archived INTEGER DEFAULT 0, deleted INTEGER DEFAULT 0, auto_submit INTEGER DEFAULT 1, form_data TEXT, category TEXT, template TEXT, wearable INTEGER DEFAULT 0, usage_count INTEGER DEFAULT 0, last_used INTEGER DEFAULT 0, key BLOB, extra TEXT, updated_at INTEGER)`,
+	`CREATE TABLE itemfield (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, item_uuid TEXT NOT NULL, item_field_uid INTEGER, label TEXT, value TEXT, deleted INTEGER DEFAULT 0, sensitive INTEGER DEFAULT 0, historical INTEGER DEFAULT 0, type TEXT, form_id TEXT, updated_at INTEGER, value_updated_at INTEGER, orde INTEGER, wearable INTEGER DEFAULT 0, history TEXT, initial TEXT, hash TEXT, strength INTEGER DEFAULT -1, algo_version INTEGER DEFAULT 1, expiry INTEGER DEFAULT 0, excluded INTEGER DEFAULT 0, pwned_check_time INTEGER DEFAULT 0, extra TEXT)`,
+	`CREATE INDEX itemfield_item_uuid ON itemfield (item_uuid)`,
+	`CREATE TABLE folder (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, uuid TEXT UNIQUE NOT NULL, title TEXT, parent_uuid TEXT, icon TEXT, extra TEXT, updated_at INTEGER)`,
+	`CREATE TABLE folder_items (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, folder_uuid TEXT NOT NULL, item_uuid TEXT NOT NULL)`,
+	`CREATE TABLE attachment (ID INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, uuid TEXT UNIQUE NOT NULL, item_uuid TEXT, name TEXT, mime TEXT, hash TEXT, size INTEGER, kind TEXT, data BLOB, key BLOB, extra TEXT, created_at INTEGER, updated_at INTEGER)`,
+}
+
+// CreateVault : create an empty vault in vaultPath, protected by the password and the keyfile when one is given.
+// The database is keyed the way Open derives the key: PBKDF2-HMAC-SHA512 of the password and keyfile over a
+// random salt, which SQLCipher keeps in the first bytes of the file. An existing vault is never overwritten.
+func CreateVault(vaultPath, name, password, flagKeyFilePath string, logLevel logrus.Level, flagNoColor bool) error {
+	if password == "" {
+		return errors.New("empty vault password provided")
+	}
+
+	v := Vault{
+		logger:            *util.ConfigureLogger(logLevel, flagNoColor),
+		FilterFields:      []string{"title", "subtitle"},
+		databaseFilename:  filepath.Join(vaultPath, vaultFileName),
+		vaultInfoFilename: filepath.Join(vaultPath, vaultInfoFileName),
+		vaultInfo: VaultInfo{
+			EncryptionAlgo: dbEncryptionAlgo,
+			KDFAlgo:        keyDerivationAlgo,
+			KDFIterations:  defaultKDFIterations,
+			VaultName:      name,
+			VaultVersion:   vaultInfoVersion,
+		},
+	}
+	if flagKeyFilePath != "" {
+		v.vaultInfo.HasKeyfile = 1
+	}
+
+	for _, path := range []string{v.databaseFilename, v.vaultInfoFilename} {
+		if exists, _ := util.FileOrDirectoryExists(path); exists {
+			return errors.New("a vault already exists: " + path)
+		}
+	}
+	if err := os.MkdirAll(vaultPath, 0700); err != nil {
+		return errors.Wrap(err, "could not create the vault directory")
+	}
+
+	masterPassword, err := v.generateMasterPassword([]byte(password), flagKeyFilePath)
+	if err != nil {
+		return errors.Wrap(err, "could not generate vault unlock key")
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return errors.Wrap(err, "could not generate the database salt")
+	}
+	dbKey, err := v.deriveKey(masterPassword, salt)
+	if err != nil {
+		return errors.Wrap(err, "could not derive database key from master password")
+	}
+
+	if err := v.createDatabase(dbKey, salt, logLevel, flagNoColor); err != nil {
+		os.Remove(v.databaseFilename)
+		return err
+	}
+
+	vaultInfoBytes, err := json.MarshalIndent(v.vaultInfo, "", "    ")
+	if err != nil {
+		os.Remove(v.databaseFilename)
+		return errors.Wrap(err, "could not encode vault info")
+	}
+	if err := os.WriteFile(v.vaultInfoFilename, append(vaultInfoBytes, '\n'), 0600); err != nil {
+		os.Remove(v.databaseFilename)
+		return errors.Wrap(err, "could not write vault info")
+	}
+
+	return nil
+}
+
+func (v *Vault) createDatabase(dbKey []byte, salt []byte, logLevel logrus.Level, flagNoColor bool) error {
+	if err := v.openEncryptedDatabase(v.databaseFilename, dbKey, salt, logLevel, flagNoColor); err != nil {
+		return errors.Wrap(err, "could not create encrypted database")
+	}
+	sqlDB, err := v.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "could not create encrypted database")
+	}
+	defer sqlDB.Close()
+
+	for _, statement := range vaultSchema {
+		if err := v.db.Exec(statement).Error; err != nil {
+			return errors.Wrap(err, "could not create the vault tables")
+		}
+	}
+	// Enpass keeps a single Identity row per vault, the signature and hash are left to the application
+	if err := v.db.Exec(`INSERT INTO Identity (Version, Sync_UUID) VALUES (?, ?)`, vaultInfoVersion, NewUUID()).Error; err != nil {
+		return errors.Wrap(err, "could not write the vault identity")
+	}
+	if err := sqlDB.Close(); err != nil {
+		return errors.Wrap(err, "could not close the database")
+	}
+
+	// Open reads the salt back from the file, make sure SQLCipher used ours
+	fileSalt, err := v.extractSalt()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(fileSalt, salt) {
+		return errors.New("the database was not created with the expected salt, the SQLCipher library is not compatible")
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package enpass
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCreateVault(t *testing.T) {
+	dir := t.TempDir()
+	keyFile, err := GenerateKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFilePath := filepath.Join(dir, "vault.enpasskey")
+	if err := os.WriteFile(keyFilePath, keyFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+	vaultPath := filepath.Join(dir, "vault")
+
+	if err := CreateVault(vaultPath, "test", "password", keyFilePath, logrus.ErrorLevel, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateVault(vaultPath, "test", "password", "", logrus.ErrorLevel, true); err == nil {
+		t.Error("an existing vault was overwritten")
+	}
+
+	vault, err := NewVault(vaultPath, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := vault.vaultInfo
+	if info.VaultName != "test" || info.HasKeyfile != 1 || info.VaultVersion != vaultInfoVersion || info.KDFIterations != defaultKDFIterations {
+		t.Errorf("unexpected vault.json %+v", info)
+	}
+	if err := vault.Open(NewVaultCredentials("password", ""), logrus.ErrorLevel, true); err == nil {
+		t.Error("the vault opened without its keyfile")
+	}
+	if err := vault.Open(NewVaultCredentials("password", keyFilePath), logrus.ErrorLevel, true); err != nil {
+		t.Fatal(err)
+	}
+	defer vault.Close()
+
+	// The columns Enpass 6 writes, as listed at the top of card.go
+	for table, want := range map[string][]string{
+		"item": {
+			"ID", "uuid", "created_at", "meta_updated_at", "field_updated_at", "title", "subtitle", "note", "icon",
+			"favorite", "trashed", "archived", "deleted", "auto_submit", "form_data", "category", "template", "wearable",
+			"usage_count", "last_used", "key", "extra", "updated_at",
+		},
+		"itemfield": {
+			"ID", "item_uuid", "item_field_uid", "label", "value", "deleted", "sensitive", "historical", "type",
+			"form_id", "updated_at", "value_updated_at", "orde", "wearable", "history", "initial", "hash", "strength",
+			"algo_version", "expiry", "excluded", "pwned_check_time", "extra",
+		},
+	} {
+		var columns []string
+		if err := vault.db.Raw("SELECT name FROM pragma_table_info(?)", table).Scan(&columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(columns, want) {
+			t.Errorf("%s columns\n got %v\nwant %v", table, columns, want)
+		}
+	}
+
+	var identity struct {
+		Version  int
+		SyncUUID string `gorm:"column:Sync_UUID"`
+	}
+	var rows int64
+	if err := vault.db.Table("Identity").Count(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.db.Table("Identity").Select("Version, Sync_UUID").Take(&identity).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 || identity.Version != vaultInfoVersion || identity.SyncUUID == "" {
+		t.Errorf("unexpected identity, %d rows: %+v", rows, identity)
+	}
+
+	count, err := vault.countItems()
+	if err != nil || count != 0 {
+		t.Errorf("a new vault holds %d items: %v", count, err)
+	}
+}
+
+func TestCreateVaultEmptyPassword(t *testing.T) {
+	if err := CreateVault(t.TempDir(), "test", "", "", logrus.ErrorLevel, true); err == nil {
+		t.Error("a vault was created without a password")
+	}
+}
+
+func TestCountAttachments(t *testing.T) {
+	vault := openNewVault(t)
+	for i, itemUUID := range []string{"a", "a", "b"} {
+		if err := vault.db.Exec("INSERT INTO attachment (uuid, item_uuid, name) VALUES (?, ?, ?)", NewUUID(), itemUUID, i).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, err := vault.CountAttachments([]string{"a", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(counts, map[string]int{"a": 2}) {
+		t.Errorf("got %v", counts)
+	}
+
+	if err := vault.db.Exec("DROP TABLE attachment").Error; err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := vault.CountAttachments([]string{"a"}); err != nil || len(counts) != 0 {
+		t.Errorf("a vault without attachments: %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,170 @@
+// Package enpasstest : build throwaway Enpass vaults with known content, to test code using enpass.Vault without
+// real secrets. The vaults are keyed like the ones of the Enpass application, so they exercise the same salt,
+// PBKDF2 and SQLCipher path as a real vault.
+package enpasstest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPassword : the password of the fixture vaults unless Options.Password is set
+const DefaultPassword = "correct horse battery staple"
+
+// Fixed uuids of the default content, to look items up in tests
+const (
+	FolderWork   = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e01"
+	ItemGitHub   = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e10"
+	ItemBank     = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e11"
+	ItemCard     = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e12"
+	ItemNote     = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e13"
+	ItemSSHKey   = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e14"
+	ItemTrashed  = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e15"
+	ItemArchived = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e16"
+	ItemDeleted  = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e17"
+
+	// AttachmentRecovery is stored in the database, AttachmentScan in a .enpassattach file, both belong to GitHub
+	AttachmentRecovery = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e20"
+	AttachmentScan     = "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e21"
+)
+
+// Options : the content and protection of a fixture vault. The zero value builds the default vault.
+type Options struct {
+	// Name of the vault, "fixture" by default
+	Name string
+	// Password of the vault, DefaultPassword by default
+	Password string
+	// KeyFile protects the vault with a generated keyfile as well
+	KeyFile bool
+	// Items replace DefaultItems. Their external attachments get a <uuid>.enpassattach file in the vault.
+	Items []enpass.Item
+	// Folders replace DefaultFolders
+	Folders []enpass.Folder
+	// Deleted are the uuids of the items to delete after they are written, [ItemDeleted] by default. Deleted
+	// items stay in the database with their content cleared.
+	Deleted []string
+}
+
+// Fixture : a vault built in a temporary directory, removed when the test ends
+type Fixture struct {
+	// Path is the vault directory
+	Path string
+	// Password of the vault
+	Password string
+	// KeyFilePath is the keyfile of the vault, empty without one
+	KeyFilePath string
+	// Items are the items written, with their uuids, before the deletions
+	Items []enpass.Item
+	// Folders are the folders written
+	Folders []enpass.Folder
+}
+
+// New : build a vault with the options in a temporary directory of the test. It fails the test on error.
+func New(tb testing.TB, opts Options) *Fixture {
+	tb.Helper()
+
+	f := &Fixture{
+		Path:     filepath.Join(tb.TempDir(), "vault"),
+		Password: opts.Password,
+		Items:    opts.Items,
+		Folders:  opts.Folders,
+	}
+	if f.Password == "" {
+		f.Password = DefaultPassword
+	}
+	if f.Items == nil {
+		f.Items = DefaultItems()
+	}
+	if f.Folders == nil {
+		f.Folders = DefaultFolders()
+	}
+	deleted := opts.Deleted
+	if deleted == nil && opts.Items == nil {
+		deleted = []string{ItemDeleted}
+	}
+	name := opts.Name
+	if name == "" {
+		name = "fixture"
+	}
+
+	if opts.KeyFile {
+		keyFile, err := enpass.GenerateKeyFile()
+		if err != nil {
+			tb.Fatalf("enpasstest: %s", err)
+		}
+		f.KeyFilePath = filepath.Join(filepath.Dir(f.Path), "fixture.enpasskey")
+		if err := os.WriteFile(f.KeyFilePath, keyFile, 0600); err != nil {
+			tb.Fatalf("enpasstest: %s", err)
+		}
+	}
+
+	if err := enpass.CreateVault(f.Path, name, f.Password, f.KeyFilePath, logrus.ErrorLevel, true); err != nil {
+		tb.Fatalf("enpasstest: %s", err)
+	}
+
+	vault := f.Open(tb)
+	if err := vault.AddFolders(f.Folders); err != nil {
+		tb.Fatalf("enpasstest: %s", err)
+	}
+	if err := vault.AddItems(f.Items); err != nil {
+		tb.Fatalf("enpasstest: %s", err)
+	}
+	if len(deleted) > 0 {
+		if err := vault.DeleteItems(deleted); err != nil {
+			tb.Fatalf("enpasstest: %s", err)
+		}
+	}
+
+	// The files of external attachments hold Size placeholder bytes, they are not attachment databases
+	for _, item := range f.Items {
+		for _, attachment := range item.Attachments {
+			if !attachment.External {
+				continue
+			}
+			attachmentPath := filepath.Join(f.Path, attachment.UUID+".enpassattach")
+			if err := os.WriteFile(attachmentPath, make([]byte, attachment.Size), 0600); err != nil {
+				tb.Fatalf("enpasstest: %s", err)
+			}
+		}
+	}
+
+	return f
+}
+
+// Credentials : the credentials unlocking the vault
+func (f *Fixture) Credentials() *enpass.VaultCredentials {
+	return enpass.NewVaultCredentials(f.Password, f.KeyFilePath)
+}
+
+// Open : open the vault, it fails the test on error
+func (f *Fixture) Open(tb testing.TB) *enpass.Vault {
+	tb.Helper()
+
+	vault, err := enpass.NewVault(f.Path, logrus.ErrorLevel, true)
+	if err != nil {
+		tb.Fatalf("enpasstest: %s", err)
+	}
+	if err := vault.Open(f.Credentials(), logrus.ErrorLevel, true); err != nil {
+		tb.Fatalf("enpasstest: %s", err)
+	}
+	tb.Cleanup(vault.Close)
+
+	return vault
+}
+
+// Item : the item with the uuid as it was written, it fails the test when there is none
+func (f *Fixture) Item(tb testing.TB, uuid string) enpass.Item {
+	tb.Helper()
+
+	for _, item := range f.Items {
+		if item.UUID == uuid {
+			return item
+		}
+	}
+	tb.Fatalf("enpasstest: no item %s in the fixture", uuid)
+	return enpass.Item{}
+}
@@ -0,0 +1,251 @@
+package enpasstest_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+	"github.com/sirupsen/logrus"
+)
+
+var none = []string{}
+
+// written : the default items as the vault holds them, without the deleted one
+func written() []enpass.Item {
+	items := []enpass.Item{}
+	for _, item := range enpasstest.DefaultItems() {
+		if item.UUID != enpasstest.ItemDeleted {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func uuids(items []enpass.Item) []string {
+	list := []string{}
+	for _, item := range items {
+		list = append(list, item.UUID)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestDefaultVault(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	vault := fixture.Open(t)
+
+	want := written()
+	got, err := vault.GetItemsByUUID(uuids(enpasstest.DefaultItems()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byUUID := map[string]enpass.Item{}
+	for _, item := range got {
+		// The items are written without an icon, the vault holds the default one
+		item.Icon = ""
+		// and without hashes, the vault keeps the SHA-1 of the passwords
+		for i, field := range item.Fields {
+			if sum := sha1.Sum([]byte(field.Value)); field.Type == "password" && field.Hash != hex.EncodeToString(sum[:]) {
+				t.Errorf("%s: the %s field has the hash %q", item.Title, field.Label, field.Hash)
+			}
+			item.Fields[i].Hash = ""
+		}
+		byUUID[item.UUID] = item
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d items, want %d", len(got), len(want))
+	}
+	for _, item := range want {
+		if !reflect.DeepEqual(byUUID[item.UUID], item) {
+			t.Errorf("%s\n got %+v\nwant %+v", item.Title, byUUID[item.UUID], item)
+		}
+	}
+
+	folders, err := vault.GetFolders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(folders, enpasstest.DefaultFolders()) {
+		t.Errorf("folders: got %+v, want %+v", folders, enpasstest.DefaultFolders())
+	}
+}
+
+func TestDefaultVaultGetItems(t *testing.T) {
+	vault := enpasstest.New(t, enpasstest.Options{}).Open(t)
+
+	// GetItems finds items through their fields, the note has none
+	withFields := []enpass.Item{}
+	for _, item := range written() {
+		if len(item.Fields) > 0 {
+			withFields = append(withFields, item)
+		}
+	}
+	items, err := vault.GetItems(none, none, none, none, none, false, true, none, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := uuids(items), uuids(withFields); !reflect.DeepEqual(got, want) {
+		t.Errorf("with trashed items\n got %v\nwant %v", got, want)
+	}
+
+	items, err = vault.GetItems(none, none, none, none, none, false, false, none, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.Trashed {
+			t.Errorf("the trashed item %s was listed", item.Title)
+		}
+	}
+	if len(items) != len(withFields)-1 {
+		t.Errorf("got %d items without the trashed one, want %d", len(items), len(withFields)-1)
+	}
+}
+
+func TestDefaultVaultGetEntries(t *testing.T) {
+	vault := enpasstest.New(t, enpasstest.Options{}).Open(t)
+
+	want := map[string]bool{}
+	for _, item := range written() {
+		for _, field := range item.Fields {
+			if field.Type == "password" {
+				want[item.UUID+" "+field.Label+" "+field.Value] = true
+			}
+		}
+	}
+	cards, err := vault.GetEntries("password", none, none, none, none, none, false, none, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, card := range cards {
+		got[card.UUID+" "+card.Label+" "+card.DecryptedValue] = true
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("password entries\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestDefaultVaultDeleted(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	vault := fixture.Open(t)
+
+	items, err := vault.GetItemsByUUID([]string{enpasstest.ItemDeleted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("the deleted item was read: %+v", items)
+	}
+	cards, err := vault.GetEntries("password", none, none, []string{"ghost"}, none, none, false, none, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 0 {
+		t.Errorf("the fields of the deleted item were read: %+v", cards)
+	}
+	// The fixture still knows the item it wrote
+	if item := fixture.Item(t, enpasstest.ItemDeleted); item.Title != "Gone" {
+		t.Errorf("unexpected item %+v", item)
+	}
+
+	data, err := os.ReadFile(filepath.Join(fixture.Path, "vault.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		Count int `json:"vault_items_count"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Count != len(written()) {
+		t.Errorf("vault_items_count is %d, want %d", info.Count, len(written()))
+	}
+}
+
+func TestKeyFileVault(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{KeyFile: true, Password: "other password", Name: "keyed"})
+	if fixture.KeyFilePath == "" {
+		t.Fatal("no keyfile was written")
+	}
+	if _, err := os.Stat(fixture.KeyFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := fixture.Open(t).GetItemsByUUID([]string{enpasstest.ItemGitHub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Title != "GitHub" {
+		t.Errorf("unexpected items %+v", items)
+	}
+
+	vault, err := enpass.NewVault(fixture.Path, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vault.Close()
+	if err := vault.Open(enpass.NewVaultCredentials(fixture.Password, ""), logrus.ErrorLevel, true); err == nil {
+		t.Error("the vault opened without its keyfile")
+	}
+	if err := vault.Open(enpass.NewVaultCredentials(enpasstest.DefaultPassword, fixture.KeyFilePath), logrus.ErrorLevel, true); err == nil {
+		t.Error("the vault opened with the default password")
+	}
+}
+
+func TestCustomItems(t *testing.T) {
+	items := []enpass.Item{{UUID: "custom", Title: "Only", Category: "login", Fields: []enpass.ItemField{{Label: "Username", Type: "username", Value: "me"}}}}
+	fixture := enpasstest.New(t, enpasstest.Options{Items: items, Folders: []enpass.Folder{}})
+
+	got, err := fixture.Open(t).GetItems(none, none, none, none, none, false, true, none, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].UUID != "custom" {
+		t.Errorf("got %+v, want only the custom item", got)
+	}
+}
+
+func TestDefaultVaultAttachments(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+	vault := fixture.Open(t)
+
+	items, err := vault.GetItemsByUUID([]string{enpasstest.ItemGitHub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fixture.Item(t, enpasstest.ItemGitHub).Attachments
+	if len(items) != 1 || !reflect.DeepEqual(items[0].Attachments, want) {
+		t.Fatalf("got %+v, want %+v", items, want)
+	}
+	if inline := items[0].Attachments[0]; inline.External || string(inline.Data) != "1111-2222\n3333-4444\n" {
+		t.Errorf("the attachment in the database was not read: %+v", inline)
+	}
+
+	info, err := os.Stat(filepath.Join(fixture.Path, enpasstest.AttachmentScan+".enpassattach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 2048 {
+		t.Errorf("the attachment file holds %d bytes", info.Size())
+	}
+	if files, _ := filepath.Glob(filepath.Join(fixture.Path, "*.enpassattach")); len(files) != 1 {
+		t.Errorf("got the attachment files %v", files)
+	}
+
+	counts, err := vault.CountAttachments(uuids(enpasstest.DefaultItems()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(counts, map[string]int{enpasstest.ItemGitHub: 2}) {
+		t.Errorf("counts: got %v", counts)
+	}
+}
@@ -0,0 +1,384 @@
+package enpass
+
+import (
+	"strings"
+
+	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
+)
+
+// itemQueryChunk : the number of uuids bound per query
+const itemQueryChunk = 500
+
+// Item : a record with all of its fields. A Card is a single field of a record.
+type Item struct {
+	UUID     string
+	Title    string
+	Subtitle string
+	Note     string
+	Category string
+	Template string
+	Icon     string
+	Favorite bool
+	Archived bool
+	Trashed  bool
+	// AutoSubmit lets the browser extension submit the login form after filling it
+	AutoSubmit bool
+	Created    int64
+	Updated    int64
+	LastUsed   int64
+	Fields     []ItemField
+	// Folders holds the uuids of the folders the item is filed in
+	Folders []string
+	// Attachments are read with the item and written by AddItems
+	Attachments []Attachment
+}
+
+// Folder : a folder of the vault, folders nest through ParentUUID
+type Folder struct {
+	UUID       string
+	Title      string
+	ParentUUID string
+	Icon       string
+	Updated    int64
+}
+
+// ItemField : a field of an item, Value is decrypted
+type ItemField struct {
+	UID       int
+	Label     string
+	Type      string
+	Value     string
+	Sensitive bool
+	Order     int
+	// Hash is the hex SHA-1 of the value the vault keeps for passwords, it is empty for other fields and is not
+	// written, AddItems computes it
+	Hash string
+}
+
+// FolderPaths : the slash-separated path of every folder, keyed by uuid
+func FolderPaths(folders []Folder) map[string]string {
+	byUUID := map[string]Folder{}
+	for _, folder := range folders {
+		byUUID[folder.UUID] = folder
+	}
+
+	paths := map[string]string{}
+	for _, folder := range folders {
+		names := []string{}
+		seen := map[string]bool{}
+		for current, ok := folder, true; ok && !seen[current.UUID]; current, ok = byUUID[current.ParentUUID] {
+			seen[current.UUID] = true
+			names = append([]string{current.Title}, names...)
+		}
+		paths[folder.UUID] = strings.Join(names, "/")
+	}
+	return paths
+}
+
+// IsSecret : report whether the field holds a secret, which is only exported or shown on request. PINs and card
+// security codes are secrets even when they are not flagged sensitive.
+func (f ItemField) IsSecret() bool {
+	return f.Sensitive || f.Type == "password" || f.Type == "totp" || f.Type == "pin" || f.Type == "ccCvc"
+}
+
+// Field : return the first field of the type with a value
+func (i *Item) Field(fieldType string) (ItemField, bool) {
+	if index := i.FieldIndex(fieldType, nil); index >= 0 {
+		return i.Fields[index], true
+	}
+	return ItemField{}, false
+}
+
+// FieldIndex : the index of the first field of the type with a value that is not in used, -1 when there is none
+func (i *Item) FieldIndex(fieldType string, used map[int]bool) int {
+	for index, field := range i.Fields {
+		if field.Type == fieldType && field.Value != "" && !used[index] {
+			return index
+		}
+	}
+	return -1
+}
+
+type rawItem struct {
+	UUID       string
+	Title      string
+	Subtitle   string
+	Note       string
+	Category   string
+	Template   string
+	Icon       string
+	Favorite   int64
+	Archived   int64
+	Trashed    int64
+	AutoSubmit int64
+	Created    int64
+	Updated    int64
+	LastUsed   int64
+	Key        []byte
+}
+
+type rawFolderItem struct {
+	FolderUUID string
+	ItemUUID   string
+}
+
+type rawItemField struct {
+	ItemUUID     string
+	ItemFieldUID int
+	Label        string
+	Type         string
+	Value        string
+	Sensitive    bool
+	Orde         int
+	Hash         string
+}
+
+// GetItems : return the items matching the filters, the query and the search with every field, in the order of
+// GetEntries. Deleted items are never returned, trashed items only with flagTrashed.
+func (v *Vault) GetItems(flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive, flagTrashed bool, flagOrderBy []string, validOrderBy []string) ([]Item, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	cards, err := v.executeEntryQuery("", flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve items from database")
+	}
+
+	uuids := []string{}
+	seen := map[string]bool{}
+	for _, card := range cards {
+		if seen[card.UUID] || (card.IsTrashed() && !flagTrashed) {
+			continue
+		}
+		seen[card.UUID] = true
+		uuids = append(uuids, card.UUID)
+	}
+
+	return v.GetItemsByUUID(uuids)
+}
+
+// GetItemsByUUID : return the items with every field, in the order of uuids. Unknown and deleted items are
+// skipped.
+func (v *Vault) GetItemsByUUID(uuids []string) ([]Item, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+	if len(uuids) <= 0 {
+		return []Item{}, nil
+	}
+
+	var (
+		itemRows   []rawItem
+		fieldRows  []rawItemField
+		folderRows []rawFolderItem
+	)
+	// Query in chunks to stay below the SQLite limit on bound variables
+	for start := 0; start < len(uuids); start += itemQueryChunk {
+		chunk := uuids[start:min(start+itemQueryChunk, len(uuids))]
+
+		var chunkItems []rawItem
+		err := v.db.Select("uuid", "title", "subtitle", "note", "category", "template", "icon", "favorite", "archived", "trashed", "auto_submit", "created_at AS created", "updated_at AS updated", "last_used", "key").
+			Table("item").
+			Where("deleted = ?", 0).
+			Where("uuid IN ?", chunk).
+			Find(&chunkItems).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "could not retrieve items from database")
+		}
+		itemRows = append(itemRows, chunkItems...)
+
+		var chunkFields []rawItemField
+		err = v.db.Select("item_uuid", "item_field_uid", "label", "type", "value", "sensitive", "orde", "hash").
+			Table("itemfield").
+			Where("deleted = ?", 0).
+			Where("item_uuid IN ?", chunk).
+			Order("orde").
+			Find(&chunkFields).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "could not retrieve item fields from database")
+		}
+		fieldRows = append(fieldRows, chunkFields...)
+
+		if !v.hasFolders() {
+			continue
+		}
+		var chunkFolders []rawFolderItem
+		err = v.db.Select("folder_uuid", "item_uuid").
+			Table("folder_items").
+			Where("item_uuid IN ?", chunk).
+			Find(&chunkFolders).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "could not retrieve the folders of the items")
+		}
+		folderRows = append(folderRows, chunkFolders...)
+	}
+
+	keys := map[string][]byte{}
+	byUUID := map[string]*Item{}
+	for _, row := range itemRows {
+		keys[row.UUID] = row.Key
+		byUUID[row.UUID] = &Item{
+			UUID:       row.UUID,
+			Title:      row.Title,
+			Subtitle:   row.Subtitle,
+			Note:       row.Note,
+			Category:   row.Category,
+			Template:   row.Template,
+			Icon:       row.Icon,
+			Favorite:   row.Favorite != 0,
+			Archived:   row.Archived != 0,
+			Trashed:    row.Trashed != 0,
+			Created:    row.Created,
+			Updated:    row.Updated,
+			LastUsed:   row.LastUsed,
+			AutoSubmit: row.AutoSubmit != 0,
+		}
+	}
+
+	for _, row := range fieldRows {
+		item, ok := byUUID[row.ItemUUID]
+		if !ok {
+			continue
+		}
+		card := Card{UUID: row.ItemUUID, Type: row.Type, RawValue: row.Value, Key: keys[row.ItemUUID]}
+		if err := card.Decrypt(); err != nil {
+			return nil, errors.Wrapf(err, "could not decrypt the %q field of %q", row.Label, item.Title)
+		}
+
+		// Only password fields are encrypted, everything else is stored as-is
+		value := card.DecryptedValue
+		if row.Type != "password" {
+			value = row.Value
+		}
+		item.Fields = append(item.Fields, ItemField{
+			UID:       row.ItemFieldUID,
+			Label:     row.Label,
+			Type:      row.Type,
+			Value:     value,
+			Sensitive: row.Sensitive,
+			Order:     row.Orde,
+			Hash:      row.Hash,
+		})
+	}
+
+	for _, row := range folderRows {
+		if item, ok := byUUID[row.ItemUUID]; ok {
+			item.Folders = append(item.Folders, row.FolderUUID)
+		}
+	}
+
+	attachments, err := v.getAttachments(uuids)
+	if err != nil {
+		return nil, err
+	}
+	for uuid, itemAttachments := range attachments {
+		if item, ok := byUUID[uuid]; ok {
+			item.Attachments = itemAttachments
+		}
+	}
+
+	items := []Item{}
+	for _, uuid := range uuids {
+		if item, ok := byUUID[uuid]; ok {
+			items = append(items, *item)
+		}
+	}
+
+	return items, nil
+}
+
+// GetFolders : return every folder of the vault, sorted by title. Vaults without folder support have none.
+func (v *Vault) GetFolders() ([]Folder, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	folders := []Folder{}
+	if !v.hasFolders() {
+		return folders, nil
+	}
+	err := v.db.Select("uuid", "title", "parent_uuid", "icon", "updated_at AS updated").
+		Table("folder").
+		Order("title").
+		Find(&folders).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve the folders")
+	}
+
+	return folders, nil
+}
+
+// CountAttachments : return the number of attachments of each item with attachments, without reading them. This
+// lets the tools that copy items without their attachments report the ones they leave behind.
+func (v *Vault) CountAttachments(uuids []string) (map[string]int, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	counts := map[string]int{}
+	if len(uuids) <= 0 || !v.db.Migrator().HasTable("attachment") {
+		return counts, nil
+	}
+	for start := 0; start < len(uuids); start += itemQueryChunk {
+		chunk := uuids[start:min(start+itemQueryChunk, len(uuids))]
+
+		var rows []struct {
+			ItemUUID string
+			Count    int
+		}
+		err := v.db.Select("item_uuid", "COUNT(*) AS count").
+			Table("attachment").
+			Where("item_uuid IN ?", chunk).
+			Group("item_uuid").
+			Find(&rows).Error
+		if err != nil {
+			return nil, errors.Wrap(err, "could not count the attachments")
+		}
+		for _, row := range rows {
+			counts[row.ItemUUID] = row.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// hasFolders : report whether the vault has the folder tables, vaults created before folders existed do not
+func (v *Vault) hasFolders() bool {
+	return v.db.Migrator().HasTable("folder") && v.db.Migrator().HasTable("folder_items")
+}
+
+// CardsFromItems : turn every field of the items into a card, the way GetEntries returns them. Values are
+// already decrypted.
+func CardsFromItems(items []Item) []Card {
+	cards := []Card{}
+	for _, item := range items {
+		var trashed int64
+		if item.Trashed {
+			trashed = 1
+		}
+		for _, field := range item.Fields {
+			cards = append(cards, Card{
+				UUID:           item.UUID,
+				Created:        util.ToHuman(item.Created),
+				Type:           field.Type,
+				Updated:        util.ToHuman(item.Updated),
+				Title:          item.Title,
+				Subtitle:       item.Subtitle,
+				Note:           item.Note,
+				Trashed:        trashed,
+				Category:       item.Category,
+				Label:          field.Label,
+				LastUsed:       util.ToHuman(item.LastUsed),
+				Sensitive:      field.Sensitive,
+				Icon:           item.Icon,
+				DecryptedValue: field.Value,
+				CreatedAt:      item.Created,
+				UpdatedAt:      item.Updated,
+				LastUsedAt:     item.LastUsed,
+			})
+		}
+	}
+	return cards
+}
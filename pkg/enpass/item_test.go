@@ -0,0 +1,43 @@
+package enpass_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+)
+
+func TestGetFolders(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{
+		Folders: []enpass.Folder{
+			{UUID: "b", Title: "Work", Icon: "icon", Updated: 10},
+			{UUID: "a", Title: "Git", ParentUUID: "b", Updated: 11},
+		},
+	})
+	vault := fixture.Open(t)
+
+	folders, err := vault.GetFolders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []enpass.Folder{
+		{UUID: "a", Title: "Git", ParentUUID: "b", Updated: 11},
+		{UUID: "b", Title: "Work", Icon: "icon", Updated: 10},
+	}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("got %+v, want %+v", folders, want)
+	}
+}
+
+func TestGetItemsByUUIDAutoSubmit(t *testing.T) {
+	vault := enpasstest.New(t, enpasstest.Options{}).Open(t)
+
+	items, err := vault.GetItemsByUUID([]string{enpasstest.ItemGitHub, enpasstest.ItemBank})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || !items[0].AutoSubmit || items[1].AutoSubmit {
+		t.Errorf("auto_submit was not read back: %+v", items)
+	}
+}
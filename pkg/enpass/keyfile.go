@@ -1,21 +1,43 @@
 package enpass
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
 
+// The keyfile sources besides a path
+const (
+	keyFileStdin     = "-"
+	keyFileFDPrefix  = "fd:"
+	keyFileEnvPrefix = "env:"
+	keyFileCmdPrefix = "cmd:"
+)
+
+var (
+	// keyFileCache : the content of every keyfile source read, stdin and descriptors can only be read once
+	keyFileCache   = map[string][]byte{}
+	keyFileCacheMu sync.Mutex
+)
+
 type Keyfile struct {
 	Key string `xml:",innerxml"`
 }
 
 func loadKeyFilePassword(path string) ([]byte, error) {
-	bytes, err := os.ReadFile(path)
+	bytes, err := ReadKeyFile(path)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not load keyfile")
+		return nil, err
 	}
 
 	var kf Keyfile
@@ -23,10 +45,81 @@ func loadKeyFilePassword(path string) ([]byte, error) {
 		return nil, errors.Wrap(err, "could not decode keyfile")
 	}
 
-	keyBytes, err := hex.DecodeString(kf.Key)
+	keyBytes, err := hex.DecodeString(strings.TrimSpace(kf.Key))
 	if err != nil {
 		return nil, errors.Wrap(err, "could not decode keyfile hex byte")
 	}
 
 	return keyBytes, nil
 }
+
+// ReadKeyFile : read a keyfile from its source, which is a path, - for stdin, fd:N for an open file descriptor,
+// env:NAME for an environment variable holding the keyfile in base64 or cmd:COMMAND for the output of a shell
+// command. Every source is read once, later calls return the same content.
+func ReadKeyFile(source string) ([]byte, error) {
+	keyFileCacheMu.Lock()
+	defer keyFileCacheMu.Unlock()
+	if data, ok := keyFileCache[source]; ok {
+		return data, nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case source == keyFileStdin:
+		data, err = io.ReadAll(os.Stdin)
+	case strings.HasPrefix(source, keyFileFDPrefix):
+		var fd int
+		if fd, err = strconv.Atoi(strings.TrimPrefix(source, keyFileFDPrefix)); err != nil || fd < 0 {
+			return nil, errors.Errorf("invalid keyfile descriptor %q", source)
+		}
+		file := os.NewFile(uintptr(fd), source)
+		if file == nil {
+			return nil, errors.Errorf("invalid keyfile descriptor %q", source)
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	case strings.HasPrefix(source, keyFileEnvPrefix):
+		name := strings.TrimPrefix(source, keyFileEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, errors.Errorf("the keyfile variable $%s is not set", name)
+		}
+		if data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err != nil {
+			return nil, errors.Wrapf(err, "the keyfile variable $%s is not base64", name)
+		}
+	case strings.HasPrefix(source, keyFileCmdPrefix):
+		data, err = exec.Command("sh", "-c", strings.TrimPrefix(source, keyFileCmdPrefix)).Output()
+		if err != nil {
+			return nil, errors.Wrap(err, "the keyfile command failed")
+		}
+	default:
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load keyfile")
+	}
+
+	keyFileCache[source] = data
+	return data, nil
+}
+
+// IsEphemeralKeyFile : report whether the keyfile source is stdin, a file descriptor or an environment variable.
+// They hand a keyfile to a single run, they are no place to keep the keyfile protecting a vault.
+func IsEphemeralKeyFile(source string) bool {
+	return source == keyFileStdin || strings.HasPrefix(source, keyFileFDPrefix) || strings.HasPrefix(source, keyFileEnvPrefix)
+}
+
+// keyFileKeyLength : the number of random bytes of a generated keyfile
+const keyFileKeyLength = 32
+
+// GenerateKeyFile : a new keyfile in the Enpass format, an XML document holding a random key in hex
+func GenerateKeyFile() ([]byte, error) {
+	key := make([]byte, keyFileKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "could not generate the keyfile key")
+	}
+	return []byte(fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<key>%s</key>\n", hex.EncodeToString(key))), nil
+}
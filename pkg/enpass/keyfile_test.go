@@ -0,0 +1,110 @@
+package enpass
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	keyFile, err := GenerateKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/test.enpasskey"
+	if err := os.WriteFile(path, keyFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENPASS_TEST_KEYFILE", base64.StdEncoding.EncodeToString(keyFile)+"\n")
+	t.Setenv("ENPASS_TEST_BAD_KEYFILE", "not base64!")
+	t.Setenv("ENPASS_TEST_PATH", path)
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"path", path, ""},
+		{"missing path", path + ".missing", "could not load keyfile"},
+		{"env", "env:ENPASS_TEST_KEYFILE", ""},
+		{"env not base64", "env:ENPASS_TEST_BAD_KEYFILE", "is not base64"},
+		{"env not set", "env:ENPASS_TEST_UNSET", "is not set"},
+		{"cmd", `cmd:cat "$ENPASS_TEST_PATH"`, ""},
+		{"cmd failing", "cmd:exit 3", "the keyfile command failed"},
+		{"fd not a number", "fd:three", "invalid keyfile descriptor"},
+		{"fd negative", "fd:-1", "invalid keyfile descriptor"},
+		{"fd not open", "fd:987654", "could not load keyfile"},
+	}
+	for _, test := range tests {
+		keyFileCache = map[string][]byte{}
+		data, err := ReadKeyFile(test.source)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: got the error %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+		} else if string(data) != string(keyFile) {
+			t.Errorf("%s: got %q", test.name, data)
+		}
+	}
+}
+
+func TestReadKeyFileDescriptor(t *testing.T) {
+	keyFileCache = map[string][]byte{}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString("<key>00</key>"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	// ReadKeyFile closes the descriptor it reads, hand it a copy so r is closed only once
+	fd, err := syscall.Dup(int(r.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	source := fmt.Sprintf("fd:%d", fd)
+	data, err := ReadKeyFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "<key>00</key>" {
+		t.Errorf("got %q", data)
+	}
+	// The descriptor is drained and closed, the content comes from the cache
+	if again, err := ReadKeyFile(source); err != nil || string(again) != string(data) {
+		t.Errorf("the second read got %q, %v", again, err)
+	}
+}
+
+func TestReadKeyFileCache(t *testing.T) {
+	keyFileCache = map[string][]byte{}
+	t.Setenv("ENPASS_TEST_KEYFILE", base64.StdEncoding.EncodeToString([]byte("first")))
+	if data, err := ReadKeyFile("env:ENPASS_TEST_KEYFILE"); err != nil || string(data) != "first" {
+		t.Fatalf("got %q, %v", data, err)
+	}
+	t.Setenv("ENPASS_TEST_KEYFILE", base64.StdEncoding.EncodeToString([]byte("second")))
+	if data, err := ReadKeyFile("env:ENPASS_TEST_KEYFILE"); err != nil || string(data) != "first" {
+		t.Errorf("the source was read again: %q, %v", data, err)
+	}
+}
+
+func TestIsEphemeralKeyFile(t *testing.T) {
+	for source, want := range map[string]bool{
+		"-": true, "fd:3": true, "env:KEYFILE": true,
+		"cmd:pass show keyfile": false, "/home/me/vault.enpasskey": false, "~/vault.enpasskey": false,
+	} {
+		if got := IsEphemeralKeyFile(source); got != want {
+			t.Errorf("%s: got %v, want %v", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package enpass
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Rekey : re-encrypt the opened database with the key derived from the password and the keyfile, an empty
+// flagKeyFilePath removes the keyfile. kdfIterations above zero replaces the PBKDF2 iterations. The salt is kept.
+// vault.json is then updated atomically with have_keyfile and kdf_iter, the other keys are preserved. The vault is
+// closed afterwards, open it again with the new credentials.
+func (v *Vault) Rekey(password, flagKeyFilePath string, kdfIterations int) error {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return errors.New("vault is not initialized")
+	}
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+	if password == "" {
+		return errors.New("empty vault password provided")
+	}
+
+	info := v.vaultInfo
+	if kdfIterations > 0 {
+		info.KDFIterations = kdfIterations
+	}
+	info.HasKeyfile = 0
+	if flagKeyFilePath != "" {
+		info.HasKeyfile = 1
+	}
+
+	masterPassword, err := v.generateMasterPassword([]byte(password), flagKeyFilePath)
+	if err != nil {
+		return errors.Wrap(err, "could not generate vault unlock key")
+	}
+	salt, err := v.extractSalt()
+	if err != nil {
+		return errors.Wrap(err, "could not get master password salt")
+	}
+
+	current := v.vaultInfo
+	v.vaultInfo = info
+	dbKey, err := v.deriveKey(masterPassword, salt)
+	v.vaultInfo = current
+	if err != nil {
+		return errors.Wrap(err, "could not derive database key from master password")
+	}
+
+	sqlDB, err := v.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "could not access the database")
+	}
+	// Other pooled connections would still use the old key
+	sqlDB.SetMaxOpenConns(1)
+
+	// The connection was opened with cipher_compatibility 3, rekey keeps those settings
+	if err := v.db.Exec(fmt.Sprintf(`PRAGMA rekey = "x'%s'"`, hex.EncodeToString(dbKey)[:masterKeyLength])).Error; err != nil {
+		return errors.Wrap(err, "could not re-key the database")
+	}
+	if err := sqlDB.Close(); err != nil {
+		return errors.Wrap(err, "could not close the database")
+	}
+	v.db = nil
+
+	if err := updateVaultInfo(v.vaultInfoFilename, map[string]interface{}{"have_keyfile": info.HasKeyfile, "kdf_iter": info.KDFIterations}); err != nil {
+		return errors.Wrap(err, "the database was re-keyed but vault.json could not be updated")
+	}
+	v.vaultInfo = info
+
+	return nil
+}
+
+// updateVaultInfo : set the keys of values in vault.json, through a temporary file renamed over it. The other keys
+// are preserved.
+func updateVaultInfo(path string, values map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	document := map[string]interface{}{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	for key, value := range values {
+		document[key] = value
+	}
+
+	data, err = json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".vault-json-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(append(data, '\n')); err == nil {
+		err = tmp.Chmod(stat.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,111 @@
+package enpass_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+	"github.com/sirupsen/logrus"
+)
+
+// readVaultInfo : the keys of vault.json
+func readVaultInfo(t *testing.T, vaultPath string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(vaultPath, "vault.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := map[string]interface{}{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// openWith : open the vault with the credentials, the vault is closed when the test ends
+func openWith(t *testing.T, vaultPath string, credentials *enpass.VaultCredentials) (*enpass.Vault, error) {
+	t.Helper()
+	vault, err := enpass.NewVault(vaultPath, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(vault.Close)
+	return vault, vault.Open(credentials, logrus.ErrorLevel, true)
+}
+
+func TestRekey(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{})
+
+	// A key enpass does not know about must survive the rewrite of vault.json
+	info := readVaultInfo(t, fixture.Path)
+	info["custom"] = "kept"
+	data, _ := json.Marshal(info)
+	if err := os.WriteFile(filepath.Join(fixture.Path, "vault.json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keyFile, err := enpass.GenerateKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFilePath := filepath.Join(t.TempDir(), "new.enpasskey")
+	if err := os.WriteFile(keyFilePath, keyFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name          string
+		password      string
+		keyFilePath   string
+		kdfIterations int
+		wantKeyFile   float64
+		wantIter      float64
+	}{
+		{"new password, keyfile added, new iterations", "new password", keyFilePath, 1000, 1, 1000},
+		{"keyfile removed, iterations kept", "third password", "", 0, 0, 1000},
+	}
+	old := fixture.Credentials()
+	for _, step := range steps {
+		vault, err := openWith(t, fixture.Path, old)
+		if err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		if err := vault.Rekey(step.password, step.keyFilePath, step.kdfIterations); err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+
+		current := enpass.NewVaultCredentials(step.password, step.keyFilePath)
+		vault, err = openWith(t, fixture.Path, current)
+		if err != nil {
+			t.Fatalf("%s: the vault does not open with the new credentials: %s", step.name, err)
+		}
+		items, err := vault.GetItemsByUUID([]string{enpasstest.ItemGitHub})
+		if err != nil {
+			t.Fatalf("%s: %s", step.name, err)
+		}
+		if password, _ := items[0].Field("password"); password.Value != "gh-s3cret!" {
+			t.Errorf("%s: the password reads %q", step.name, password.Value)
+		}
+
+		if _, err := openWith(t, fixture.Path, old); err == nil {
+			t.Errorf("%s: the vault still opens with the old credentials", step.name)
+		}
+		if step.keyFilePath != "" {
+			if _, err := openWith(t, fixture.Path, enpass.NewVaultCredentials(step.password, "")); err == nil {
+				t.Errorf("%s: the vault opens without its keyfile", step.name)
+			}
+		}
+
+		info := readVaultInfo(t, fixture.Path)
+		if info["have_keyfile"] != step.wantKeyFile || info["kdf_iter"] != step.wantIter {
+			t.Errorf("%s: have_keyfile %v, kdf_iter %v", step.name, info["have_keyfile"], info["kdf_iter"])
+		}
+		if info["custom"] != "kept" || info["vault_name"] != "fixture" {
+			t.Errorf("%s: the other keys of vault.json were lost: %v", step.name, info)
+		}
+		old = current
+	}
+}
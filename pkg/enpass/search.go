@@ -0,0 +1,310 @@
+package enpass
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/fuzzy"
+	"github.com/pkg/errors"
+	"github.com/thoas/go-funk"
+)
+
+const (
+	MatchExact = "exact"
+	MatchFuzzy = "fuzzy"
+	MatchLike  = "like"
+	MatchRegex = "regex"
+)
+
+var (
+	// ValidMatchModes : the modes accepted by Vault.MatchMode
+	ValidMatchModes = []string{MatchExact, MatchFuzzy, MatchLike, MatchRegex}
+
+	// ErrCardNotFound : no card matched the filters
+	ErrCardNotFound = errors.New("card not found")
+
+	// searchWeights : how much a hit in each part of an item counts towards its relevance
+	searchWeights = map[string]int{
+		"title":    10,
+		"subtitle": 6,
+		"url":      4,
+		"note":     2,
+		"field":    1,
+	}
+)
+
+type searchRow struct {
+	UUID     string
+	Title    string
+	Subtitle string
+	Note     string
+	Type     string
+	Value    string
+}
+
+// matcher : decides whether a text matches a single pattern and how well, following the match mode
+type matcher func(text string) (score int, ok bool)
+
+// newMatcher : build a matcher for pattern. In like mode a pattern without wildcards matches anywhere in the text
+// when substring is set, which is what a free text search expects.
+func newMatcher(mode, pattern string, flagCaseSensitive, substring bool) (matcher, error) {
+	// Regular expressions get the (?i) flag instead, lowering them would change their meaning
+	if !flagCaseSensitive && (mode == MatchExact || mode == MatchLike || mode == "") {
+		pattern = strings.ToLower(pattern)
+	}
+	normalize := func(text string) string {
+		if flagCaseSensitive {
+			return text
+		}
+		return strings.ToLower(text)
+	}
+
+	switch mode {
+	case MatchExact:
+		return func(text string) (int, bool) {
+			if normalize(text) == pattern {
+				return 3, true
+			}
+			return 0, false
+		}, nil
+	case MatchFuzzy:
+		return func(text string) (int, bool) {
+			score, ok := fuzzy.Score(pattern, text, flagCaseSensitive)
+			return max(score, 1), ok
+		}, nil
+	case MatchRegex:
+		expr := pattern
+		if !flagCaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid regular expression %q", pattern)
+		}
+		return func(text string) (int, bool) {
+			return 1, re.MatchString(text)
+		}, nil
+	case MatchLike, "":
+		if substring && !strings.ContainsAny(pattern, "%_") {
+			pattern = "%" + pattern + "%"
+		}
+		re := regexp.MustCompile(likeToRegexp(pattern))
+		bare := strings.Trim(pattern, "%")
+		return func(text string) (int, bool) {
+			text = normalize(text)
+			if !re.MatchString(text) {
+				return 0, false
+			}
+
+			// Exact and prefix hits rank above plain matches
+			if text == bare {
+				return 3, true
+			} else if strings.HasPrefix(text, bare) {
+				return 2, true
+			}
+			return 1, true
+		}, nil
+	}
+
+	return nil, fmt.Errorf("invalid match mode %q, valid: %s", mode, strings.Join(ValidMatchModes, ", "))
+}
+
+// likeToRegexp : translate an SQL LIKE pattern to an anchored regular expression
+func likeToRegexp(pattern string) string {
+	var expr strings.Builder
+	expr.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return expr.String()
+}
+
+// filterCards : keep the cards whose column matches any of the patterns in the vault match mode. The best score
+// of every kept card is added to its running score so matches can be ranked.
+func (v *Vault) filterCards(cards []Card, scores []int, column string, patterns []string, flagCaseSensitive bool) ([]Card, []int, error) {
+	return filterCards(v.MatchMode, cards, scores, column, patterns, flagCaseSensitive)
+}
+
+func filterCards(mode string, cards []Card, scores []int, column string, patterns []string, flagCaseSensitive bool) ([]Card, []int, error) {
+	if len(patterns) <= 0 {
+		return cards, scores, nil
+	}
+
+	matchers := []matcher{}
+	for _, pattern := range patterns {
+		m, err := newMatcher(mode, pattern, flagCaseSensitive, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	var (
+		kept       []Card
+		keptScores []int
+	)
+	for i, card := range cards {
+		best, found := 0, false
+		for _, m := range matchers {
+			if score, ok := m(cardColumn(card, column)); ok {
+				best, found = max(best, score), true
+			}
+		}
+		if found {
+			kept = append(kept, card)
+			keptScores = append(keptScores, scores[i]+best)
+		}
+	}
+
+	return kept, keptScores, nil
+}
+
+func cardColumn(card Card, column string) string {
+	switch column {
+	case "category":
+		return card.Category
+	case "label":
+		return card.Label
+	case "subtitle":
+		return card.Subtitle
+	case "title":
+		return card.Title
+	case "uuid":
+		return card.UUID
+	}
+	return ""
+}
+
+// FilterCards : apply the category, title, login, uuid and label filters to cards that were not read from a
+// vault, e.g. imported ones, following the match mode. The order of the cards is kept.
+func FilterCards(cards []Card, mode string, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive bool) ([]Card, error) {
+	if !funk.ContainsString(ValidMatchModes, mode) && mode != "" {
+		return nil, fmt.Errorf("invalid match mode %q, valid: %s", mode, strings.Join(ValidMatchModes, ", "))
+	}
+
+	var err error
+	scores := make([]int, len(cards))
+	for _, filter := range []struct {
+		column   string
+		patterns []string
+	}{
+		{"category", flagRecordCategory},
+		{"title", flagRecordTitle},
+		{"subtitle", flagRecordLogin},
+		{"uuid", flagRecordUuid},
+		{"label", flagLabel},
+	} {
+		if cards, scores, err = filterCards(mode, cards, scores, filter.column, filter.patterns, flagCaseSensitive); err != nil {
+			return nil, err
+		}
+	}
+
+	return cards, nil
+}
+
+// searchItems : scan the title, subtitle, note, URL and non-sensitive field values of every item for term and
+// return the relevance of every matching item keyed by UUID
+func (v *Vault) searchItems(term string, flagCaseSensitive bool) (map[string]int, error) {
+	m, err := newMatcher(v.MatchMode, term, flagCaseSensitive, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var searchRows []searchRow
+	err = v.db.Select("item.uuid", "item.title", "item.subtitle", "item.note", "itemfield.type", "itemfield.value").
+		Table("item").
+		Joins("LEFT JOIN itemfield ON uuid = item_uuid AND itemfield.sensitive = ? AND itemfield.deleted = ?", 0, 0).
+		Where("item.deleted = ?", 0).
+		Find(&searchRows).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search the database")
+	}
+
+	ranks := map[string]int{}
+	seen := map[string]bool{}
+	for _, row := range searchRows {
+		// The item columns repeat on every field row, only score them once
+		if !seen[row.UUID] {
+			seen[row.UUID] = true
+			for column, text := range map[string]string{"title": row.Title, "subtitle": row.Subtitle, "note": row.Note} {
+				if score, ok := m(text); ok && text != "" {
+					ranks[row.UUID] += searchWeights[column] * score
+				}
+			}
+		}
+
+		if row.Value == "" {
+			continue
+		}
+		weight := searchWeights["field"]
+		if row.Type == "url" {
+			weight = searchWeights["url"]
+		}
+		if score, ok := m(row.Value); ok {
+			ranks[row.UUID] += weight * score
+		}
+	}
+
+	return ranks, nil
+}
+
+// Suggest : return up to limit item titles close to the given terms by edit distance, closest first
+func (v *Vault) Suggest(terms []string, limit int) ([]string, error) {
+	if v.db == nil {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	var titles []string
+	if err := v.db.Table("item").Where("deleted = ?", 0).Distinct("title").Pluck("title", &titles).Error; err != nil {
+		return nil, errors.Wrap(err, "could not retrieve titles from database")
+	}
+
+	distances := map[string]int{}
+	for _, term := range terms {
+		term = strings.Trim(term, "%*")
+		if term == "" {
+			continue
+		}
+		threshold := max(2, len([]rune(term))/3)
+
+		for _, title := range titles {
+			title = strings.TrimSpace(title)
+			best := fuzzy.Distance(term, title)
+			for _, word := range strings.Fields(title) {
+				best = min(best, fuzzy.Distance(term, word))
+			}
+			if best > threshold {
+				continue
+			}
+			if previous, ok := distances[title]; !ok || best < previous {
+				distances[title] = best
+			}
+		}
+	}
+
+	suggestions := make([]string, 0, len(distances))
+	for title := range distances {
+		suggestions = append(suggestions, title)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if distances[suggestions[i]] != distances[suggestions[j]] {
+			return distances[suggestions[i]] < distances[suggestions[j]]
+		}
+		return suggestions[i] < suggestions[j]
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
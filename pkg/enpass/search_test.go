@@ -0,0 +1,97 @@
+package enpass
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLikeToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		matches []string
+		misses  []string
+	}{
+		{"github", `(?s)^github$`, []string{"github"}, []string{"github.com", "my github"}},
+		{"%hub", `(?s)^.*hub$`, []string{"github", "hub"}, []string{"hubs"}},
+		{"git%", `(?s)^git.*$`, []string{"git", "github"}, []string{"a git"}},
+		{"g_t", `(?s)^g.t$`, []string{"git", "got"}, []string{"gt", "gift"}},
+		{"%a.b%", `(?s)^.*a\.b.*$`, []string{"x a.b y"}, []string{"axb"}},
+		{"(1+1)*[x]", `(?s)^\(1\+1\)\*\[x\]$`, []string{"(1+1)*[x]"}, []string{"11x"}},
+		{`a\b$^|?{}`, `(?s)^a\\b\$\^\|\?\{\}$`, []string{`a\b$^|?{}`}, []string{"ab"}},
+		{"%", `(?s)^.*$`, []string{"", "line one\nline two"}, nil},
+	}
+	for _, test := range tests {
+		expr := likeToRegexp(test.pattern)
+		if expr != test.want {
+			t.Errorf("%q: got %s, want %s", test.pattern, expr, test.want)
+			continue
+		}
+		match, err := newMatcher(MatchLike, test.pattern, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range test.matches {
+			if _, ok := match(text); !ok {
+				t.Errorf("%q does not match %q", test.pattern, text)
+			}
+		}
+		for _, text := range test.misses {
+			if _, ok := match(text); ok {
+				t.Errorf("%q matches %q", test.pattern, text)
+			}
+		}
+	}
+}
+
+func TestNewMatcherCase(t *testing.T) {
+	for _, mode := range ValidMatchModes {
+		insensitive, err := newMatcher(mode, "GitHub", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sensitive, err := newMatcher(mode, "GitHub", true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := insensitive("github"); !ok {
+			t.Errorf("%s: the case-insensitive matcher missed github", mode)
+		}
+		if _, ok := sensitive("github"); ok {
+			t.Errorf("%s: the case-sensitive matcher matched github", mode)
+		}
+		if _, ok := sensitive("GitHub"); !ok {
+			t.Errorf("%s: the case-sensitive matcher missed GitHub", mode)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	vault := openNewVault(t)
+	items := []Item{{Title: "GitHub"}, {Title: "GitLab"}, {Title: "My Bank"}, {Title: "Mail"}}
+	if err := vault.AddItems(items); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		terms []string
+		limit int
+		want  []string
+	}{
+		{[]string{"githib"}, 5, []string{"GitHub", "GitLab"}},
+		{[]string{"githib"}, 1, []string{"GitHub"}},
+		{[]string{"%bnak%"}, 5, []string{"My Bank"}},
+		{[]string{"mail", "gitlab"}, 5, []string{"GitLab", "Mail", "GitHub"}},
+		{[]string{"nothing like it"}, 5, []string{}},
+		{[]string{"%"}, 5, []string{}},
+	}
+	for _, test := range tests {
+		got, err := vault.Suggest(test.terms, test.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %q, want %q", test.terms, got, test.want)
+		}
+	}
+}
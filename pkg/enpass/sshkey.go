@@ -0,0 +1,69 @@
+package enpass
+
+import (
+	"github.com/gdanko/enpass/util"
+	"github.com/pkg/errors"
+)
+
+// GetSSHKeyCandidates : return every non-empty field of the items in one of the given categories and every field
+// carrying one of the given labels. The values are decrypted but not validated, callers decide what is a key.
+func (v *Vault) GetSSHKeyCandidates(categories, labels []string, flagCaseSensitive bool) ([]Card, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	if len(categories) <= 0 && len(labels) <= 0 {
+		return nil, errors.New("at least one ssh key category or label is required")
+	}
+
+	var fieldRows []RawCard
+
+	query := v.db.Select("item.uuid", "itemField.type", "item.created_at AS created", "item.updated_at AS updated", "item.title", "item.subtitle", "item.trashed", "item.deleted", "item.category", "itemfield.label", "itemfield.value AS raw_value", "item.key", "itemfield.sensitive").Table("item").Joins("INNER JOIN itemfield ON uuid = item_uuid")
+
+	query.Where("item.deleted = ?", 0)
+	query.Where("item.trashed = ?", 0)
+	query.Where("itemfield.deleted = ?", 0)
+	query.Where("itemfield.value != ?", "")
+
+	matches := v.processFilters(categories, "category", flagCaseSensitive)
+	if len(labels) > 0 {
+		matches = matches.Or(v.processFilters(labels, "label", flagCaseSensitive))
+	}
+	query.Where(matches)
+	query.Order("title")
+
+	if err := query.Find(&fieldRows).Error; err != nil {
+		return nil, errors.Wrap(err, "could not retrieve ssh key fields from database")
+	}
+
+	var cards []Card
+	for _, row := range fieldRows {
+		card := Card{
+			UUID:      row.UUID,
+			Created:   util.ToHuman(row.Created),
+			Type:      row.Type,
+			Updated:   util.ToHuman(row.Updated),
+			Title:     row.Title,
+			Subtitle:  row.Subtitle,
+			Category:  row.Category,
+			Label:     row.Label,
+			Sensitive: row.Sensitive,
+			RawValue:  row.RawValue,
+			Key:       row.Key,
+		}
+		if err := card.Decrypt(); err != nil {
+			v.logger.WithError(err).Warningf("could not decrypt the %q field of %q, skipping it", card.Label, card.Title)
+			continue
+		}
+
+		// Only password fields are encrypted, everything else is stored as-is
+		if card.DecryptedValue == "" {
+			card.DecryptedValue = card.RawValue
+		}
+		card.RawValue = ""
+		card.Key = []byte{}
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
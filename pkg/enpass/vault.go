@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	// sqlcipher is necessary for sqlite crypto support
 	"github.com/gdanko/enpass/globals"
+	"github.com/gdanko/enpass/pkg/dsl"
 	"github.com/gdanko/enpass/pkg/unlock"
 	"github.com/gdanko/enpass/util"
 	sqlcipher "github.com/gdanko/gorm-sqlcipher"
@@ -50,7 +53,12 @@ type Vault struct {
 
 	// settings for filtering entries
 	FilterFields []string
-	FilterAnd    bool
+	MatchMode    string
+	Query        string
+	Search       string
+
+	// Deprecated: FilterAnd is ignored, combine the filters with AND in Query instead.
+	FilterAnd bool
 
 	// vault.enpassdb : SQLCipher database
 	databaseFilename string
@@ -66,6 +74,10 @@ type Vault struct {
 
 	// vault.json : contains info about your vault for synchronizing
 	vaultInfo VaultInfo
+
+	// the archive the vault was extracted from, and the temporary directory it was extracted to
+	archivePath   string
+	extractedPath string
 }
 
 type VaultCredentials struct {
@@ -85,6 +97,16 @@ func prompt(logger *logrus.Logger, flagNonInteractive bool, msg string) string {
 	return ""
 }
 
+// NewVaultCredentials : the credentials of a vault protected by the password and the keyfile, when one is given
+func NewVaultCredentials(password, keyFilePath string) *VaultCredentials {
+	return &VaultCredentials{Password: password, flagKeyFilePath: keyFilePath}
+}
+
+// KeyFilePath : the keyfile of the credentials, empty without one
+func (credentials *VaultCredentials) KeyFilePath() string {
+	return credentials.flagKeyFilePath
+}
+
 func (credentials *VaultCredentials) IsComplete() bool {
 	return credentials.Password != "" || credentials.DBKey != nil
 }
@@ -118,7 +140,12 @@ func DetermineVaultPath(logger *logrus.Logger, flagVaultPath string) (vaultPath
 func OpenVault(logger *logrus.Logger, flagEnablePin bool, flagNonInteractive bool, vaultPath string, flagKeyFilePath string, logLevel logrus.Level, flagNoColor bool) (vault *Vault, credentials *VaultCredentials, err error) {
 	vault, err = NewVault(vaultPath, logLevel, flagNoColor)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+
+	if flagKeyFilePath == "" && globals.GetConfig().KeyFile != "" {
+		flagKeyFilePath = util.ExpandPath(globals.GetConfig().KeyFile)
+		logger.Debugf("using the keyfile %s from the configuration", flagKeyFilePath)
 	}
 
 	var store *unlock.SecureStore
@@ -165,14 +192,25 @@ func InitializeStore(logger *logrus.Logger, vaultPath string, flagNonInteractive
 
 func AssembleVaultCredentials(logger *logrus.Logger, vaultPath string, flagKeyFilePath string, flagNonInteractive bool, store *unlock.SecureStore) *VaultCredentials {
 	var (
+		enpassConfig            = globals.GetConfig()
 		vaultPassword           string
 		vaultPasswordFromEnv    = os.Getenv("MASTERPW")
-		vaultPasswordFromConfig = globals.GetConfig().VaultPassword
+		vaultPasswordFromConfig = enpassConfig.VaultPassword
 	)
 
 	if vaultPasswordFromConfig != "" {
 		logger.Debug("found a vault password in the configuration file")
 		vaultPassword = vaultPasswordFromConfig
+	} else if enpassConfig.PasswordCommand != "" {
+		logger.Debug("running the configured password command")
+		out, err := exec.Command("sh", "-c", enpassConfig.PasswordCommand).Output()
+		if err != nil {
+			logger.WithError(err).Fatal("the password command failed")
+		}
+		vaultPassword = strings.TrimRight(string(out), "\r\n")
+	} else if enpassConfig.PasswordEnv != "" && os.Getenv(enpassConfig.PasswordEnv) != "" {
+		logger.Debugf("found a vault password in $%s", enpassConfig.PasswordEnv)
+		vaultPassword = os.Getenv(enpassConfig.PasswordEnv)
 	} else if vaultPasswordFromEnv != "" {
 		logger.Debug("found a vault password in the environment")
 		vaultPassword = vaultPasswordFromEnv
@@ -209,10 +247,24 @@ func NewVault(vaultPath string, logLevel logrus.Level, flagNoColor bool) (*Vault
 	v.logger.SetLevel(logLevel)
 
 	vaultPath, _ = filepath.EvalSymlinks(vaultPath)
+	if info, err := os.Stat(vaultPath); err == nil && info.Mode().IsRegular() {
+		v.logger.Debugf("extracting the vault archive %s", vaultPath)
+		extracted, err := extractVaultArchive(vaultPath)
+		if err != nil {
+			return nil, err
+		}
+		v.archivePath = vaultPath
+		v.extractedPath = extracted
+		// logrus Exit and Fatal skip the deferred Close
+		logrus.RegisterExitHandler(v.removeExtracted)
+		vaultPath = extracted
+	}
+
 	v.databaseFilename = filepath.Join(vaultPath, vaultFileName)
 	v.vaultInfoFilename = filepath.Join(vaultPath, vaultInfoFileName)
 	v.logger.Debug("checking provided vault paths")
 	if err := v.checkPaths(); err != nil {
+		v.removeExtracted()
 		return nil, err
 	}
 
@@ -220,6 +272,7 @@ func NewVault(vaultPath string, logLevel logrus.Level, flagNoColor bool) (*Vault
 	var err error
 	v.vaultInfo, err = v.loadVaultInfo()
 	if err != nil {
+		v.removeExtracted()
 		return nil, errors.Wrap(err, "could not load vault info")
 	}
 
@@ -231,7 +284,9 @@ func NewVault(vaultPath string, logLevel logrus.Level, flagNoColor bool) (*Vault
 	return &v, nil
 }
 
-func (v *Vault) openEncryptedDatabase(path string, dbKey []byte, logLevel logrus.Level, flagNoColor bool) (err error) {
+// openEncryptedDatabase : open the SQLCipher database with the derived key. SQLCipher reads the salt from the
+// first bytes of an existing database, a new database needs it in the raw key.
+func (v *Vault) openEncryptedDatabase(path string, dbKey []byte, salt []byte, logLevel logrus.Level, flagNoColor bool) (err error) {
 	colorful := true
 	if flagNoColor {
 		colorful = false
@@ -267,9 +322,10 @@ func (v *Vault) openEncryptedDatabase(path string, dbKey []byte, logLevel logrus
 	// The raw key for the sqlcipher database is given
 	// by the first 64 characters of the hex-encoded key
 	dbName := fmt.Sprintf(
-		"%s?_pragma_key=x'%s'&_pragma_cipher_compatibility=3",
+		"%s?_pragma_key=x'%s%s'&_pragma_cipher_compatibility=3",
 		path,
 		hex.EncodeToString(dbKey)[:masterKeyLength],
+		hex.EncodeToString(salt),
 	)
 
 	v.db, err = gorm.Open(sqlcipher.Open(dbName), gormConfig)
@@ -337,7 +393,7 @@ func (v *Vault) Open(credentials *VaultCredentials, logLevel logrus.Level, flagN
 	}
 
 	v.logger.Debug("opening encrypted database")
-	if err := v.openEncryptedDatabase(v.databaseFilename, credentials.DBKey, logLevel, flagNoColor); err != nil {
+	if err := v.openEncryptedDatabase(v.databaseFilename, credentials.DBKey, nil, logLevel, flagNoColor); err != nil {
 		return errors.Wrap(err, "could not open encrypted database")
 	}
 
@@ -362,8 +418,15 @@ func (v *Vault) Open(credentials *VaultCredentials, logLevel logrus.Level, flagN
 	return nil
 }
 
-// Close : close the connection to the underlying database. Always call this in the end.
+// HasKeyfile : report whether vault.json says the vault is protected by a keyfile
+func (v *Vault) HasKeyfile() bool {
+	return v.vaultInfo.HasKeyfile == 1
+}
+
+// Close : close the connection to the underlying database. Always call this in the end, it removes the vault
+// extracted from an archive.
 func (v *Vault) Close() {
+	v.removeExtracted()
 	// if v.db != nil {
 	// 	err := v.db.Close()
 	// 	v.logger.WithError(err).Debug("closed vault")
@@ -376,7 +439,7 @@ func (v *Vault) GetEntries(flagCardType string, flagRecordCategory, flagRecordTi
 		return nil, errors.New("vault is not initialized")
 	}
 
-	rows, err := v.executeEntryQuery(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy)
+	rows, err := v.executeEntryQuery(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, false)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not retrieve cards from database")
 	}
@@ -385,7 +448,7 @@ func (v *Vault) GetEntries(flagCardType string, flagRecordCategory, flagRecordTi
 	for _, card := range rows {
 		err = card.Decrypt()
 		if err != nil {
-			panic(err)
+			return nil, errors.Wrapf(err, "could not decrypt the %q field of %q, run enpass verify", card.Label, card.Title)
 		}
 		cards = append(cards, Card{
 			UUID:           card.UUID,
@@ -403,6 +466,9 @@ func (v *Vault) GetEntries(flagCardType string, flagRecordCategory, flagRecordTi
 			Sensitive:      card.Sensitive,
 			Icon:           card.Icon,
 			DecryptedValue: card.DecryptedValue,
+			CreatedAt:      card.CreatedAt,
+			UpdatedAt:      card.UpdatedAt,
+			LastUsedAt:     card.LastUsedAt,
 		})
 	}
 
@@ -429,7 +495,7 @@ func (v *Vault) GetEntry(flagCardType string, flagRecordCategory, flagRecordTitl
 	}
 
 	if ret == nil {
-		return nil, errors.New("card not found")
+		return nil, ErrCardNotFound
 	}
 
 	return ret, nil
@@ -441,6 +507,15 @@ func (v *Vault) processFilters(filterList []string, columnName string, flagCaseS
 
 	if len(filterList) > 0 {
 		for _, item := range filterList {
+			if v.MatchMode == MatchExact {
+				collation := " COLLATE NOCASE"
+				if flagCaseSensitive {
+					collation = ""
+				}
+				tx = tx.Or(fmt.Sprintf("%s = ?%s", columnName, collation), item)
+				continue
+			}
+
 			keyword = "LIKE"
 			if flagCaseSensitive {
 				keyword = "GLOB"
@@ -455,7 +530,139 @@ func (v *Vault) processFilters(filterList []string, columnName string, flagCaseS
 	return tx
 }
 
-func (v *Vault) executeEntryQuery(flagCardType string, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive bool, flagOrderBy []string, validOrderBy []string) (cards []Card, err error) {
+// ExplainEntries : return the SQL statement and arguments of the query GetEntries runs for the filters, or
+// GetItems with allFields, without opening a vault. Regular expressions, fuzzy patterns and --search are applied
+// to the rows afterwards, they are not part of the statement.
+func ExplainEntries(matchMode, query string, flagCardType string, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive bool, flagOrderBy []string, validOrderBy []string, allFields bool, logLevel logrus.Level, flagNoColor bool) (string, []interface{}, error) {
+	db, err := gorm.Open(sqlcipher.Open(":memory:"), &gorm.Config{
+		DryRun:      true,
+		QueryFields: true,
+		Logger:      logger.Discard,
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "could not open an in-memory database")
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	v := Vault{
+		logger:    *util.ConfigureLogger(logLevel, flagNoColor),
+		db:        db,
+		MatchMode: matchMode,
+		Query:     query,
+	}
+	tx, err := v.entryQuery(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, allFields)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var explained []RawCard
+	statement := tx.Find(&explained).Statement
+	return statement.SQL.String(), statement.Vars, nil
+}
+
+// executeEntryQuery : return one card per matching field. With allFields the card type and the default labels
+// are not applied, so every field of the matching items is returned.
+func (v *Vault) executeEntryQuery(flagCardType string, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive bool, flagOrderBy []string, validOrderBy []string, allFields bool) (cards []Card, err error) {
+	query, err := v.entryQuery(flagCardType, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel, flagCaseSensitive, flagOrderBy, validOrderBy, allFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Regular expressions and fuzzy patterns cannot be expressed in SQLite, they are applied to the rows below
+	postFilter := v.MatchMode == MatchRegex || v.MatchMode == MatchFuzzy
+
+	var ranks map[string]int
+	if v.Search != "" {
+		ranks, err = v.searchItems(v.Search, flagCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query.Find(&rows)
+
+	for i := range rows {
+		cards = append(cards, Card{
+			UUID:           rows[i].UUID,
+			Created:        util.ToHuman(rows[i].Created),
+			Type:           rows[i].Type,
+			Updated:        util.ToHuman(rows[i].Updated),
+			Title:          rows[i].Title,
+			Subtitle:       rows[i].Subtitle,
+			Note:           rows[i].Note,
+			Trashed:        rows[i].Trashed,
+			Deleted:        rows[i].Deleted,
+			Category:       rows[i].Category,
+			Label:          rows[i].Label,
+			LastUsed:       util.ToHuman(rows[i].LastUsed),
+			Sensitive:      rows[i].Sensitive,
+			Icon:           rows[i].Icon,
+			DecryptedValue: rows[i].DecryptedValue,
+			CreatedAt:      rows[i].Created,
+			UpdatedAt:      rows[i].Updated,
+			LastUsedAt:     rows[i].LastUsed,
+			RawValue:       rows[i].RawValue,
+			Key:            rows[i].Key,
+		})
+	}
+
+	if !postFilter && ranks == nil {
+		return cards, nil
+	}
+
+	scores := make([]int, len(cards))
+	if ranks != nil {
+		var found []Card
+		scores = scores[:0]
+		for _, card := range cards {
+			if rank, ok := ranks[card.UUID]; ok {
+				found = append(found, card)
+				scores = append(scores, rank)
+			}
+		}
+		cards = found
+	}
+
+	if postFilter {
+		for _, filter := range []struct {
+			column   string
+			patterns []string
+		}{
+			{"category", flagRecordCategory},
+			{"title", flagRecordTitle},
+			{"subtitle", flagRecordLogin},
+			{"uuid", flagRecordUuid},
+		} {
+			cards, scores, err = v.filterCards(cards, scores, filter.column, filter.patterns, flagCaseSensitive)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Rank by relevance when searching or fuzzy matching, the requested order breaks ties
+	if ranks != nil || v.MatchMode == MatchFuzzy {
+		order := make([]int, len(cards))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return scores[order[i]] > scores[order[j]]
+		})
+		ranked := make([]Card, len(cards))
+		for i, index := range order {
+			ranked[i] = cards[index]
+		}
+		cards = ranked
+	}
+
+	return cards, nil
+}
+
+// entryQuery : build the query of executeEntryQuery, every filter that SQLite can express is part of it
+func (v *Vault) entryQuery(flagCardType string, flagRecordCategory, flagRecordTitle, flagRecordLogin, flagRecordUuid, flagLabel []string, flagCaseSensitive bool, flagOrderBy []string, validOrderBy []string, allFields bool) (*gorm.DB, error) {
 	var (
 		configDefaultLabels []string
 		configOrderByFields []string
@@ -474,7 +681,7 @@ func (v *Vault) executeEntryQuery(flagCardType string, flagRecordCategory, flagR
 
 	if len(flagLabel) > 0 {
 		labels = flagLabel
-	} else if len(flagLabel) <= 0 {
+	} else if !allFields {
 		configDefaultLabels = globals.GetConfig().DefaultLabels
 		if len(configDefaultLabels) > 0 {
 			labels = configDefaultLabels
@@ -484,14 +691,31 @@ func (v *Vault) executeEntryQuery(flagCardType string, flagRecordCategory, flagR
 	query := v.db.Select("item.uuid", "itemField.type", "item.created_at AS created", "item.updated_at AS updated", "item.title", "item.subtitle", "item.note", "item.trashed", "item.deleted", "item.category", "itemfield.label", "itemfield.value AS raw_value", "item.key", "item.last_used", "itemfield.sensitive", "item.icon").Table("item").Joins("INNER JOIN itemfield ON uuid = item_uuid")
 
 	query.Where("item.deleted = ?", 0)
-	query.Where("type = ?", flagCardType)
+	if !allFields {
+		query.Where("type = ?", flagCardType)
+	}
+
+	if !funk.ContainsString(ValidMatchModes, v.MatchMode) && v.MatchMode != "" {
+		return nil, fmt.Errorf("invalid match mode %q, valid: %s", v.MatchMode, strings.Join(ValidMatchModes, ", "))
+	}
 
-	query.Where(v.processFilters(flagRecordCategory, "category", flagCaseSensitive))
-	query.Where(v.processFilters(flagRecordTitle, "title", flagCaseSensitive))
-	query.Where(v.processFilters(flagRecordLogin, "subtitle", flagCaseSensitive))
-	query.Where(v.processFilters(flagRecordUuid, "uuid", flagCaseSensitive))
+	// Regular expressions and fuzzy patterns are applied to the rows by executeEntryQuery
+	if v.MatchMode != MatchRegex && v.MatchMode != MatchFuzzy {
+		query.Where(v.processFilters(flagRecordCategory, "category", flagCaseSensitive))
+		query.Where(v.processFilters(flagRecordTitle, "title", flagCaseSensitive))
+		query.Where(v.processFilters(flagRecordLogin, "subtitle", flagCaseSensitive))
+		query.Where(v.processFilters(flagRecordUuid, "uuid", flagCaseSensitive))
+	}
 	query.Where(v.processFilters(labels, "label", flagCaseSensitive))
 
+	if v.Query != "" {
+		compiled, err := dsl.Compile(v.Query, flagCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		query.Where(compiled.SQL, compiled.Args...)
+	}
+
 	if len(orderByFields) > 0 {
 		badFields := funk.SubtractString(orderByFields, validOrderBy)
 		goodFields := funk.IntersectString(orderByFields, validOrderBy)
@@ -507,28 +731,5 @@ func (v *Vault) executeEntryQuery(flagCardType string, flagRecordCategory, flagR
 		}
 	}
 
-	query.Find(&rows)
-
-	for i := range rows {
-		cards = append(cards, Card{
-			UUID:           rows[i].UUID,
-			Created:        util.ToHuman(rows[i].Created),
-			Type:           rows[i].Type,
-			Updated:        util.ToHuman(rows[i].Updated),
-			Title:          rows[i].Title,
-			Subtitle:       rows[i].Subtitle,
-			Note:           rows[i].Note,
-			Trashed:        rows[i].Trashed,
-			Deleted:        rows[i].Deleted,
-			Category:       rows[i].Category,
-			Label:          rows[i].Label,
-			LastUsed:       util.ToHuman(rows[i].LastUsed),
-			Icon:           rows[i].Icon,
-			DecryptedValue: rows[i].DecryptedValue,
-			RawValue:       rows[i].RawValue,
-			Key:            rows[i].Key,
-		})
-	}
-
-	return cards, nil
+	return query, nil
 }
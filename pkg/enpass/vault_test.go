@@ -0,0 +1,62 @@
+package enpass
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// openNewVault : create a vault in a temporary directory and open it
+func openNewVault(t *testing.T) *Vault {
+	t.Helper()
+	vaultPath := filepath.Join(t.TempDir(), "vault")
+	if err := CreateVault(vaultPath, "test", "password", "", logrus.ErrorLevel, true); err != nil {
+		t.Fatal(err)
+	}
+	vault, err := NewVault(vaultPath, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Open(NewVaultCredentials("password", ""), logrus.ErrorLevel, true); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(vault.Close)
+	return vault
+}
+
+func TestExplainEntries(t *testing.T) {
+	statement, args, err := ExplainEntries(MatchLike, "category:login AND NOT trashed", "password", nil, []string{"git%"}, nil, nil, nil, false, []string{"title"}, []string{"title"}, false, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"SELECT item.uuid,",
+		"FROM `item` INNER JOIN itemfield ON uuid = item_uuid",
+		"WHERE item.deleted = ? AND type = ? AND title LIKE ? AND (((item.category LIKE ? ESCAPE '\\') AND (NOT (item.trashed != 0))))",
+		"ORDER BY title",
+	} {
+		if !strings.Contains(statement, want) {
+			t.Errorf("the statement lacks %q:\n%s", want, statement)
+		}
+	}
+	if want := []interface{}{0, "password", "git%", "login"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args %v, want %v", args, want)
+	}
+
+	// Every field of the items, the card type is not applied
+	statement, _, err = ExplainEntries(MatchRegex, "title:x", "password", nil, []string{"^git"}, nil, nil, nil, false, nil, nil, true, logrus.ErrorLevel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(statement, "type = ?") || strings.Contains(statement, " title LIKE ?") {
+		t.Errorf("regular expressions and the card type should not be part of the statement:\n%s", statement)
+	}
+
+	if _, _, err := ExplainEntries("bogus", "title:x", "password", nil, nil, nil, nil, nil, false, nil, nil, false, logrus.ErrorLevel, true); err == nil {
+		t.Error("an invalid match mode was accepted")
+	}
+}
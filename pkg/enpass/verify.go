@@ -0,0 +1,129 @@
+package enpass
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// The checks of Verify
+const (
+	CheckDecrypt     = "decrypt"
+	CheckDuplicate   = "duplicate-uuid"
+	CheckItemKey     = "item-key"
+	CheckItemsCount  = "items-count"
+	CheckOrphanField = "orphaned-field"
+)
+
+// VerifyProblem : a problem found by Verify, UUID and Title name the item when there is one
+type VerifyProblem struct {
+	Check   string `json:"check" yaml:"check"`
+	UUID    string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// VerifyReport : what Verify checked and the problems it found, Decrypted counts the password fields that decrypt
+type VerifyReport struct {
+	Vault     string          `json:"vault" yaml:"vault"`
+	Items     int             `json:"items" yaml:"items"`
+	Fields    int             `json:"fields" yaml:"fields"`
+	Decrypted int             `json:"decrypted" yaml:"decrypted"`
+	Problems  []VerifyProblem `json:"problems" yaml:"problems"`
+}
+
+type verifyItem struct {
+	UUID    string
+	Title   string
+	Key     []byte
+	Deleted int64
+}
+
+type verifyField struct {
+	ItemUUID     string
+	ItemFieldUID int
+	Label        string
+	Type         string
+	Value        string
+	Deleted      int64
+}
+
+// Verify : check the integrity of the opened vault without stopping at the first problem. Every password field of
+// every item is decrypted, item keys must hold 44 bytes, fields must belong to an item, uuids must be unique and
+// vault_items_count of vault.json must match the items that are not deleted.
+func (v *Vault) Verify() (*VerifyReport, error) {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	var items []verifyItem
+	if err := v.db.Select("uuid", "title", "key", "deleted").Table("item").Find(&items).Error; err != nil {
+		return nil, errors.Wrap(err, "could not retrieve items from database")
+	}
+	var fields []verifyField
+	if err := v.db.Select("item_uuid", "item_field_uid", "label", "type", "value", "deleted").Table("itemfield").Find(&fields).Error; err != nil {
+		return nil, errors.Wrap(err, "could not retrieve item fields from database")
+	}
+
+	report := &VerifyReport{Vault: v.vaultInfo.VaultName, Problems: []VerifyProblem{}}
+	problem := func(check, uuid, title, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, VerifyProblem{Check: check, UUID: uuid, Title: title, Message: fmt.Sprintf(format, args...)})
+	}
+
+	byUUID := map[string]verifyItem{}
+	seen := map[string]int{}
+	for _, item := range items {
+		seen[item.UUID]++
+		if seen[item.UUID] == 2 {
+			problem(CheckDuplicate, item.UUID, item.Title, "the uuid is used by several items")
+		}
+		byUUID[item.UUID] = item
+		if item.Deleted != 0 {
+			continue
+		}
+		report.Items++
+		if len(item.Key) != 44 {
+			problem(CheckItemKey, item.UUID, item.Title, "the item key holds %d bytes instead of 44", len(item.Key))
+		}
+	}
+
+	orphans := map[string]int{}
+	for _, field := range fields {
+		item, ok := byUUID[field.ItemUUID]
+		if !ok {
+			orphans[field.ItemUUID]++
+			continue
+		}
+		if field.Deleted != 0 || item.Deleted != 0 {
+			continue
+		}
+		report.Fields++
+		// Only password fields are encrypted, other sensitive fields are stored as-is
+		if field.Type != "password" || field.Value == "" {
+			continue
+		}
+
+		card := Card{UUID: field.ItemUUID, Type: field.Type, RawValue: field.Value, Key: item.Key}
+		if err := card.Decrypt(); err != nil {
+			problem(CheckDecrypt, item.UUID, item.Title, "the %q field does not decrypt: %s", field.Label, err)
+			continue
+		}
+		report.Decrypted++
+	}
+
+	orphaned := []string{}
+	for uuid := range orphans {
+		orphaned = append(orphaned, uuid)
+	}
+	sort.Strings(orphaned)
+	for _, uuid := range orphaned {
+		problem(CheckOrphanField, uuid, "", "%d fields belong to no item", orphans[uuid])
+	}
+
+	if report.Items != v.vaultInfo.VaultNumItems {
+		problem(CheckItemsCount, "", "", "vault.json counts %d items, the database holds %d", v.vaultInfo.VaultNumItems, report.Items)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,91 @@
+package enpass
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	vault := openNewVault(t)
+	items := []Item{
+		{UUID: "a2ec30c0-aeed-41f7-aed7-cc50e69ff501", Title: "GitHub", Fields: []ItemField{
+			{Label: "Username", Type: "username", Value: "octocat"},
+			{Label: "Password", Type: "password", Value: "s3cret", Sensitive: true},
+			{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			{Label: "Old password", Type: "password"},
+		}},
+		{UUID: "a2ec30c0-aeed-41f7-aed7-cc50e69ff502", Title: "Bank", Fields: []ItemField{
+			{Label: "Password", Type: "password", Value: "b4nk", Sensitive: true},
+		}},
+	}
+	if err := vault.AddItems(items); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := vault.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sensitive text and the empty password are not encrypted, they are not counted as decrypted
+	want := &VerifyReport{Vault: "test", Items: 2, Fields: 5, Decrypted: 2, Problems: []VerifyProblem{}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got %+v, want %+v", report, want)
+	}
+}
+
+func TestVerifyProblems(t *testing.T) {
+	vault := openNewVault(t)
+	items := []Item{
+		{UUID: "a2ec30c0-aeed-41f7-aed7-cc50e69ff501", Title: "GitHub", Fields: []ItemField{
+			{UID: 1, Label: "Password", Type: "password", Value: "s3cret"},
+		}},
+		{UUID: "a2ec30c0-aeed-41f7-aed7-cc50e69ff502", Title: "Bank", Fields: []ItemField{
+			{UID: 1, Label: "Password", Type: "password", Value: "b4nk"},
+		}},
+	}
+	if err := vault.AddItems(items); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, statement := range []string{
+		// A password that does not decrypt with the item key
+		`UPDATE itemfield SET value = '00112233445566778899aabbccddeeff0011' WHERE item_uuid = 'a2ec30c0-aeed-41f7-aed7-cc50e69ff501'`,
+		`UPDATE item SET key = x'0102' WHERE uuid = 'a2ec30c0-aeed-41f7-aed7-cc50e69ff502'`,
+		`INSERT INTO itemfield (item_uuid, label, type, value) VALUES ('gone', 'Username', 'username', 'x')`,
+		// A non-unique copy of GitHub, the schema only allows it once the unique index is gone
+		`CREATE TABLE item_copy AS SELECT * FROM item`,
+		`DROP TABLE item`,
+		`ALTER TABLE item_copy RENAME TO item`,
+		`INSERT INTO item (uuid, title, key, deleted) SELECT uuid, title, key, 0 FROM item WHERE title = 'GitHub'`,
+	} {
+		if err := vault.db.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %s", statement, err)
+		}
+	}
+
+	// The copy is an item of its own for the count
+	vault.vaultInfo.VaultNumItems = 3
+	report, err := vault.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []string{}
+	for _, problem := range report.Problems {
+		checks = append(checks, problem.Check+" "+problem.Title)
+	}
+	sort.Strings(checks)
+	want := []string{"decrypt GitHub", "decrypt Bank", "duplicate-uuid GitHub", "item-key Bank", "orphaned-field "}
+	sort.Strings(want)
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("got %q, want %q", checks, want)
+	}
+	if report.Decrypted != 0 || report.Items != 3 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+
+	vault.vaultInfo.VaultNumItems = 2
+	if report, _ := vault.Verify(); report.Problems[len(report.Problems)-1].Check != CheckItemsCount {
+		t.Errorf("the items count was not checked: %+v", report.Problems)
+	}
+}
@@ -0,0 +1,250 @@
+package enpass
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// NewUUID : a random uuid in the lower case form Enpass uses
+func NewUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// AddItems : write the items into the vault in a single transaction. Every item gets a new key, password fields
+// are encrypted with it. Items without a uuid get a new one, the timestamps default to now. vault_items_count of
+// vault.json is updated afterwards, by the other writes too. The Enpass application should be closed while the
+// vault is written to, it does not expect other writers.
+func (v *Vault) AddItems(items []Item) error {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return errors.New("vault is not initialized")
+	}
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	err := v.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := insertItem(tx, item); err != nil {
+				return errors.Wrapf(err, "could not add %q", item.Title)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return v.updateItemsCount()
+}
+
+// ReplaceItems : write the items into the vault in a single transaction, replacing the items with the same uuid
+// and their fields and folders, deleted ones included. Attachments of the replaced items are kept, those the items
+// carry are added or replace the ones with the same uuid. Items that do not exist yet are added like AddItems does.
+func (v *Vault) ReplaceItems(items []Item) error {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return errors.New("vault is not initialized")
+	}
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	err := v.db.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if item.UUID != "" {
+				for _, statement := range []string{
+					`DELETE FROM folder_items WHERE item_uuid = ?`,
+					`DELETE FROM itemfield WHERE item_uuid = ?`,
+					`DELETE FROM item WHERE uuid = ?`,
+				} {
+					if err := tx.Exec(statement, item.UUID).Error; err != nil {
+						return errors.Wrapf(err, "could not replace %q", item.Title)
+					}
+				}
+			}
+			if err := insertItem(tx, item); err != nil {
+				return errors.Wrapf(err, "could not replace %q", item.Title)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return v.updateItemsCount()
+}
+
+func insertItem(tx *gorm.DB, item Item) error {
+	now := time.Now().Unix()
+	if item.UUID == "" {
+		item.UUID = NewUUID()
+	}
+	if item.Created <= 0 {
+		item.Created = now
+	}
+	if item.Updated <= 0 {
+		item.Updated = now
+	}
+	if item.Icon == "" {
+		item.Icon = `{"fav":"","image":{"file":"misc/login"},"type":1,"uuid":""}`
+	}
+
+	// The item key is an AES-256 key followed by the GCM nonce
+	key := make([]byte, 44)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	err := tx.Exec(
+		`INSERT INTO item (uuid, created_at, meta_updated_at, field_updated_at, title, subtitle, note, icon, favorite, trashed, archived, deleted, auto_submit, form_data, category, template, wearable, usage_count, last_used, key, extra, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, '', ?, ?, 0, 0, ?, ?, '', ?)`,
+		item.UUID, item.Created, item.Updated, item.Updated, item.Title, item.Subtitle, item.Note, item.Icon,
+		boolToInt(item.Favorite), boolToInt(item.Trashed), boolToInt(item.Archived), boolToInt(item.AutoSubmit),
+		item.Category, item.Template, item.LastUsed, key, item.Updated,
+	).Error
+	if err != nil {
+		return err
+	}
+
+	for index, field := range item.Fields {
+		uid, order := field.UID, field.Order
+		if uid <= 0 {
+			uid = index + 1
+		}
+		if order <= 0 {
+			order = index + 1
+		}
+
+		card := Card{UUID: item.UUID, Type: field.Type, DecryptedValue: field.Value, Key: key}
+		if err := card.Encrypt(); err != nil {
+			return err
+		}
+		// Enpass keeps the SHA-1 of passwords to find reused ones
+		hash := ""
+		if field.Type == "password" && field.Value != "" {
+			sum := sha1.Sum([]byte(field.Value))
+			hash = hex.EncodeToString(sum[:])
+		}
+
+		err := tx.Exec(
+			`INSERT INTO itemfield (item_uuid, item_field_uid, label, value, deleted, sensitive, historical, type, form_id, updated_at, value_updated_at, orde, wearable, history, initial, hash, strength, algo_version, expiry, excluded, pwned_check_time, extra)
+			VALUES (?, ?, ?, ?, 0, ?, 0, ?, '', ?, ?, ?, 0, '', '', ?, -1, 1, 0, 0, 0, '')`,
+			item.UUID, uid, field.Label, card.RawValue, boolToInt(field.Sensitive), field.Type,
+			item.Updated, item.Updated, order, hash,
+		).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, folder := range item.Folders {
+		if err := tx.Exec(`INSERT INTO folder_items (folder_uuid, item_uuid) VALUES (?, ?)`, folder, item.UUID).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, attachment := range item.Attachments {
+		if err := insertAttachment(tx, item.UUID, attachment); err != nil {
+			return errors.Wrapf(err, "could not add the attachment %q", attachment.Name)
+		}
+	}
+
+	return nil
+}
+
+// AddFolders : write the folders into the vault in a single transaction. Folders without a uuid get a new one.
+func (v *Vault) AddFolders(folders []Folder) error {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return errors.New("vault is not initialized")
+	}
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	return v.db.Transaction(func(tx *gorm.DB) error {
+		for _, folder := range folders {
+			if folder.UUID == "" {
+				folder.UUID = NewUUID()
+			}
+			if folder.Updated <= 0 {
+				folder.Updated = time.Now().Unix()
+			}
+			err := tx.Exec(
+				`INSERT INTO folder (uuid, title, parent_uuid, icon, extra, updated_at) VALUES (?, ?, ?, ?, '', ?)`,
+				folder.UUID, folder.Title, folder.ParentUUID, folder.Icon, folder.Updated,
+			).Error
+			if err != nil {
+				return errors.Wrapf(err, "could not add the folder %q", folder.Title)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteItems : delete the items the way Enpass does, the rows stay with the deleted flag set and their content
+// cleared
+func (v *Vault) DeleteItems(uuids []string) error {
+	if v.db == nil || v.vaultInfo.VaultName == "" {
+		return errors.New("vault is not initialized")
+	}
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	err := v.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(uuids); start += itemQueryChunk {
+			chunk := uuids[start:min(start+itemQueryChunk, len(uuids))]
+			err := tx.Exec(`UPDATE item SET deleted = 1, title = '', subtitle = '', note = '', key = X'', updated_at = ? WHERE uuid IN ?`, now, chunk).Error
+			if err != nil {
+				return errors.Wrap(err, "could not delete the items")
+			}
+			err = tx.Exec(`UPDATE itemfield SET deleted = 1, value = '', hash = '', updated_at = ? WHERE item_uuid IN ?`, now, chunk).Error
+			if err != nil {
+				return errors.Wrap(err, "could not delete the item fields")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return v.updateItemsCount()
+}
+
+// countItems : the number of items of the database that are not deleted, trashed ones included
+func (v *Vault) countItems() (int, error) {
+	var count int64
+	if err := v.db.Table("item").Where("deleted = ?", 0).Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, "could not count the items")
+	}
+	return int(count), nil
+}
+
+// updateItemsCount : set vault_items_count of vault.json to the number of items, as Enpass keeps it
+func (v *Vault) updateItemsCount() error {
+	count, err := v.countItems()
+	if err != nil {
+		return err
+	}
+	if err := updateVaultInfo(v.vaultInfoFilename, map[string]interface{}{"vault_items_count": count}); err != nil {
+		return errors.Wrap(err, "the items were written but vault_items_count could not be updated in vault.json")
+	}
+	v.vaultInfo.VaultNumItems = count
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package enpass_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+)
+
+func TestAddItems(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{Items: []enpass.Item{}})
+	vault := fixture.Open(t)
+
+	item := enpass.Item{
+		Title: "GitHub", Subtitle: "octocat", Category: "login", Template: "login.default",
+		Fields: []enpass.ItemField{
+			{UID: 1, Order: 1, Label: "Username", Type: "username", Value: "octocat"},
+			{UID: 2, Order: 2, Label: "Password", Type: "password", Value: "s3cret", Sensitive: true},
+		},
+	}
+	if err := vault.AddItems([]enpass.Item{item, item}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(fixture.Path, "vault.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		Count int `json:"vault_items_count"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Count != 2 {
+		t.Errorf("vault_items_count is %d, want 2", info.Count)
+	}
+
+	items, err := fixture.Open(t).GetItems([]string{}, []string{}, []string{}, []string{}, []string{}, false, false, []string{}, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].UUID == "" || items[0].UUID == items[1].UUID {
+		t.Fatalf("the items did not get their own uuids: %+v", items)
+	}
+	if password, _ := items[0].Field("password"); password.Value != "s3cret" {
+		t.Errorf("the password was not written encrypted and read back: %q", password.Value)
+	}
+}
+
+func TestAddItemsAttachments(t *testing.T) {
+	fixture := enpasstest.New(t, enpasstest.Options{Items: []enpass.Item{}})
+	vault := fixture.Open(t)
+
+	attachment := enpass.Attachment{UUID: "0c1d2e3f-4a5b-4c6d-8e7f-8091a2b3c4d5", Name: "codes.txt", Mime: "text/plain", Size: 19, Data: []byte("recovery 1111-2222\n")}
+	item := enpass.Item{UUID: "0c1d2e3f-4a5b-4c6d-8e7f-8091a2b3c4d6", Title: "GitHub", Attachments: []enpass.Attachment{attachment}}
+	if err := vault.AddItems([]enpass.Item{item}); err != nil {
+		t.Fatal(err)
+	}
+	vault.Close()
+
+	database, err := os.ReadFile(filepath.Join(fixture.Path, "vault.enpassdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(database, attachment.Data) {
+		t.Error("the attachment was written in clear")
+	}
+
+	vault = fixture.Open(t)
+	items, err := vault.GetItemsByUUID([]string{item.UUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || !reflect.DeepEqual(items[0].Attachments, []enpass.Attachment{attachment}) {
+		t.Fatalf("the attachment was not read back: %+v", items)
+	}
+
+	// A replaced item keeps its attachments, the one it carries replaces the one with its uuid
+	attachment.Data, attachment.Size = []byte("recovery 3333-4444\n"), 19
+	item.Attachments = []enpass.Attachment{attachment}
+	if err := vault.ReplaceItems([]enpass.Item{item, {UUID: item.UUID, Title: "GitHub"}}); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := vault.GetItemsByUUID([]string{item.UUID}); len(items) != 1 || !reflect.DeepEqual(items[0].Attachments, []enpass.Attachment{attachment}) {
+		t.Errorf("the attachment was not replaced: %+v", items)
+	}
+}
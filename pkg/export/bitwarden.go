@@ -0,0 +1,340 @@
+package export
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+
+	bitwardenFieldText   = 0
+	bitwardenFieldHidden = 1
+
+	// bitwardenIterations : the PBKDF2 iterations of password-protected exports, the Bitwarden default
+	bitwardenIterations = 600000
+)
+
+// BitwardenJSON : the unencrypted JSON export of Bitwarden
+type BitwardenJSON struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []BitwardenFolder `json:"folders"`
+	Items     []BitwardenItem   `json:"items"`
+}
+
+// BitwardenProtectedJSON : the password-protected JSON export of Bitwarden, Data holds the encrypted BitwardenJSON
+type BitwardenProtectedJSON struct {
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"passwordProtected"`
+	Salt              string `json:"salt"`
+	KdfType           int    `json:"kdfType"`
+	KdfIterations     int    `json:"kdfIterations"`
+	KdfMemory         *int   `json:"kdfMemory"`
+	KdfParallelism    *int   `json:"kdfParallelism"`
+	EncKeyValidation  string `json:"encKeyValidation_DO_NOT_EDIT"`
+	Data              string `json:"data"`
+}
+
+// BitwardenFolder : a folder of the Bitwarden export, nested folders are named Parent/Child
+type BitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// BitwardenItem : an item of the Bitwarden export, only the member matching Type is set
+type BitwardenItem struct {
+	ID              string               `json:"id"`
+	OrganizationID  *string              `json:"organizationId"`
+	FolderID        *string              `json:"folderId"`
+	Type            int                  `json:"type"`
+	Reprompt        int                  `json:"reprompt"`
+	Name            string               `json:"name"`
+	Notes           *string              `json:"notes"`
+	Favorite        bool                 `json:"favorite"`
+	Fields          []BitwardenField     `json:"fields,omitempty"`
+	Login           *BitwardenLogin      `json:"login,omitempty"`
+	SecureNote      *BitwardenSecureNote `json:"secureNote,omitempty"`
+	Card            *BitwardenCard       `json:"card,omitempty"`
+	Identity        *BitwardenIdentity   `json:"identity,omitempty"`
+	CollectionIDs   []string             `json:"collectionIds"`
+	RevisionDate    string               `json:"revisionDate,omitempty"`
+	CreationDate    string               `json:"creationDate,omitempty"`
+	PasswordHistory []BitwardenPassword  `json:"passwordHistory"`
+}
+
+// BitwardenField : a custom field of a Bitwarden item
+type BitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+// BitwardenLogin : the login of a Bitwarden item
+type BitwardenLogin struct {
+	URIs     []BitwardenURI `json:"uris,omitempty"`
+	Username string         `json:"username,omitempty"`
+	Password string         `json:"password,omitempty"`
+	TOTP     string         `json:"totp,omitempty"`
+}
+
+// BitwardenURI : a website of a Bitwarden login
+type BitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+// BitwardenSecureNote : the secure note of a Bitwarden item, Type is always 0
+type BitwardenSecureNote struct {
+	Type int `json:"type"`
+}
+
+// BitwardenCard : the payment card of a Bitwarden item
+type BitwardenCard struct {
+	CardholderName string `json:"cardholderName,omitempty"`
+	Brand          string `json:"brand,omitempty"`
+	Number         string `json:"number,omitempty"`
+	ExpMonth       string `json:"expMonth,omitempty"`
+	ExpYear        string `json:"expYear,omitempty"`
+	Code           string `json:"code,omitempty"`
+}
+
+// BitwardenIdentity : the identity of a Bitwarden item
+type BitwardenIdentity struct {
+	Title          string `json:"title,omitempty"`
+	FirstName      string `json:"firstName,omitempty"`
+	MiddleName     string `json:"middleName,omitempty"`
+	LastName       string `json:"lastName,omitempty"`
+	Address1       string `json:"address1,omitempty"`
+	Address2       string `json:"address2,omitempty"`
+	City           string `json:"city,omitempty"`
+	State          string `json:"state,omitempty"`
+	PostalCode     string `json:"postalCode,omitempty"`
+	Country        string `json:"country,omitempty"`
+	Company        string `json:"company,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	SSN            string `json:"ssn,omitempty"`
+	Username       string `json:"username,omitempty"`
+	PassportNumber string `json:"passportNumber,omitempty"`
+	LicenseNumber  string `json:"licenseNumber,omitempty"`
+}
+
+// BitwardenPassword : a previous password of a Bitwarden login
+type BitwardenPassword struct {
+	LastUsedDate string `json:"lastUsedDate"`
+	Password     string `json:"password"`
+}
+
+// WriteBitwardenJSON : write the items in the JSON format of Bitwarden. Logins, credit cards, identities and notes
+// map to the matching item types, anything else becomes a login or a secure note depending on its fields. Fields
+// without a slot of their own become custom fields, hidden when they are secret. Bitwarden items live in a single
+// folder, the first folder of the item is used. With opts.Password the export is password-protected.
+func WriteBitwardenJSON(w io.Writer, items []enpass.Item, opts Options) error {
+	if len(opts.KeyFile) > 0 {
+		return fmt.Errorf("the bitwarden-json format cannot be protected by a key file")
+	}
+
+	document := BitwardenJSON{
+		Folders: []BitwardenFolder{},
+		Items:   []BitwardenItem{},
+	}
+	paths := enpass.FolderPaths(opts.Folders)
+	for _, folder := range opts.Folders {
+		document.Folders = append(document.Folders, BitwardenFolder{ID: folder.UUID, Name: paths[folder.UUID]})
+	}
+	for _, item := range items {
+		document.Items = append(document.Items, bitwardenItem(item, paths, opts.IncludeSecrets))
+	}
+
+	if opts.Password == "" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	protected, err := bitwardenProtect(data, opts.Password)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(protected)
+}
+
+func bitwardenItem(item enpass.Item, paths map[string]string, includeSecrets bool) BitwardenItem {
+	m := mapItem(item, includeSecrets)
+	bwItem := BitwardenItem{
+		ID:              item.UUID,
+		Name:            item.Title,
+		Favorite:        item.Favorite,
+		RevisionDate:    isoTime(item.Updated),
+		CreationDate:    isoTime(item.Created),
+		PasswordHistory: []BitwardenPassword{},
+	}
+	if item.Note != "" {
+		bwItem.Notes = &item.Note
+	}
+	for _, folder := range item.Folders {
+		if _, ok := paths[folder]; ok {
+			folderID := folder
+			bwItem.FolderID = &folderID
+			break
+		}
+	}
+
+	switch m.kind {
+	case kindLogin:
+		bwItem.Type = bitwardenLogin
+		bwItem.Login = &BitwardenLogin{Username: m.username, Password: m.password, TOTP: m.totp}
+		for _, uri := range m.urls {
+			bwItem.Login.URIs = append(bwItem.Login.URIs, BitwardenURI{URI: uri})
+		}
+	case kindCard:
+		bwItem.Type = bitwardenCard
+		bwItem.Card = &BitwardenCard{
+			CardholderName: m.card["cardholder"],
+			Brand:          m.card["brand"],
+			Number:         m.card["number"],
+			Code:           m.card["code"],
+		}
+		if month, year, ok := parseExpiry(m.card["expiry"]); ok {
+			bwItem.Card.ExpMonth, bwItem.Card.ExpYear = strconv.Itoa(month), strconv.Itoa(year)
+		} else if m.card["expiry"] != "" {
+			bwItem.Fields = append(bwItem.Fields, BitwardenField{Name: "Expiry", Value: m.card["expiry"], Type: bitwardenFieldText})
+		}
+	case kindIdentity:
+		bwItem.Type = bitwardenIdentity
+		id := m.identity
+		bwItem.Identity = &BitwardenIdentity{
+			Title:          id["title"],
+			FirstName:      id["firstName"],
+			MiddleName:     id["middleName"],
+			LastName:       id["lastName"],
+			Address1:       id["address1"],
+			Address2:       id["address2"],
+			City:           id["city"],
+			State:          id["state"],
+			PostalCode:     id["postalCode"],
+			Country:        id["country"],
+			Company:        id["company"],
+			Email:          id["email"],
+			Phone:          id["phone"],
+			SSN:            id["ssn"],
+			Username:       id["username"],
+			PassportNumber: id["passportNumber"],
+			LicenseNumber:  id["licenseNumber"],
+		}
+	default:
+		bwItem.Type = bitwardenSecureNote
+		bwItem.SecureNote = &BitwardenSecureNote{}
+	}
+
+	// Websites and one-time codes only have a slot on logins, keep them as custom fields elsewhere
+	if m.kind != kindLogin {
+		for _, uri := range m.urls {
+			bwItem.Fields = append(bwItem.Fields, BitwardenField{Name: "URL", Value: uri, Type: bitwardenFieldText})
+		}
+		if m.totp != "" {
+			bwItem.Fields = append(bwItem.Fields, BitwardenField{Name: "TOTP", Value: m.totp, Type: bitwardenFieldHidden})
+		}
+	}
+	for _, field := range m.custom {
+		name := field.Label
+		if name == "" {
+			name = field.Type
+		}
+		fieldType := bitwardenFieldText
+		if field.IsSecret() {
+			fieldType = bitwardenFieldHidden
+		}
+		bwItem.Fields = append(bwItem.Fields, BitwardenField{Name: name, Value: field.Value, Type: fieldType})
+	}
+
+	return bwItem
+}
+
+// bitwardenProtect : encrypt the export the way Bitwarden does for password-protected exports. The key is derived
+// from the password with PBKDF2-SHA256 and stretched with HKDF into an AES-256-CBC key and an HMAC-SHA256 key.
+func bitwardenProtect(data []byte, password string) (*BitwardenProtectedJSON, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	// Bitwarden uses the base64 text of the salt, not the raw bytes
+	encodedSalt := base64.StdEncoding.EncodeToString(salt)
+	key := pbkdf2.Key([]byte(password), []byte(encodedSalt), bitwardenIterations, 32, sha256.New)
+
+	encKey, macKey := make([]byte, 32), make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("enc")), encKey); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("mac")), macKey); err != nil {
+		return nil, err
+	}
+
+	validation, err := bitwardenEncString([]byte(newGUID()), encKey, macKey)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := bitwardenEncString(data, encKey, macKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitwardenProtectedJSON{
+		Encrypted:         true,
+		PasswordProtected: true,
+		Salt:              encodedSalt,
+		KdfType:           0,
+		KdfIterations:     bitwardenIterations,
+		EncKeyValidation:  validation,
+		Data:              encrypted,
+	}, nil
+}
+
+// bitwardenEncString : an EncString of type 2, AES-256-CBC with an HMAC-SHA256 over the IV and the ciphertext
+func bitwardenEncString(plaintext, encKey, macKey []byte) (string, error) {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+
+	return fmt.Sprintf("2.%s|%s|%s",
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(ciphertext),
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+	), nil
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// bitwardenKeys : the keys Bitwarden derives from the password and the salt of a protected export
+func bitwardenKeys(t *testing.T, protected BitwardenProtectedJSON, password string) ([]byte, []byte) {
+	t.Helper()
+	key := pbkdf2.Key([]byte(password), []byte(protected.Salt), protected.KdfIterations, 32, sha256.New)
+	encKey, macKey := make([]byte, 32), make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("enc")), encKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("mac")), macKey); err != nil {
+		t.Fatal(err)
+	}
+	return encKey, macKey
+}
+
+// bitwardenDecString : check the HMAC of an EncString of type 2 and decrypt it
+func bitwardenDecString(encString string, encKey, macKey []byte) ([]byte, error) {
+	if !strings.HasPrefix(encString, "2.") {
+		return nil, fmt.Errorf("not an EncString of type 2: %q", encString)
+	}
+	parts := strings.Split(strings.TrimPrefix(encString, "2."), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("the EncString has %d parts", len(parts))
+	}
+	decoded := make([][]byte, 3)
+	for i, part := range parts {
+		var err error
+		if decoded[i], err = base64.StdEncoding.DecodeString(part); err != nil {
+			return nil, err
+		}
+	}
+	iv, ciphertext, sum := decoded[0], decoded[1], decoded[2]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, fmt.Errorf("the HMAC does not match")
+	}
+	if len(iv) != aes.BlockSize || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("bad IV or ciphertext length")
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	padding := int(plaintext[len(plaintext)-1])
+	if padding < 1 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("bad padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func TestWriteBitwardenJSONProtected(t *testing.T) {
+	items := []enpass.Item{{
+		UUID: "i1", Title: "GitHub", Category: "login", Folders: []string{"f1"},
+		Fields: []enpass.ItemField{
+			{Label: "Username", Type: "username", Value: "octocat"},
+			{Label: "Password", Type: "password", Value: "s3cret"},
+		},
+	}}
+	opts := Options{IncludeSecrets: true, Folders: []enpass.Folder{{UUID: "f1", Title: "Work"}}}
+
+	var plain bytes.Buffer
+	if err := WriteBitwardenJSON(&plain, items, opts); err != nil {
+		t.Fatal(err)
+	}
+	opts.Password = "correct horse"
+	var out bytes.Buffer
+	if err := WriteBitwardenJSON(&out, items, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var protected BitwardenProtectedJSON
+	if err := json.Unmarshal(out.Bytes(), &protected); err != nil {
+		t.Fatal(err)
+	}
+	if !protected.Encrypted || !protected.PasswordProtected || protected.KdfType != 0 || protected.KdfIterations != bitwardenIterations {
+		t.Fatalf("unexpected header %+v", protected)
+	}
+
+	encKey, macKey := bitwardenKeys(t, protected, "correct horse")
+	validation, err := bitwardenDecString(protected.EncKeyValidation, encKey, macKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).Match(validation) {
+		t.Errorf("the key validation is not a uuid: %q", validation)
+	}
+	data, err := bitwardenDecString(protected.Data, encKey, macKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want BitwardenJSON
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(plain.Bytes(), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decrypted\n got %+v\nwant %+v", got, want)
+	}
+	if got.Items[0].Login.Password != "s3cret" {
+		t.Errorf("the password was not exported: %+v", got.Items[0].Login)
+	}
+
+	wrongEnc, wrongMac := bitwardenKeys(t, protected, "wrong")
+	if _, err := bitwardenDecString(protected.Data, wrongEnc, wrongMac); err == nil {
+		t.Error("the export decrypted with the wrong password")
+	}
+}
+
+func TestBitwardenEncString(t *testing.T) {
+	encKey, macKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	for _, plaintext := range []string{"", "a", "exactly 16 bytes", strings.Repeat("x", 100)} {
+		encString, err := bitwardenEncString([]byte(plaintext), encKey, macKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := bitwardenDecString(encString, encKey, macKey)
+		if err != nil {
+			t.Fatalf("%q: %s", plaintext, err)
+		}
+		if string(got) != plaintext {
+			t.Errorf("got %q, want %q", got, plaintext)
+		}
+	}
+
+	first, _ := bitwardenEncString([]byte("same"), encKey, macKey)
+	second, _ := bitwardenEncString([]byte("same"), encKey, macKey)
+	if first == second {
+		t.Error("the IV is not random")
+	}
+	tampered := []byte(first)
+	tampered[len("2.")+2] ^= 1
+	if _, err := bitwardenDecString(string(tampered), encKey, macKey); err == nil {
+		t.Error("a tampered EncString was accepted")
+	}
+}
+
+func TestWriteBitwardenJSONKeyFile(t *testing.T) {
+	if err := WriteBitwardenJSON(io.Discard, nil, Options{KeyFile: []byte("key")}); err == nil {
+		t.Error("a key file was accepted")
+	}
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// browserColumns : the header of the password export of a browser, for each column of a login
+type browserColumns struct {
+	title    string
+	url      string
+	username string
+	password string
+	note     string
+	totp     string
+	created  string
+	// createdScale divides the creation time to get seconds
+	createdScale int64
+}
+
+var browserFormats = map[string]browserColumns{
+	"chrome-csv":  {title: "name", url: "url", username: "username", password: "password", note: "note"},
+	"firefox-csv": {url: "url", username: "username", password: "password", created: "timecreated", createdScale: 1000},
+	"safari-csv":  {title: "title", url: "url", username: "username", password: "password", note: "notes", totp: "otpauth"},
+}
+
+// ReadBrowserCSV : read the password export of a browser, format is chrome-csv, firefox-csv or safari-csv. Every
+// row becomes a login item with title, username, password and website fields, and the note or one-time code the
+// browser keeps. Empty rows are skipped.
+func ReadBrowserCSV(r io.Reader, format string) ([]enpass.Item, error) {
+	columns, ok := browserFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown browser format %q", format)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []enpass.Item{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse the %s export: %s", format, err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{columns.url, columns.username, columns.password} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("the %s export has no %q column, found: %s", format, required, strings.Join(header, ", "))
+		}
+	}
+
+	items := []enpass.Item{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse the %s export: %s", format, err)
+		}
+		value := func(column string) string {
+			if i, ok := index[column]; ok && column != "" && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		website, username, password := value(columns.url), value(columns.username), value(columns.password)
+		if website == "" && username == "" && password == "" {
+			continue
+		}
+		title := value(columns.title)
+		if title == "" {
+			title = urlHost(website)
+		}
+
+		item := enpass.Item{
+			Title:    title,
+			Subtitle: username,
+			Note:     value(columns.note),
+			Category: "login",
+			Template: "login.default",
+			// Enpass submits the logins it saves from the browser
+			AutoSubmit: true,
+			Fields: []enpass.ItemField{
+				{Label: "Username", Type: "username", Value: username},
+				{Label: "Password", Type: "password", Value: password, Sensitive: true},
+				{Label: "Website", Type: "url", Value: website},
+			},
+		}
+		if totp := value(columns.totp); totp != "" {
+			item.Fields = append(item.Fields, enpass.ItemField{Label: "One-time code", Type: "totp", Value: totp, Sensitive: true})
+		}
+		if created, err := strconv.ParseInt(value(columns.created), 10, 64); err == nil && created > 0 {
+			item.Created = created / columns.createdScale
+		}
+		for i := range item.Fields {
+			item.Fields[i].UID, item.Fields[i].Order = i+1, i+1
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// LoginKey : the key under which two logins are the same account, the host and path of the first website without
+// the scheme and a leading www, and the login, both case-insensitive. It is empty for items without a website.
+func LoginKey(item enpass.Item) string {
+	website, ok := item.Field("url")
+	if !ok {
+		return ""
+	}
+	login := item.Subtitle
+	for _, fieldType := range []string{"username", "email"} {
+		if field, ok := item.Field(fieldType); ok {
+			login = field.Value
+			break
+		}
+	}
+
+	target := strings.ToLower(strings.TrimSpace(website.Value))
+	if parsed, err := url.Parse(target); err == nil && parsed.Host != "" {
+		target = parsed.Host + parsed.Path
+	}
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "www."), "/")
+
+	return target + "\x00" + strings.ToLower(strings.TrimSpace(login))
+}
+
+// urlHost : the host of a website, the website itself when it does not parse
+func urlHost(website string) string {
+	if parsed, err := url.Parse(website); err == nil && parsed.Host != "" {
+		return strings.TrimPrefix(parsed.Host, "www.")
+	}
+	return website
+}
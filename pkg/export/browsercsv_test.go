@@ -0,0 +1,109 @@
+package export
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func TestReadBrowserCSV(t *testing.T) {
+	login := func(title, username, password, website string) enpass.Item {
+		return enpass.Item{
+			Title: title, Subtitle: username, Category: "login", Template: "login.default", AutoSubmit: true,
+			Fields: []enpass.ItemField{
+				{UID: 1, Order: 1, Label: "Username", Type: "username", Value: username},
+				{UID: 2, Order: 2, Label: "Password", Type: "password", Value: password, Sensitive: true},
+				{UID: 3, Order: 3, Label: "Website", Type: "url", Value: website},
+			},
+		}
+	}
+
+	chrome := login("GitHub", "octocat", "s3cret", "https://github.com/login")
+	chrome.Note = "work"
+	firefox := login("example.com", "jane", "hunter2", "https://www.example.com/")
+	firefox.Created = 1700000000
+	safari := login("Bank", "jdoe", "pa$$", "https://bank.example")
+	safari.Fields = append(safari.Fields, enpass.ItemField{UID: 4, Order: 4, Label: "One-time code", Type: "totp", Value: "otpauth://totp/Bank?secret=JBSWY3DP", Sensitive: true})
+
+	tests := []struct {
+		format string
+		data   string
+		want   []enpass.Item
+	}{
+		{
+			format: "chrome-csv",
+			data:   "\ufeffname,url,username,password,note\nGitHub,https://github.com/login,octocat,s3cret,work\n,,,,\n",
+			want:   []enpass.Item{chrome},
+		},
+		{
+			format: "firefox-csv",
+			data:   "\"url\",\"username\",\"password\",\"httpRealm\",\"formActionOrigin\",\"guid\",\"timeCreated\"\n\"https://www.example.com/\",\"jane\",\"hunter2\",,\"\",\"{1}\",\"1700000000000\"\n",
+			want:   []enpass.Item{firefox},
+		},
+		{
+			format: "safari-csv",
+			data:   "Title,URL,Username,Password,Notes,OTPAuth\nBank,https://bank.example,jdoe,pa$$,,otpauth://totp/Bank?secret=JBSWY3DP\n",
+			want:   []enpass.Item{safari},
+		},
+		{
+			format: "chrome-csv",
+			data:   "",
+			want:   []enpass.Item{},
+		},
+	}
+	for _, test := range tests {
+		got, err := ReadBrowserCSV(strings.NewReader(test.data), test.format)
+		if err != nil {
+			t.Errorf("%s: %s", test.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s\n got %+v\nwant %+v", test.format, got, test.want)
+		}
+	}
+}
+
+func TestReadBrowserCSVErrors(t *testing.T) {
+	if _, err := ReadBrowserCSV(strings.NewReader("url,username,password\n"), "opera-csv"); err == nil {
+		t.Error("an unknown format was accepted")
+	}
+	if _, err := ReadBrowserCSV(strings.NewReader("name,url,username\n"), "chrome-csv"); err == nil || !strings.Contains(err.Error(), `"password"`) {
+		t.Errorf("a missing column was not reported: %v", err)
+	}
+	if _, err := ReadBrowserCSV(strings.NewReader("name,url,username,password\n\"broken,x,y,z\n"), "chrome-csv"); err == nil {
+		t.Error("a broken file was accepted")
+	}
+}
+
+func TestLoginKey(t *testing.T) {
+	item := func(subtitle string, fields ...enpass.ItemField) enpass.Item {
+		return enpass.Item{Subtitle: subtitle, Fields: fields}
+	}
+	url := func(value string) enpass.ItemField { return enpass.ItemField{Type: "url", Value: value} }
+
+	tests := []struct {
+		name string
+		item enpass.Item
+		want string
+	}{
+		{"no website", item("octocat", enpass.ItemField{Type: "username", Value: "octocat"}), ""},
+		{"scheme, www and case are ignored", item("", url("HTTPS://www.GitHub.com/login/"), enpass.ItemField{Type: "username", Value: " OctoCat "}), "github.com/login\x00octocat"},
+		{"the username before the e-mail", item("", url("github.com"), enpass.ItemField{Type: "email", Value: "o@example.com"}, enpass.ItemField{Type: "username", Value: "octocat"}), "github.com\x00octocat"},
+		{"the e-mail without a username", item("", url("https://github.com"), enpass.ItemField{Type: "email", Value: "o@example.com"}), "github.com\x00o@example.com"},
+		{"the subtitle without either", item("octocat", url("https://github.com")), "github.com\x00octocat"},
+		{"the query is ignored", item("octocat", url("https://github.com/?next=/x")), "github.com\x00octocat"},
+	}
+	for _, test := range tests {
+		if got := LoginKey(test.item); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+
+	first := item("octocat", url("https://github.com/"))
+	second := item("OCTOCAT", url("http://www.github.com"))
+	if LoginKey(first) != LoginKey(second) {
+		t.Errorf("%q and %q are not the same login", LoginKey(first), LoginKey(second))
+	}
+}
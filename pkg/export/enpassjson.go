@@ -0,0 +1,180 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// EnpassJSON : the JSON export of the Enpass desktop application
+type EnpassJSON struct {
+	Folders []EnpassJSONFolder `json:"folders"`
+	Items   []EnpassJSONItem   `json:"items"`
+}
+
+// EnpassJSONFolder : a folder of the Enpass JSON export
+type EnpassJSONFolder struct {
+	Icon       string `json:"icon"`
+	ParentUUID string `json:"parent_uuid"`
+	Title      string `json:"title"`
+	UpdatedAt  int64  `json:"updated_at"`
+	UUID       string `json:"uuid"`
+}
+
+// EnpassJSONItem : an item of the Enpass JSON export, the flags are 0 or 1
+type EnpassJSONItem struct {
+	Archived     int               `json:"archived"`
+	AutoSubmit   int               `json:"auto_submit"`
+	Category     string            `json:"category"`
+	CreatedAt    int64             `json:"createdAt"`
+	Favorite     int               `json:"favorite"`
+	Fields       []EnpassJSONField `json:"fields,omitempty"`
+	Folders      []string          `json:"folders,omitempty"`
+	Icon         json.RawMessage   `json:"icon,omitempty"`
+	LastUsed     int64             `json:"last_used,omitempty"`
+	Note         string            `json:"note"`
+	Subtitle     string            `json:"subtitle"`
+	TemplateType string            `json:"template_type"`
+	Title        string            `json:"title"`
+	Trashed      int               `json:"trashed"`
+	UpdatedAt    int64             `json:"updated_at"`
+	UUID         string            `json:"uuid"`
+}
+
+// EnpassJSONField : a field of an Enpass JSON item
+type EnpassJSONField struct {
+	Deleted   int    `json:"deleted"`
+	Label     string `json:"label"`
+	Order     int    `json:"order"`
+	Sensitive int    `json:"sensitive"`
+	Type      string `json:"type"`
+	UID       int    `json:"uid"`
+	Value     string `json:"value"`
+}
+
+// WriteEnpassJSON : write the items and folders in the Enpass JSON format. Without includeSecrets the values of
+// passwords, one-time codes and sensitive fields are emptied, the fields themselves are kept.
+func WriteEnpassJSON(w io.Writer, items []enpass.Item, folders []enpass.Folder, includeSecrets bool) error {
+	document := EnpassJSON{
+		Folders: []EnpassJSONFolder{},
+		Items:   []EnpassJSONItem{},
+	}
+	for _, folder := range folders {
+		document.Folders = append(document.Folders, EnpassJSONFolder{
+			Icon:       folder.Icon,
+			ParentUUID: folder.ParentUUID,
+			Title:      folder.Title,
+			UpdatedAt:  folder.Updated,
+			UUID:       folder.UUID,
+		})
+	}
+
+	for _, item := range items {
+		jsonItem := EnpassJSONItem{
+			Archived:     boolToInt(item.Archived),
+			AutoSubmit:   boolToInt(item.AutoSubmit),
+			Category:     item.Category,
+			CreatedAt:    item.Created,
+			Favorite:     boolToInt(item.Favorite),
+			Folders:      item.Folders,
+			LastUsed:     item.LastUsed,
+			Note:         item.Note,
+			Subtitle:     item.Subtitle,
+			TemplateType: item.Template,
+			Title:        item.Title,
+			Trashed:      boolToInt(item.Trashed),
+			UpdatedAt:    item.Updated,
+			UUID:         item.UUID,
+		}
+		// The icon is stored as JSON, anything else is dropped rather than producing an invalid document
+		if item.Icon != "" && json.Valid([]byte(item.Icon)) {
+			jsonItem.Icon = json.RawMessage(item.Icon)
+		}
+		for _, field := range item.Fields {
+			value := field.Value
+			if field.IsSecret() && !includeSecrets {
+				value = ""
+			}
+			jsonItem.Fields = append(jsonItem.Fields, EnpassJSONField{
+				Label:     field.Label,
+				Order:     field.Order,
+				Sensitive: boolToInt(field.Sensitive),
+				Type:      field.Type,
+				UID:       field.UID,
+				Value:     value,
+			})
+		}
+		document.Items = append(document.Items, jsonItem)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(document)
+}
+
+// ReadEnpassJSON : read an Enpass JSON export. Deleted fields are skipped.
+func ReadEnpassJSON(r io.Reader) ([]enpass.Item, []enpass.Folder, error) {
+	var document EnpassJSON
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the Enpass JSON export: %s", err)
+	}
+
+	folders := []enpass.Folder{}
+	for _, folder := range document.Folders {
+		folders = append(folders, enpass.Folder{
+			UUID:       folder.UUID,
+			Title:      folder.Title,
+			ParentUUID: folder.ParentUUID,
+			Icon:       folder.Icon,
+			Updated:    folder.UpdatedAt,
+		})
+	}
+
+	items := []enpass.Item{}
+	for _, jsonItem := range document.Items {
+		item := enpass.Item{
+			UUID:       jsonItem.UUID,
+			Title:      jsonItem.Title,
+			Subtitle:   jsonItem.Subtitle,
+			Note:       jsonItem.Note,
+			Category:   jsonItem.Category,
+			Template:   jsonItem.TemplateType,
+			Favorite:   jsonItem.Favorite != 0,
+			Archived:   jsonItem.Archived != 0,
+			Trashed:    jsonItem.Trashed != 0,
+			AutoSubmit: jsonItem.AutoSubmit != 0,
+			Created:    jsonItem.CreatedAt,
+			Updated:    jsonItem.UpdatedAt,
+			LastUsed:   jsonItem.LastUsed,
+			Folders:    jsonItem.Folders,
+		}
+		if len(jsonItem.Icon) > 0 && string(jsonItem.Icon) != "null" {
+			item.Icon = string(jsonItem.Icon)
+		}
+		for _, field := range jsonItem.Fields {
+			if field.Deleted != 0 {
+				continue
+			}
+			item.Fields = append(item.Fields, enpass.ItemField{
+				UID:       field.UID,
+				Label:     field.Label,
+				Type:      field.Type,
+				Value:     field.Value,
+				Sensitive: field.Sensitive != 0,
+				Order:     field.Order,
+			})
+		}
+		items = append(items, item)
+	}
+
+	return items, folders, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
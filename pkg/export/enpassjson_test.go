@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func TestEnpassJSONRoundTrip(t *testing.T) {
+	folders := []enpass.Folder{{UUID: "f1", Title: "Work", Updated: 10}, {UUID: "f2", Title: "Git", ParentUUID: "f1", Updated: 11}}
+	items := []enpass.Item{
+		{
+			UUID: "i1", Title: "GitHub", Subtitle: "octocat", Note: "n", Category: "login", Template: "login.default",
+			Favorite: true, AutoSubmit: true, Created: 1, Updated: 2, LastUsed: 3, Folders: []string{"f2"},
+			Fields: []enpass.ItemField{
+				{UID: 1, Order: 1, Label: "Username", Type: "username", Value: "octocat"},
+				{UID: 2, Order: 2, Label: "Password", Type: "password", Value: "s3cret", Sensitive: true},
+			},
+		},
+		{UUID: "i2", Title: "Old", Category: "note", Template: "note.default", Archived: true, Trashed: true, Created: 4, Updated: 5},
+	}
+
+	var out bytes.Buffer
+	if err := WriteEnpassJSON(&out, items, folders, true); err != nil {
+		t.Fatal(err)
+	}
+	gotItems, gotFolders, err := ReadEnpassJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotItems, items) {
+		t.Errorf("items\n got %+v\nwant %+v", gotItems, items)
+	}
+	if !reflect.DeepEqual(gotFolders, folders) {
+		t.Errorf("folders\n got %+v\nwant %+v", gotFolders, folders)
+	}
+}
+
+func TestEnpassJSONWithoutSecrets(t *testing.T) {
+	items := []enpass.Item{{
+		Title: "GitHub",
+		Fields: []enpass.ItemField{
+			{Label: "Password", Type: "password", Value: "s3cret"},
+			{Label: "Code", Type: "totp", Value: "JBSWY3DP"},
+			{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			{Label: "Website", Type: "url", Value: "https://github.com"},
+		},
+	}}
+
+	var out bytes.Buffer
+	if err := WriteEnpassJSON(&out, items, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	var document EnpassJSON
+	if err := json.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	values := []string{}
+	for _, field := range document.Items[0].Fields {
+		values = append(values, field.Value)
+	}
+	if want := []string{"", "", "", "https://github.com"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("got values %q, want %q", values, want)
+	}
+	if document.Items[0].AutoSubmit != 0 {
+		t.Errorf("auto_submit is %d for an item without it", document.Items[0].AutoSubmit)
+	}
+}
@@ -0,0 +1,175 @@
+// Package export : write vault items to other file formats and read them back
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/output"
+)
+
+// Options : the settings shared by every exporter
+type Options struct {
+	// Folders are the folders of the vault, for the formats that keep them
+	Folders []enpass.Folder
+	// IncludeSecrets keeps passwords, one-time codes and sensitive fields
+	IncludeSecrets bool
+	// KeyFile is the content of the key file protecting the encrypted formats
+	KeyFile []byte
+	// Password protects the encrypted formats
+	Password string
+}
+
+// Exporter : writes whole items in a file format
+type Exporter interface {
+	Export(w io.Writer, items []enpass.Item, opts Options) error
+}
+
+// ExporterFunc : adapts a function to the Exporter interface
+type ExporterFunc func(w io.Writer, items []enpass.Item, opts Options) error
+
+func (f ExporterFunc) Export(w io.Writer, items []enpass.Item, opts Options) error {
+	return f(w, items, opts)
+}
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]Exporter{
+		"1pux":           ExporterFunc(WriteOnePUX),
+		"bitwarden-json": ExporterFunc(WriteBitwardenJSON),
+		"csv": ExporterFunc(func(w io.Writer, items []enpass.Item, opts Options) error {
+			return output.WriteCSV(w, items, ',', opts.IncludeSecrets)
+		}),
+		"enpass-json": ExporterFunc(func(w io.Writer, items []enpass.Item, opts Options) error {
+			return WriteEnpassJSON(w, items, opts.Folders, opts.IncludeSecrets)
+		}),
+		"kdbx": ExporterFunc(WriteKDBX),
+		"tsv": ExporterFunc(func(w io.Writer, items []enpass.Item, opts Options) error {
+			return output.WriteCSV(w, items, '\t', opts.IncludeSecrets)
+		}),
+	}
+)
+
+// Register : make an exporter available under name, replacing any exporter of the same name
+func Register(name string, exporter Exporter) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[name] = exporter
+}
+
+// Lookup : return the exporter registered under name
+func Lookup(name string) (Exporter, error) {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	exporter, ok := exporters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q, valid: %s", name, strings.Join(namesLocked(), ", "))
+	}
+	return exporter, nil
+}
+
+// Names : the names of the registered exporters, sorted
+func Names() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Importer : reads whole items from a file format
+type Importer interface {
+	Import(r io.Reader) ([]enpass.Item, error)
+}
+
+// ImporterFunc : adapts a function to the Importer interface
+type ImporterFunc func(r io.Reader) ([]enpass.Item, error)
+
+func (f ImporterFunc) Import(r io.Reader) ([]enpass.Item, error) {
+	return f(r)
+}
+
+var (
+	importersMu sync.RWMutex
+	importers   = map[string]Importer{
+		"chrome-csv": browserImporter("chrome-csv"),
+		"enpass-json": ImporterFunc(func(r io.Reader) ([]enpass.Item, error) {
+			items, _, err := ReadEnpassJSON(r)
+			return items, err
+		}),
+		"firefox-csv": browserImporter("firefox-csv"),
+		"safari-csv":  browserImporter("safari-csv"),
+	}
+)
+
+func browserImporter(format string) Importer {
+	return ImporterFunc(func(r io.Reader) ([]enpass.Item, error) {
+		return ReadBrowserCSV(r, format)
+	})
+}
+
+// RegisterImporter : make an importer available under name, replacing any importer of the same name
+func RegisterImporter(name string, importer Importer) {
+	importersMu.Lock()
+	defer importersMu.Unlock()
+	importers[name] = importer
+}
+
+// LookupImporter : return the importer registered under name
+func LookupImporter(name string) (Importer, error) {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	importer, ok := importers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown import format %q, valid: %s", name, strings.Join(importerNamesLocked(), ", "))
+	}
+	return importer, nil
+}
+
+// ImporterNames : the names of the registered importers, sorted
+func ImporterNames() []string {
+	importersMu.RLock()
+	defer importersMu.RUnlock()
+	return importerNamesLocked()
+}
+
+func importerNamesLocked() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBinary : report whether the format is binary and does not belong on a terminal
+func IsBinary(name string) bool {
+	return name == "1pux" || name == "kdbx"
+}
+
+// IsEncrypted : report whether the format is always protected by Options.Password and Options.KeyFile
+func IsEncrypted(name string) bool {
+	return name == "kdbx"
+}
+
+// CanEncrypt : report whether the format is protected by Options.Password when it is set
+func CanEncrypt(name string) bool {
+	return IsEncrypted(name) || name == "bitwarden-json"
+}
+
+// IsLoginSource : report whether the import format holds logins to add to a vault, rather than a vault export
+func IsLoginSource(name string) bool {
+	_, ok := browserFormats[name]
+	return ok
+}
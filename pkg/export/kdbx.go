@@ -0,0 +1,199 @@
+package export
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/kdbx"
+)
+
+// kdbxStandardStrings : the strings KeePass shows in dedicated columns, custom strings must not reuse them
+var kdbxStandardStrings = []string{"Title", "UserName", "Password", "URL", "Notes", "otp"}
+
+// WriteKDBX : write the items as a KeePass KDBX 4 database protected by opts.Password and/or opts.KeyFile. Each
+// category becomes a group. The first username, password and URL fields and the note map to the standard
+// strings, the first one-time code to the KeePassXC otp string and every other field to a custom string.
+// Attachments become binaries, except the external ones that were not read.
+func WriteKDBX(w io.Writer, items []enpass.Item, opts Options) error {
+	var keyFileKey []byte
+	if len(opts.KeyFile) > 0 {
+		var err error
+		if keyFileKey, err = kdbx.KeyFileKey(opts.KeyFile); err != nil {
+			return err
+		}
+	}
+	compositeKey, err := kdbx.CompositeKey(opts.Password, keyFileKey)
+	if err != nil {
+		return errors.New("the kdbx format requires a password or a key file")
+	}
+
+	groups := map[string]*kdbx.Group{}
+	for _, item := range items {
+		category := item.Category
+		if category == "" {
+			category = "uncategorized"
+		}
+		group, ok := groups[category]
+		if !ok {
+			group = &kdbx.Group{UUID: kdbx.NewUUID(), Name: strings.ToUpper(category[:1]) + category[1:]}
+			groups[category] = group
+		}
+		group.Entries = append(group.Entries, kdbxEntry(item, opts.IncludeSecrets))
+	}
+
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	db := &kdbx.Database{
+		Name: "Enpass",
+		Root: kdbx.Group{UUID: kdbx.NewUUID(), Name: "Enpass"},
+	}
+	for _, category := range categories {
+		db.Root.Groups = append(db.Root.Groups, *groups[category])
+	}
+
+	return kdbx.Write(w, db, compositeKey, kdbx.DefaultKDF)
+}
+
+func kdbxEntry(item enpass.Item, includeSecrets bool) kdbx.Entry {
+	entry := kdbx.Entry{
+		UUID:     kdbxUUID(item.UUID),
+		Created:  unixTime(item.Created),
+		Modified: unixTime(item.Updated),
+		Accessed: unixTime(item.LastUsed),
+	}
+
+	tags := []string{}
+	for tag, set := range map[string]bool{"archived": item.Archived, "favorite": item.Favorite, "trashed": item.Trashed} {
+		if set {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	entry.Tags = strings.Join(tags, ",")
+
+	secret := func(field enpass.ItemField) string {
+		if field.IsSecret() && !includeSecrets {
+			return ""
+		}
+		return field.Value
+	}
+
+	standard := map[string]string{"Title": item.Title, "Notes": item.Note}
+	used := map[int]bool{}
+	for _, column := range []struct {
+		key   string
+		types []string
+	}{
+		{"UserName", []string{"username", "email"}},
+		{"Password", []string{"password"}},
+		{"URL", []string{"url"}},
+		{"otp", []string{"totp"}},
+	} {
+		for _, fieldType := range column.types {
+			if index := item.FieldIndex(fieldType, used); index >= 0 {
+				used[index] = true
+				standard[column.key] = secret(item.Fields[index])
+				break
+			}
+		}
+	}
+	if standard["UserName"] == "" {
+		standard["UserName"] = item.Subtitle
+	}
+	if standard["otp"] != "" {
+		standard["otp"] = otpURI(standard["otp"], item.Title, standard["UserName"])
+	}
+
+	for _, key := range kdbxStandardStrings {
+		if key == "otp" && standard[key] == "" {
+			continue
+		}
+		entry.Strings = append(entry.Strings, kdbx.String{Key: key, Value: standard[key], Protected: key == "Password" || key == "otp"})
+	}
+
+	taken := map[string]bool{}
+	for _, key := range kdbxStandardStrings {
+		taken[key] = true
+	}
+	for index, field := range item.Fields {
+		if used[index] || field.Type == "section" || field.Value == "" {
+			continue
+		}
+		key := field.Label
+		if key == "" {
+			key = field.Type
+		}
+		candidate := key
+		for n := 2; taken[candidate]; n++ {
+			candidate = fmt.Sprintf("%s (%d)", key, n)
+		}
+		taken[candidate] = true
+		entry.Strings = append(entry.Strings, kdbx.String{Key: candidate, Value: secret(field), Protected: field.IsSecret()})
+	}
+
+	// Binaries are keyed by name, a name used twice gets a number
+	names := map[string]bool{}
+	for _, attachment := range item.Attachments {
+		if attachment.External {
+			continue
+		}
+		name := attachment.Name
+		if name == "" {
+			name = "attachment"
+		}
+		candidate := name
+		for n := 2; names[candidate]; n++ {
+			candidate = fmt.Sprintf("%s (%d)", name, n)
+		}
+		names[candidate] = true
+		entry.Attachments = append(entry.Attachments, kdbx.Attachment{Name: candidate, Data: attachment.Data})
+	}
+
+	return entry
+}
+
+// kdbxUUID : keep the Enpass uuid so entries can be matched across exports
+func kdbxUUID(uuid string) [16]byte {
+	var out [16]byte
+	decoded, err := hex.DecodeString(strings.ReplaceAll(uuid, "-", ""))
+	if err != nil || len(decoded) != 16 {
+		return kdbx.NewUUID()
+	}
+	copy(out[:], decoded)
+	return out
+}
+
+func unixTime(timestamp int64) time.Time {
+	if timestamp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0).UTC()
+}
+
+// otpURI : KeePassXC expects an otpauth:// URI, Enpass stores either the URI or the bare secret
+func otpURI(value, title, username string) string {
+	if strings.HasPrefix(value, "otpauth://") {
+		return value
+	}
+	label := title
+	if username != "" {
+		label = title + ":" + username
+	}
+	query := url.Values{}
+	query.Set("secret", strings.ToUpper(strings.ReplaceAll(value, " ", "")))
+	query.Set("issuer", title)
+	query.Set("period", "30")
+	query.Set("digits", "6")
+	return (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}).String()
+}
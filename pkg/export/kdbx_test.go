@@ -0,0 +1,62 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/kdbx"
+)
+
+func TestKDBXEntry(t *testing.T) {
+	item := enpass.Item{
+		UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e10", Title: "GitHub", Subtitle: "octocat", Note: "work",
+		Favorite: true, Trashed: true,
+		Fields: []enpass.ItemField{
+			{Label: "E-mail", Type: "email", Value: "octocat@example.com"},
+			{Label: "Password", Type: "password", Value: "s3cret"},
+			{Label: "Website", Type: "url", Value: "https://github.com"},
+			{Label: "Code", Type: "totp", Value: "jbsw y3dp"},
+			{Label: "Section", Type: "section"},
+			{Label: "Password", Type: "password", Value: "old"},
+			{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+		},
+		Attachments: []enpass.Attachment{
+			{Name: "codes.txt", Data: []byte("1111")},
+			{Name: "scan.pdf", External: true},
+			{Name: "codes.txt", Data: []byte("2222")},
+		},
+	}
+
+	entry := kdbxEntry(item, true)
+	want := []kdbx.String{
+		{Key: "Title", Value: "GitHub"},
+		{Key: "UserName", Value: "octocat@example.com"},
+		{Key: "Password", Value: "s3cret", Protected: true},
+		{Key: "URL", Value: "https://github.com"},
+		{Key: "Notes", Value: "work"},
+		{Key: "otp", Value: "otpauth://totp/GitHub:octocat@example.com?digits=6&issuer=GitHub&period=30&secret=JBSWY3DP", Protected: true},
+		{Key: "Password (2)", Value: "old", Protected: true},
+		{Key: "Recovery", Value: "r3c0very", Protected: true},
+	}
+	if !reflect.DeepEqual(entry.Strings, want) {
+		t.Errorf("strings\n got %+v\nwant %+v", entry.Strings, want)
+	}
+	attachments := []kdbx.Attachment{{Name: "codes.txt", Data: []byte("1111")}, {Name: "codes.txt (2)", Data: []byte("2222")}}
+	if !reflect.DeepEqual(entry.Attachments, attachments) {
+		t.Errorf("attachments\n got %+v\nwant %+v", entry.Attachments, attachments)
+	}
+	if entry.Tags != "favorite,trashed" {
+		t.Errorf("tags: got %q", entry.Tags)
+	}
+	if got := kdbxUUID(item.UUID); got[0] != 0x6f || got[15] != 0x10 {
+		t.Errorf("the uuid was not kept: %x", got)
+	}
+
+	entry = kdbxEntry(item, false)
+	for _, s := range entry.Strings {
+		if s.Protected && s.Value != "" {
+			t.Errorf("the secret %s was exported without includeSecrets", s.Key)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package export
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+const (
+	kindCard     = "card"
+	kindIdentity = "identity"
+	kindLogin    = "login"
+	kindNote     = "note"
+)
+
+var (
+	// cardFieldTypes : the Enpass credit card field types and their slot
+	cardFieldTypes = map[string]string{
+		"ccName":   "cardholder",
+		"ccType":   "brand",
+		"ccNumber": "number",
+		"ccCvc":    "code",
+		"ccExpiry": "expiry",
+	}
+
+	// cardFieldLabels : the labels of credit card fields stored as plain text
+	cardFieldLabels = map[string]string{
+		"cardholder":       "cardholder",
+		"cardholder name":  "cardholder",
+		"name on card":     "cardholder",
+		"type":             "brand",
+		"card type":        "brand",
+		"number":           "number",
+		"card number":      "number",
+		"cvc":              "code",
+		"cvv":              "code",
+		"security code":    "code",
+		"expiry":           "expiry",
+		"expiry date":      "expiry",
+		"expiration":       "expiry",
+		"expiration date":  "expiry",
+		"valid thru":       "expiry",
+		"valid until":      "expiry",
+		"expiration month": "expiry",
+	}
+
+	// identityFieldLabels : the labels of identity fields and their slot
+	identityFieldLabels = map[string]string{
+		"title":                  "title",
+		"first name":             "firstName",
+		"middle name":            "middleName",
+		"initial":                "middleName",
+		"last name":              "lastName",
+		"surname":                "lastName",
+		"company":                "company",
+		"organization":           "company",
+		"email":                  "email",
+		"e-mail":                 "email",
+		"phone":                  "phone",
+		"mobile":                 "phone",
+		"telephone":              "phone",
+		"address":                "address1",
+		"street":                 "address1",
+		"address line 1":         "address1",
+		"address line 2":         "address2",
+		"city":                   "city",
+		"state":                  "state",
+		"province":               "state",
+		"zip":                    "postalCode",
+		"zip code":               "postalCode",
+		"postal code":            "postalCode",
+		"country":                "country",
+		"ssn":                    "ssn",
+		"social security number": "ssn",
+		"passport number":        "passportNumber",
+		"license number":         "licenseNumber",
+		"username":               "username",
+	}
+
+	expiryPattern = regexp.MustCompile(`^\s*(\d{1,2})\s*[/\-.]\s*(\d{2}|\d{4})\s*$`)
+	isoExpiry     = regexp.MustCompile(`^\s*(\d{4})\s*-\s*(\d{1,2})\s*$`)
+)
+
+// mappedItem : the fields of an item sorted into the slots shared by the password manager formats. Secrets are
+// already emptied when they are not exported.
+type mappedItem struct {
+	kind     string
+	username string
+	password string
+	totp     string
+	urls     []string
+	card     map[string]string
+	identity map[string]string
+	custom   []enpass.ItemField
+}
+
+// itemKind : the kind of an item, from its category or template, then from its fields
+func itemKind(item enpass.Item) string {
+	for _, name := range []string{item.Category, strings.SplitN(item.Template, ".", 2)[0]} {
+		switch strings.ToLower(name) {
+		case "login", "password":
+			return kindLogin
+		case "creditcard":
+			return kindCard
+		case "identity":
+			return kindIdentity
+		case "note":
+			return kindNote
+		}
+	}
+	for _, field := range item.Fields {
+		switch field.Type {
+		case "username", "email", "password", "url":
+			return kindLogin
+		}
+	}
+	return kindNote
+}
+
+func mapItem(item enpass.Item, includeSecrets bool) mappedItem {
+	m := mappedItem{
+		kind:     itemKind(item),
+		card:     map[string]string{},
+		identity: map[string]string{},
+	}
+	value := func(field enpass.ItemField) string {
+		if field.IsSecret() && !includeSecrets {
+			return ""
+		}
+		return field.Value
+	}
+
+	for _, field := range item.Fields {
+		if field.Type == "section" || field.Value == "" {
+			continue
+		}
+		label := strings.ToLower(strings.TrimSpace(field.Label))
+
+		switch {
+		case (field.Type == "username" || field.Type == "email") && m.username == "" && m.kind != kindIdentity:
+			m.username = value(field)
+		case field.Type == "password" && m.password == "" && m.kind != kindCard:
+			m.password = value(field)
+		case field.Type == "totp" && m.totp == "":
+			m.totp = value(field)
+		case field.Type == "url":
+			m.urls = append(m.urls, field.Value)
+		case m.kind == kindCard && cardFieldTypes[field.Type] != "" && m.card[cardFieldTypes[field.Type]] == "":
+			m.card[cardFieldTypes[field.Type]] = value(field)
+		case m.kind == kindCard && cardFieldLabels[label] != "" && m.card[cardFieldLabels[label]] == "":
+			m.card[cardFieldLabels[label]] = value(field)
+		case m.kind == kindIdentity && field.Type == "email" && m.identity["email"] == "":
+			m.identity["email"] = value(field)
+		case m.kind == kindIdentity && field.Type == "phone" && m.identity["phone"] == "":
+			m.identity["phone"] = value(field)
+		case m.kind == kindIdentity && identityFieldLabels[label] != "" && m.identity[identityFieldLabels[label]] == "":
+			m.identity[identityFieldLabels[label]] = value(field)
+		default:
+			field.Value = value(field)
+			m.custom = append(m.custom, field)
+		}
+	}
+	if m.username == "" && m.kind == kindLogin {
+		m.username = item.Subtitle
+	}
+
+	return m
+}
+
+// parseExpiry : split MM/YY, MM/YYYY or YYYY-MM into a month and a four digit year
+func parseExpiry(expiry string) (int, int, bool) {
+	var month, year string
+	if match := expiryPattern.FindStringSubmatch(expiry); match != nil {
+		month, year = match[1], match[2]
+	} else if match := isoExpiry.FindStringSubmatch(expiry); match != nil {
+		year, month = match[1], match[2]
+	} else {
+		return 0, 0, false
+	}
+
+	m, _ := strconv.Atoi(month)
+	y, _ := strconv.Atoi(year)
+	if len(year) == 2 {
+		y += 2000
+	}
+	if m < 1 || m > 12 {
+		return 0, 0, false
+	}
+	return m, y, true
+}
+
+// isoTime : a unix timestamp as the ISO 8601 time with milliseconds the password managers use, empty when unknown
+func isoTime(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// newGUID : a random version 4 uuid
+func newGUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
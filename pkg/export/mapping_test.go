@@ -0,0 +1,149 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func TestItemKind(t *testing.T) {
+	tests := []struct {
+		name string
+		item enpass.Item
+		want string
+	}{
+		{"login category", enpass.Item{Category: "login"}, kindLogin},
+		{"password category", enpass.Item{Category: "password"}, kindLogin},
+		{"card category", enpass.Item{Category: "creditcard"}, kindCard},
+		{"identity category", enpass.Item{Category: "Identity"}, kindIdentity},
+		{"note category", enpass.Item{Category: "note"}, kindNote},
+		{"template when the category is unknown", enpass.Item{Category: "misc", Template: "creditcard.visa"}, kindCard},
+		{"fields when nothing else matches", enpass.Item{Category: "computer", Fields: []enpass.ItemField{{Type: "text"}, {Type: "url"}}}, kindLogin},
+		{"note by default", enpass.Item{Category: "computer", Fields: []enpass.ItemField{{Type: "text"}}}, kindNote},
+	}
+	for _, test := range tests {
+		if got := itemKind(test.item); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMapItem(t *testing.T) {
+	tests := []struct {
+		name           string
+		item           enpass.Item
+		includeSecrets bool
+		want           mappedItem
+	}{
+		{
+			name: "login",
+			item: enpass.Item{Category: "login", Fields: []enpass.ItemField{
+				{Label: "Username", Type: "username", Value: "octocat"},
+				{Label: "E-mail", Type: "email", Value: "octocat@example.com"},
+				{Label: "Password", Type: "password", Value: "s3cret"},
+				{Label: "Website", Type: "url", Value: "https://github.com"},
+				{Label: "Website", Type: "url", Value: "https://gist.github.com"},
+				{Label: "Code", Type: "totp", Value: "JBSWY3DP"},
+				{Label: "Section", Type: "section", Value: "x"},
+				{Label: "Empty", Type: "text"},
+				{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			}},
+			includeSecrets: true,
+			want: mappedItem{
+				kind: kindLogin, username: "octocat", password: "s3cret", totp: "JBSWY3DP",
+				urls:     []string{"https://github.com", "https://gist.github.com"},
+				card:     map[string]string{},
+				identity: map[string]string{},
+				custom: []enpass.ItemField{
+					{Label: "E-mail", Type: "email", Value: "octocat@example.com"},
+					{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+				},
+			},
+		},
+		{
+			name: "login without secrets, the subtitle is the username",
+			item: enpass.Item{Category: "login", Subtitle: "octocat", Fields: []enpass.ItemField{
+				{Label: "Password", Type: "password", Value: "s3cret"},
+				{Label: "Code", Type: "totp", Value: "JBSWY3DP"},
+				{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			}},
+			want: mappedItem{
+				kind: kindLogin, username: "octocat",
+				card:     map[string]string{},
+				identity: map[string]string{},
+				custom:   []enpass.ItemField{{Label: "Recovery", Type: "text", Sensitive: true}},
+			},
+		},
+		{
+			name: "card by field type and by label",
+			item: enpass.Item{Category: "creditcard", Fields: []enpass.ItemField{
+				{Label: "Cardholder", Type: "ccName", Value: "Jane Doe"},
+				{Label: "Number", Type: "ccNumber", Value: "4111111111111111"},
+				{Label: "CVV", Type: "text", Value: "123", Sensitive: true},
+				{Label: "Valid thru", Type: "text", Value: "04/29"},
+				{Label: "PIN", Type: "password", Value: "0000"},
+			}},
+			includeSecrets: true,
+			want: mappedItem{
+				kind: kindCard,
+				card: map[string]string{
+					"cardholder": "Jane Doe", "number": "4111111111111111", "code": "123", "expiry": "04/29",
+				},
+				identity: map[string]string{},
+				custom:   []enpass.ItemField{{Label: "PIN", Type: "password", Value: "0000"}},
+			},
+		},
+		{
+			name: "identity",
+			item: enpass.Item{Category: "identity", Fields: []enpass.ItemField{
+				{Label: "First name", Type: "text", Value: "Jane"},
+				{Label: "Surname", Type: "text", Value: "Doe"},
+				{Label: "Mail", Type: "email", Value: "jane@example.com"},
+				{Label: "Mobile", Type: "phone", Value: "555-0100"},
+				{Label: "Zip code", Type: "text", Value: "94105"},
+				{Label: "Hobby", Type: "text", Value: "chess"},
+			}},
+			want: mappedItem{
+				kind: kindIdentity,
+				card: map[string]string{},
+				identity: map[string]string{
+					"firstName": "Jane", "lastName": "Doe", "email": "jane@example.com", "phone": "555-0100", "postalCode": "94105",
+				},
+				custom: []enpass.ItemField{{Label: "Hobby", Type: "text", Value: "chess"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := mapItem(test.item, test.includeSecrets); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s\n got %+v\nwant %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		expiry string
+		month  int
+		year   int
+		ok     bool
+	}{
+		{"04/29", 4, 2029, true},
+		{"4/2029", 4, 2029, true},
+		{" 12 - 30 ", 12, 2030, true},
+		{"01.31", 1, 2031, true},
+		{"2029-04", 4, 2029, true},
+		{"13/29", 0, 0, false},
+		{"00/29", 0, 0, false},
+		{"2029-13", 0, 0, false},
+		{"April 2029", 0, 0, false},
+		{"04/029", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, test := range tests {
+		month, year, ok := parseExpiry(test.expiry)
+		if month != test.month || year != test.year || ok != test.ok {
+			t.Errorf("%q: got %d, %d, %t, want %d, %d, %t", test.expiry, month, year, ok, test.month, test.year, test.ok)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// 1PUX category codes
+const (
+	onePUXLogin      = "001"
+	onePUXCard       = "002"
+	onePUXSecureNote = "003"
+	onePUXIdentity   = "004"
+)
+
+// OnePUXAttributes : the export.attributes file of a 1PUX archive
+type OnePUXAttributes struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"createdAt"`
+}
+
+// OnePUXData : the export.data file of a 1PUX archive
+type OnePUXData struct {
+	Accounts []OnePUXAccount `json:"accounts"`
+}
+
+// OnePUXAccount : an account of a 1PUX archive
+type OnePUXAccount struct {
+	Attrs  OnePUXAccountAttrs `json:"attrs"`
+	Vaults []OnePUXVault      `json:"vaults"`
+}
+
+// OnePUXAccountAttrs : the attributes of a 1PUX account
+type OnePUXAccountAttrs struct {
+	AccountName string `json:"accountName"`
+	Name        string `json:"name"`
+	Avatar      string `json:"avatar"`
+	Email       string `json:"email"`
+	UUID        string `json:"uuid"`
+	Domain      string `json:"domain"`
+}
+
+// OnePUXVault : a vault of a 1PUX account
+type OnePUXVault struct {
+	Attrs OnePUXVaultAttrs `json:"attrs"`
+	Items []OnePUXItem     `json:"items"`
+}
+
+// OnePUXVaultAttrs : the attributes of a 1PUX vault, Type P is a personal vault
+type OnePUXVaultAttrs struct {
+	UUID   string `json:"uuid"`
+	Desc   string `json:"desc"`
+	Avatar string `json:"avatar"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+}
+
+// OnePUXItem : an item of a 1PUX vault
+type OnePUXItem struct {
+	UUID         string         `json:"uuid"`
+	FavIndex     int            `json:"favIndex"`
+	CreatedAt    int64          `json:"createdAt"`
+	UpdatedAt    int64          `json:"updatedAt"`
+	State        string         `json:"state"`
+	CategoryUUID string         `json:"categoryUuid"`
+	Details      OnePUXDetails  `json:"details"`
+	Overview     OnePUXOverview `json:"overview"`
+}
+
+// OnePUXDetails : the fields of a 1PUX item
+type OnePUXDetails struct {
+	LoginFields     []OnePUXLoginField `json:"loginFields"`
+	NotesPlain      string             `json:"notesPlain"`
+	Sections        []OnePUXSection    `json:"sections"`
+	PasswordHistory []interface{}      `json:"passwordHistory"`
+}
+
+// OnePUXLoginField : a username or password of a 1PUX login, T is text and P a password
+type OnePUXLoginField struct {
+	Value       string `json:"value"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	FieldType   string `json:"fieldType"`
+	Designation string `json:"designation"`
+}
+
+// OnePUXSection : a group of fields of a 1PUX item
+type OnePUXSection struct {
+	Title  string        `json:"title"`
+	Name   string        `json:"name"`
+	Fields []OnePUXField `json:"fields"`
+}
+
+// OnePUXField : a field of a 1PUX section. Value holds a single member named after the kind of the value, e.g.
+// string, concealed, totp, url, email or monthYear.
+type OnePUXField struct {
+	Title         string                 `json:"title"`
+	ID            string                 `json:"id"`
+	Value         map[string]interface{} `json:"value"`
+	IndexAtSource int                    `json:"indexAtSource"`
+	Guarded       bool                   `json:"guarded"`
+	Multiline     bool                   `json:"multiline"`
+	DontGenerate  bool                   `json:"dontGenerate"`
+}
+
+// OnePUXOverview : the summary of a 1PUX item
+type OnePUXOverview struct {
+	Subtitle string      `json:"subtitle"`
+	URLs     []OnePUXURL `json:"urls,omitempty"`
+	Title    string      `json:"title"`
+	URL      string      `json:"url"`
+	Tags     []string    `json:"tags,omitempty"`
+}
+
+// OnePUXURL : a website of a 1PUX item
+type OnePUXURL struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// WriteOnePUX : write the items as a 1Password Unencrypted Export, a zip archive holding a single account with a
+// single vault. Logins, credit cards, identities and notes map to the matching categories, anything else becomes
+// a login or a secure note depending on its fields. 1Password has no folders, the folder paths become tags, as do
+// the favorite and trashed flags. Archived items keep their state.
+func WriteOnePUX(w io.Writer, items []enpass.Item, opts Options) error {
+	paths := enpass.FolderPaths(opts.Folders)
+	vault := OnePUXVault{
+		Attrs: OnePUXVaultAttrs{UUID: strings.ReplaceAll(newGUID(), "-", ""), Name: "Enpass", Type: "P"},
+		Items: []OnePUXItem{},
+	}
+	for _, item := range items {
+		vault.Items = append(vault.Items, onePUXItem(item, paths, opts.IncludeSecrets))
+	}
+	data := OnePUXData{Accounts: []OnePUXAccount{{
+		Attrs:  OnePUXAccountAttrs{AccountName: "Enpass", Name: "Enpass", UUID: strings.ReplaceAll(newGUID(), "-", "")},
+		Vaults: []OnePUXVault{vault},
+	}}}
+	attributes := OnePUXAttributes{Version: 3, Description: "1Password Unencrypted Export", CreatedAt: time.Now().Unix()}
+
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name    string
+		content interface{}
+	}{
+		{"export.attributes", attributes},
+		{"export.data", data},
+	} {
+		entry, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func onePUXItem(item enpass.Item, paths map[string]string, includeSecrets bool) OnePUXItem {
+	m := mapItem(item, includeSecrets)
+	puxItem := OnePUXItem{
+		UUID:      strings.ReplaceAll(item.UUID, "-", ""),
+		CreatedAt: item.Created,
+		UpdatedAt: item.Updated,
+		State:     "active",
+		Details: OnePUXDetails{
+			LoginFields:     []OnePUXLoginField{},
+			NotesPlain:      item.Note,
+			Sections:        []OnePUXSection{},
+			PasswordHistory: []interface{}{},
+		},
+		Overview: OnePUXOverview{Title: item.Title, Subtitle: item.Subtitle},
+	}
+	if item.Archived {
+		puxItem.State = "archived"
+	}
+	if item.Favorite {
+		puxItem.FavIndex = 1
+		puxItem.Overview.Tags = append(puxItem.Overview.Tags, "favorite")
+	}
+	if item.Trashed {
+		puxItem.Overview.Tags = append(puxItem.Overview.Tags, "trashed")
+	}
+	for _, folder := range item.Folders {
+		if path, ok := paths[folder]; ok {
+			puxItem.Overview.Tags = append(puxItem.Overview.Tags, path)
+		}
+	}
+	for _, url := range m.urls {
+		puxItem.Overview.URLs = append(puxItem.Overview.URLs, OnePUXURL{URL: url})
+	}
+	if len(m.urls) > 0 {
+		puxItem.Overview.URL = m.urls[0]
+	}
+
+	field := func(id, title, kind string, value interface{}) OnePUXField {
+		return OnePUXField{Title: title, ID: id, Value: map[string]interface{}{kind: value}}
+	}
+	main := OnePUXSection{Fields: []OnePUXField{}}
+
+	switch m.kind {
+	case kindLogin:
+		puxItem.CategoryUUID = onePUXLogin
+		if m.username != "" {
+			puxItem.Details.LoginFields = append(puxItem.Details.LoginFields, OnePUXLoginField{Value: m.username, Name: "username", FieldType: "T", Designation: "username"})
+		}
+		if m.password != "" {
+			puxItem.Details.LoginFields = append(puxItem.Details.LoginFields, OnePUXLoginField{Value: m.password, Name: "password", FieldType: "P", Designation: "password"})
+		}
+	case kindCard:
+		puxItem.CategoryUUID = onePUXCard
+		for _, slot := range []struct{ key, id, title, kind string }{
+			{"cardholder", "cardholder", "cardholder name", "string"},
+			{"brand", "type", "type", "creditCardType"},
+			{"number", "ccnum", "number", "creditCardNumber"},
+			{"code", "cvv", "verification number", "concealed"},
+		} {
+			if m.card[slot.key] != "" {
+				main.Fields = append(main.Fields, field(slot.id, slot.title, slot.kind, m.card[slot.key]))
+			}
+		}
+		if month, year, ok := parseExpiry(m.card["expiry"]); ok {
+			main.Fields = append(main.Fields, field("expiry", "expiry date", "monthYear", year*100+month))
+		} else if m.card["expiry"] != "" {
+			main.Fields = append(main.Fields, field("expiry", "expiry date", "string", m.card["expiry"]))
+		}
+	case kindIdentity:
+		puxItem.CategoryUUID = onePUXIdentity
+		id := m.identity
+		for _, slot := range []struct{ key, id, title string }{
+			{"firstName", "firstname", "first name"},
+			{"middleName", "initial", "initial"},
+			{"lastName", "lastname", "last name"},
+			{"company", "company", "company"},
+		} {
+			if id[slot.key] != "" {
+				main.Fields = append(main.Fields, field(slot.id, slot.title, "string", id[slot.key]))
+			}
+		}
+		main.Name, main.Title = "name", "Identification"
+		puxItem.Details.Sections = append(puxItem.Details.Sections, main)
+		main = OnePUXSection{Name: "address", Title: "Address", Fields: []OnePUXField{}}
+
+		street := strings.TrimSpace(strings.Join([]string{id["address1"], id["address2"]}, "\n"))
+		if street != "" || id["city"] != "" || id["state"] != "" || id["postalCode"] != "" || id["country"] != "" {
+			main.Fields = append(main.Fields, field("address", "address", "address", map[string]string{
+				"street":  street,
+				"city":    id["city"],
+				"state":   id["state"],
+				"zip":     id["postalCode"],
+				"country": id["country"],
+			}))
+		}
+		if id["phone"] != "" {
+			main.Fields = append(main.Fields, field("defphone", "default phone", "phone", id["phone"]))
+		}
+		puxItem.Details.Sections = append(puxItem.Details.Sections, main)
+		main = OnePUXSection{Name: "internet", Title: "Internet Details", Fields: []OnePUXField{}}
+		if id["username"] != "" {
+			main.Fields = append(main.Fields, field("username", "username", "string", id["username"]))
+		}
+		if id["email"] != "" {
+			main.Fields = append(main.Fields, field("email", "email", "email", map[string]interface{}{"email_address": id["email"], "provider": nil}))
+		}
+		for _, slot := range []struct{ key, title string }{
+			{"title", "title"},
+			{"ssn", "social security number"},
+			{"passportNumber", "passport number"},
+			{"licenseNumber", "license number"},
+		} {
+			if id[slot.key] != "" {
+				kind := "string"
+				if slot.key == "ssn" {
+					kind = "concealed"
+				}
+				main.Fields = append(main.Fields, field(slot.key, slot.title, kind, id[slot.key]))
+			}
+		}
+		puxItem.Details.Sections = append(puxItem.Details.Sections, main)
+		main = OnePUXSection{Fields: []OnePUXField{}}
+	default:
+		puxItem.CategoryUUID = onePUXSecureNote
+	}
+
+	if m.totp != "" {
+		main.Fields = append(main.Fields, field("TOTP_"+strings.ReplaceAll(newGUID(), "-", ""), "one-time password", "totp", m.totp))
+	}
+	for index, custom := range m.custom {
+		title := custom.Label
+		if title == "" {
+			title = custom.Type
+		}
+		kind := "string"
+		switch {
+		case custom.IsSecret():
+			kind = "concealed"
+		case custom.Type == "url":
+			kind = "url"
+		case custom.Type == "phone":
+			kind = "phone"
+		}
+		puxField := field(strings.ReplaceAll(newGUID(), "-", ""), title, kind, custom.Value)
+		puxField.IndexAtSource = index
+		puxField.Multiline = custom.Type == "multiline"
+		main.Fields = append(main.Fields, puxField)
+	}
+	if len(main.Fields) > 0 {
+		puxItem.Details.Sections = append(puxItem.Details.Sections, main)
+	}
+
+	return puxItem
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// readOnePUX : unzip a 1PUX archive and decode its two files
+func readOnePUX(t *testing.T, data []byte) (OnePUXAttributes, OnePUXData) {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"export.attributes", "export.data"}) {
+		t.Fatalf("the archive holds %v", names)
+	}
+
+	var (
+		attributes OnePUXAttributes
+		export     OnePUXData
+	)
+	for i, target := range []interface{}{&attributes, &export} {
+		file, err := archive.File[i].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.NewDecoder(file).Decode(target); err != nil {
+			t.Fatalf("%s: %s", archive.File[i].Name, err)
+		}
+		file.Close()
+	}
+	return attributes, export
+}
+
+// onePUXFields : the fields of every section of the item, keyed by title
+func onePUXFields(item OnePUXItem) map[string]OnePUXField {
+	fields := map[string]OnePUXField{}
+	for _, section := range item.Details.Sections {
+		for _, field := range section.Fields {
+			fields[field.Title] = field
+		}
+	}
+	return fields
+}
+
+func TestWriteOnePUX(t *testing.T) {
+	items := []enpass.Item{
+		{
+			UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e10", Title: "GitHub", Category: "login", Favorite: true, Folders: []string{"f1"},
+			Fields: []enpass.ItemField{
+				{Label: "Username", Type: "username", Value: "octocat"},
+				{Label: "Password", Type: "password", Value: "s3cret"},
+				{Label: "Website", Type: "url", Value: "https://github.com"},
+				{Label: "Code", Type: "totp", Value: "JBSWY3DP"},
+				{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			},
+		},
+		{
+			UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e11", Title: "Visa", Category: "creditcard",
+			Fields: []enpass.ItemField{
+				{Label: "Cardholder", Type: "ccName", Value: "John Doe"},
+				{Label: "Number", Type: "ccNumber", Value: "4111111111111111"},
+				{Label: "CVC", Type: "ccCvc", Value: "123"},
+				{Label: "Expiry", Type: "ccExpiry", Value: "07/29"},
+			},
+		},
+		{UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e12", Title: "Door codes", Category: "note", Note: "front 1234", Archived: true},
+		{
+			UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e13", Title: "Me", Category: "identity",
+			Fields: []enpass.ItemField{
+				{Label: "First name", Type: "text", Value: "John"},
+				{Label: "Last name", Type: "text", Value: "Doe"},
+				{Label: "SSN", Type: "text", Value: "078-05-1120"},
+			},
+		},
+	}
+	opts := Options{Folders: []enpass.Folder{{UUID: "f1", Title: "Work"}}, IncludeSecrets: true}
+
+	var out bytes.Buffer
+	if err := WriteOnePUX(&out, items, opts); err != nil {
+		t.Fatal(err)
+	}
+	attributes, export := readOnePUX(t, out.Bytes())
+	if attributes.Version != 3 || attributes.CreatedAt <= 0 {
+		t.Errorf("unexpected attributes %+v", attributes)
+	}
+	if len(export.Accounts) != 1 || len(export.Accounts[0].Vaults) != 1 {
+		t.Fatalf("unexpected accounts %+v", export.Accounts)
+	}
+	written := export.Accounts[0].Vaults[0].Items
+	if len(written) != len(items) {
+		t.Fatalf("got %d items", len(written))
+	}
+
+	categories := []string{}
+	for _, item := range written {
+		categories = append(categories, item.CategoryUUID)
+	}
+	if want := []string{onePUXLogin, onePUXCard, onePUXSecureNote, onePUXIdentity}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("categories: got %v, want %v", categories, want)
+	}
+
+	login := written[0]
+	wantLogin := []OnePUXLoginField{
+		{Value: "octocat", Name: "username", FieldType: "T", Designation: "username"},
+		{Value: "s3cret", Name: "password", FieldType: "P", Designation: "password"},
+	}
+	if !reflect.DeepEqual(login.Details.LoginFields, wantLogin) {
+		t.Errorf("login fields: got %+v", login.Details.LoginFields)
+	}
+	if login.UUID != "6f0d1c4e8a514a7c9d8e0a1b2c3d4e10" || login.Overview.URL != "https://github.com" || login.FavIndex != 1 {
+		t.Errorf("unexpected login %+v", login)
+	}
+	if !reflect.DeepEqual(login.Overview.Tags, []string{"favorite", "Work"}) {
+		t.Errorf("tags: got %v", login.Overview.Tags)
+	}
+	fields := onePUXFields(login)
+	if totp := fields["one-time password"].Value["totp"]; totp != "JBSWY3DP" {
+		t.Errorf("totp: got %v", fields["one-time password"].Value)
+	}
+	if recovery := fields["Recovery"].Value["concealed"]; recovery != "r3c0very" {
+		t.Errorf("the sensitive custom field is not concealed: %v", fields["Recovery"].Value)
+	}
+
+	card := onePUXFields(written[1])
+	// JSON numbers decode as float64
+	if expiry := card["expiry date"].Value["monthYear"]; expiry != float64(202907) {
+		t.Errorf("expiry: got %v", card["expiry date"].Value)
+	}
+	if code := card["verification number"].Value["concealed"]; code != "123" {
+		t.Errorf("code: got %v", card["verification number"].Value)
+	}
+
+	if note := written[2]; note.State != "archived" || note.Details.NotesPlain != "front 1234" {
+		t.Errorf("unexpected note %+v", note)
+	}
+
+	identity := onePUXFields(written[3])
+	if identity["first name"].Value["string"] != "John" || identity["social security number"].Value["concealed"] != "078-05-1120" {
+		t.Errorf("unexpected identity fields %+v", identity)
+	}
+}
+
+func TestWriteOnePUXWithoutSecrets(t *testing.T) {
+	items := []enpass.Item{{
+		UUID: "6f0d1c4e-8a51-4a7c-9d8e-0a1b2c3d4e10", Title: "GitHub", Category: "login",
+		Fields: []enpass.ItemField{
+			{Label: "Username", Type: "username", Value: "octocat"},
+			{Label: "Password", Type: "password", Value: "s3cret"},
+			{Label: "Code", Type: "totp", Value: "JBSWY3DP"},
+			{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+		},
+	}}
+
+	var out bytes.Buffer
+	if err := WriteOnePUX(&out, items, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.Bytes(), []byte("s3cret")) {
+		t.Error("the archive holds the password")
+	}
+	_, export := readOnePUX(t, out.Bytes())
+	item := export.Accounts[0].Vaults[0].Items[0]
+	for _, field := range item.Details.LoginFields {
+		if field.Designation == "password" {
+			t.Errorf("the password was exported: %+v", field)
+		}
+	}
+	for title, field := range onePUXFields(item) {
+		for kind, value := range field.Value {
+			if (kind == "totp" || kind == "concealed") && value != "" {
+				t.Errorf("the secret %s was exported: %v", title, value)
+			}
+		}
+	}
+}
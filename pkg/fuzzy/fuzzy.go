@@ -0,0 +1,109 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 1
+	bonusConsecutive = 5
+	bonusWordStart   = 3
+	bonusFirstChar   = 8
+	penaltyGap       = 1
+)
+
+// Score : score how well pattern matches text as a subsequence, compared case-insensitively unless caseSensitive
+// is set. Consecutive characters, characters at the start of a word and a match on the very first character score
+// higher, gaps cost a little. ok is false when the pattern is not a subsequence of text. An empty pattern matches
+// everything with a score of 0.
+func Score(pattern, text string, caseSensitive bool) (score int, ok bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	fold := unicode.ToLower
+	if caseSensitive {
+		fold = func(r rune) rune { return r }
+	}
+	patternRunes := []rune(pattern)
+	for i, r := range patternRunes {
+		patternRunes[i] = fold(r)
+	}
+	textRunes := []rune(text)
+
+	var (
+		pi        int
+		lastMatch = -1
+		prev      rune
+	)
+	for ti, r := range textRunes {
+		if pi >= len(patternRunes) {
+			break
+		}
+		if fold(r) != patternRunes[pi] {
+			prev = r
+			continue
+		}
+
+		score += scoreMatch
+		if ti == 0 {
+			score += bonusFirstChar
+		} else if !isWordRune(prev) || (unicode.IsUpper(r) && unicode.IsLower(prev)) {
+			score += bonusWordStart
+		}
+		if lastMatch >= 0 {
+			if ti == lastMatch+1 {
+				score += bonusConsecutive
+			} else {
+				score -= penaltyGap * min(ti-lastMatch-1, 3)
+			}
+		}
+		lastMatch = ti
+		prev = r
+		pi++
+	}
+
+	if pi < len(patternRunes) {
+		return 0, false
+	}
+
+	return score, true
+}
+
+// Distance : return the Levenshtein edit distance between a and b, compared case-insensitively
+func Distance(a, b string) int {
+	ar := []rune(strings.ToLower(a))
+	br := []rune(strings.ToLower(b))
+
+	if len(ar) == 0 {
+		return len(br)
+	}
+	if len(br) == 0 {
+		return len(ar)
+	}
+
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(br)]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
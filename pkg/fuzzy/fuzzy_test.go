@@ -0,0 +1,65 @@
+package fuzzy
+
+import "testing"
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		text          string
+		caseSensitive bool
+		ok            bool
+	}{
+		{"", "anything", false, true},
+		{"gh", "GitHub", false, true},
+		{"ghb", "GitHub", false, true},
+		{"hg", "GitHub", false, false},
+		{"github", "Git", false, false},
+		{"GH", "github", false, true},
+		{"gh", "GitHub", true, false},
+		{"GH", "GitHub", true, true},
+		{"été", "Été à Paris", false, true},
+	}
+	for _, test := range tests {
+		if _, ok := Score(test.pattern, test.text, test.caseSensitive); ok != test.ok {
+			t.Errorf("Score(%q, %q, %v): got %v, want %v", test.pattern, test.text, test.caseSensitive, ok, test.ok)
+		}
+	}
+
+	// Each pair is ranked best first
+	better := []struct {
+		pattern, best, worse string
+	}{
+		{"git", "GitHub", "my gist item"},
+		{"bank", "Bank", "big ankle"},
+		{"gh", "GitHub", "debug high"},
+		{"ml", "my-login", "simple"},
+	}
+	for _, test := range better {
+		best, _ := Score(test.pattern, test.best, false)
+		worse, _ := Score(test.pattern, test.worse, false)
+		if best <= worse {
+			t.Errorf("%q: %q scores %d, %q scores %d", test.pattern, test.best, best, test.worse, worse)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"github", "GitHub", 0},
+		{"githib", "github", 1},
+		{"gthub", "github", 1},
+		{"kitten", "sitting", 3},
+		{"façade", "facade", 1},
+	}
+	for _, test := range tests {
+		if got := Distance(test.a, test.b); got != test.want {
+			t.Errorf("Distance(%q, %q): got %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package kdbx : write KeePass KDBX 4 databases, encrypted with ChaCha20 and an Argon2id derived key
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	signature1   uint32 = 0x9AA2D903
+	signature2   uint32 = 0xB54BFB67
+	fileVersion4 uint32 = 0x00040000
+
+	// outer header fields
+	headerEnd              byte = 0
+	headerCipherID         byte = 2
+	headerCompressionFlags byte = 3
+	headerMasterSeed       byte = 4
+	headerEncryptionIV     byte = 7
+	headerKdfParameters    byte = 11
+
+	// inner header fields
+	innerHeaderEnd       byte = 0
+	innerHeaderStreamID  byte = 1
+	innerHeaderStreamKey byte = 2
+	innerHeaderBinary    byte = 3
+
+	compressionGzip     uint32 = 1
+	innerStreamChaCha20 uint32 = 3
+	blockSize                  = 1024 * 1024
+
+	// secondsToUnixEpoch : KDBX 4 counts seconds from 0001-01-01 UTC
+	secondsToUnixEpoch int64 = 62135596800
+)
+
+var (
+	cipherChaCha20 = []byte{0xd6, 0x03, 0x8a, 0x2b, 0x8b, 0x6f, 0x4c, 0xb5, 0xa5, 0x24, 0x33, 0x9a, 0x31, 0xdb, 0xb5, 0x9a}
+	kdfArgon2id    = []byte{0x9e, 0x29, 0x8b, 0x19, 0x56, 0xdb, 0x47, 0x73, 0xb2, 0x3d, 0xfc, 0x3e, 0xc6, 0xf0, 0xa1, 0xe6}
+)
+
+// KDF : the Argon2id parameters, see DefaultKDF
+type KDF struct {
+	Iterations  uint32
+	MemoryKiB   uint32
+	Parallelism uint8
+}
+
+// DefaultKDF : the Argon2id parameters used by Write, 64 MiB and 3 passes
+var DefaultKDF = KDF{Iterations: 3, MemoryKiB: 64 * 1024, Parallelism: 2}
+
+// Database : the content of a KDBX file
+type Database struct {
+	Name string
+	Root Group
+}
+
+// Group : a group holding entries and subgroups
+type Group struct {
+	UUID    [16]byte
+	Name    string
+	Entries []Entry
+	Groups  []Group
+}
+
+// Entry : a KeePass entry. The standard strings are Title, UserName, Password, URL and Notes.
+type Entry struct {
+	UUID        [16]byte
+	Created     time.Time
+	Modified    time.Time
+	Accessed    time.Time
+	Tags        string
+	Strings     []String
+	Attachments []Attachment
+}
+
+// String : a string of an entry, protected strings are encrypted in memory by KeePass
+type String struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+// Attachment : a file attached to an entry
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// NewUUID : a random UUID for groups and entries
+func NewUUID() [16]byte {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(err)
+	}
+	return uuid
+}
+
+// CompositeKey : combine the password and the key file key into the KeePass composite key. Either may be empty,
+// not both.
+func CompositeKey(password string, keyFileKey []byte) ([]byte, error) {
+	if password == "" && len(keyFileKey) <= 0 {
+		return nil, errors.New("a password or a key file is required")
+	}
+
+	composite := sha256.New()
+	if password != "" {
+		hash := sha256.Sum256([]byte(password))
+		composite.Write(hash[:])
+	}
+	if len(keyFileKey) > 0 {
+		composite.Write(keyFileKey)
+	}
+	return composite.Sum(nil), nil
+}
+
+// Write : write the database as KDBX 4, protected by the composite key
+func Write(w io.Writer, db *Database, compositeKey []byte, kdf KDF) error {
+	var (
+		masterSeed = randomBytes(32)
+		iv         = randomBytes(12)
+		kdfSalt    = randomBytes(32)
+		streamKey  = randomBytes(64)
+	)
+
+	transformedKey := argon2.IDKey(compositeKey, kdfSalt, kdf.Iterations, kdf.MemoryKiB, kdf.Parallelism, 32)
+	encryptionKey := sha256.Sum256(append(append([]byte{}, masterSeed...), transformedKey...))
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, masterSeed...), transformedKey...), 0x01))
+
+	// Outer header
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, signature1)
+	binary.Write(&header, binary.LittleEndian, signature2)
+	binary.Write(&header, binary.LittleEndian, fileVersion4)
+	writeField(&header, headerCipherID, cipherChaCha20)
+	writeField(&header, headerCompressionFlags, uint32Bytes(compressionGzip))
+	writeField(&header, headerMasterSeed, masterSeed)
+	writeField(&header, headerEncryptionIV, iv)
+	writeField(&header, headerKdfParameters, kdfParameters(kdfSalt, kdf))
+	writeField(&header, headerEnd, []byte("\r\n\r\n"))
+
+	headerHash := sha256.Sum256(header.Bytes())
+	headerMAC := hmac.New(sha256.New, blockKey(math.MaxUint64, hmacKey[:]))
+	headerMAC.Write(header.Bytes())
+
+	// Inner header, then the XML document, compressed together
+	document, binaries, err := marshalDocument(db, streamKey)
+	if err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	var inner bytes.Buffer
+	writeField(&inner, innerHeaderStreamID, uint32Bytes(innerStreamChaCha20))
+	writeField(&inner, innerHeaderStreamKey, streamKey)
+	for _, data := range binaries {
+		// the first byte holds the flags, 0 is not protected
+		writeField(&inner, innerHeaderBinary, append([]byte{0}, data...))
+	}
+	writeField(&inner, innerHeaderEnd, nil)
+	gz.Write(inner.Bytes())
+	gz.Write(document)
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey[:], iv)
+	if err != nil {
+		return err
+	}
+	encrypted := make([]byte, payload.Len())
+	cipher.XORKeyStream(encrypted, payload.Bytes())
+
+	var out bytes.Buffer
+	out.Write(header.Bytes())
+	out.Write(headerHash[:])
+	out.Write(headerMAC.Sum(nil))
+	writeBlocks(&out, encrypted, hmacKey[:])
+
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// writeBlocks : split the encrypted payload into HMAC-authenticated blocks, ending with an empty block
+func writeBlocks(out *bytes.Buffer, data []byte, hmacKey []byte) {
+	for index := uint64(0); ; index++ {
+		size := min(blockSize, len(data))
+		block := data[:size]
+		data = data[size:]
+
+		mac := hmac.New(sha256.New, blockKey(index, hmacKey))
+		binary.Write(mac, binary.LittleEndian, index)
+		binary.Write(mac, binary.LittleEndian, uint32(len(block)))
+		mac.Write(block)
+
+		out.Write(mac.Sum(nil))
+		binary.Write(out, binary.LittleEndian, uint32(len(block)))
+		out.Write(block)
+
+		if len(block) == 0 {
+			return
+		}
+	}
+}
+
+func blockKey(index uint64, hmacKey []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, index)
+	buf.Write(hmacKey)
+	key := sha512.Sum512(buf.Bytes())
+	return key[:]
+}
+
+func writeField(buf *bytes.Buffer, id byte, data []byte) {
+	buf.WriteByte(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+// kdfParameters : the Argon2id parameters as a KeePass variant dictionary
+func kdfParameters(salt []byte, kdf KDF) []byte {
+	const (
+		typeUInt32    byte = 0x04
+		typeUInt64    byte = 0x05
+		typeByteArray byte = 0x42
+	)
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(0x0100))
+	entry := func(kind byte, name string, value []byte) {
+		buf.WriteByte(kind)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(name)))
+		buf.WriteString(name)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(value)))
+		buf.Write(value)
+	}
+	entry(typeByteArray, "$UUID", kdfArgon2id)
+	entry(typeByteArray, "S", salt)
+	entry(typeUInt32, "P", uint32Bytes(uint32(kdf.Parallelism)))
+	entry(typeUInt64, "M", uint64Bytes(uint64(kdf.MemoryKiB)*1024))
+	entry(typeUInt64, "I", uint64Bytes(uint64(kdf.Iterations)))
+	entry(typeUInt32, "V", uint32Bytes(0x13))
+	buf.WriteByte(0)
+
+	return buf.Bytes()
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// encodeTime : KDBX 4 stores times as base64 of the little endian seconds since 0001-01-01 UTC
+func encodeTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return base64.StdEncoding.EncodeToString(uint64Bytes(uint64(t.Unix() + secondsToUnixEpoch)))
+}
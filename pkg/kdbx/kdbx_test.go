@@ -0,0 +1,394 @@
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+)
+
+// testKDF : cheap Argon2id parameters, the format is the same
+var testKDF = KDF{Iterations: 1, MemoryKiB: 64, Parallelism: 1}
+
+// readBack : the entries read from a KDBX 4 file, decrypted the way KeePass does
+type readBack struct {
+	document xmlFile
+	binaries [][]byte
+	// protected values decrypted, by group name and entry title
+	strings map[string]map[string]string
+}
+
+// read : parse and decrypt a KDBX 4 file written by Write, checking every hash and HMAC on the way
+func read(data []byte, compositeKey []byte) (*readBack, error) {
+	r := bytes.NewReader(data)
+	var sig1, sig2, version uint32
+	binary.Read(r, binary.LittleEndian, &sig1)
+	binary.Read(r, binary.LittleEndian, &sig2)
+	binary.Read(r, binary.LittleEndian, &version)
+	if sig1 != signature1 || sig2 != signature2 || version != fileVersion4 {
+		return nil, errors.New("not a KDBX 4 file")
+	}
+
+	fields := map[byte][]byte{}
+	for {
+		id, data, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		if id == headerEnd {
+			break
+		}
+		fields[id] = data
+	}
+	header := data[:len(data)-r.Len()]
+	if !bytes.Equal(fields[headerCipherID], cipherChaCha20) {
+		return nil, errors.New("unexpected cipher")
+	}
+	if binary.LittleEndian.Uint32(fields[headerCompressionFlags]) != compressionGzip {
+		return nil, errors.New("unexpected compression")
+	}
+
+	kdf, err := readVariantDictionary(fields[headerKdfParameters])
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(kdf["$UUID"], kdfArgon2id) || binary.LittleEndian.Uint32(kdf["V"]) != 0x13 {
+		return nil, errors.New("unexpected KDF")
+	}
+	transformedKey := argon2.IDKey(compositeKey, kdf["S"],
+		uint32(binary.LittleEndian.Uint64(kdf["I"])),
+		uint32(binary.LittleEndian.Uint64(kdf["M"])/1024),
+		uint8(binary.LittleEndian.Uint32(kdf["P"])), 32)
+	seed := fields[headerMasterSeed]
+	encryptionKey := sha256.Sum256(append(append([]byte{}, seed...), transformedKey...))
+	hmacKey := sha512.Sum512(append(append(append([]byte{}, seed...), transformedKey...), 0x01))
+
+	headerHash := make([]byte, 32)
+	headerMAC := make([]byte, 32)
+	io.ReadFull(r, headerHash)
+	io.ReadFull(r, headerMAC)
+	if sum := sha256.Sum256(header); !bytes.Equal(sum[:], headerHash) {
+		return nil, errors.New("header hash mismatch")
+	}
+	mac := hmac.New(sha256.New, blockKey(math.MaxUint64, hmacKey[:]))
+	mac.Write(header)
+	if !hmac.Equal(mac.Sum(nil), headerMAC) {
+		return nil, errors.New("header HMAC mismatch, wrong key")
+	}
+
+	var encrypted []byte
+	for index := uint64(0); ; index++ {
+		blockMAC := make([]byte, 32)
+		var size uint32
+		if _, err := io.ReadFull(r, blockMAC); err != nil {
+			return nil, err
+		}
+		binary.Read(r, binary.LittleEndian, &size)
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, blockKey(index, hmacKey[:]))
+		binary.Write(mac, binary.LittleEndian, index)
+		binary.Write(mac, binary.LittleEndian, size)
+		mac.Write(block)
+		if !hmac.Equal(mac.Sum(nil), blockMAC) {
+			return nil, fmt.Errorf("HMAC mismatch of block %d", index)
+		}
+		if size == 0 {
+			break
+		}
+		encrypted = append(encrypted, block...)
+	}
+	if r.Len() != 0 {
+		return nil, errors.New("data after the last block")
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey[:], fields[headerEncryptionIV])
+	if err != nil {
+		return nil, err
+	}
+	compressed := make([]byte, len(encrypted))
+	cipher.XORKeyStream(compressed, encrypted)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	back := &readBack{strings: map[string]map[string]string{}}
+	inner := bytes.NewReader(payload)
+	var streamKey []byte
+	for {
+		id, data, err := readField(inner)
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case innerHeaderStreamID:
+			if binary.LittleEndian.Uint32(data) != innerStreamChaCha20 {
+				return nil, errors.New("unexpected inner stream")
+			}
+		case innerHeaderStreamKey:
+			streamKey = data
+		case innerHeaderBinary:
+			back.binaries = append(back.binaries, data[1:])
+		}
+		if id == innerHeaderEnd {
+			break
+		}
+	}
+	if err := xml.NewDecoder(inner).Decode(&back.document); err != nil {
+		return nil, err
+	}
+
+	streamHash := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(streamHash[:32], streamHash[32:44])
+	if err != nil {
+		return nil, err
+	}
+	if err := back.unprotect(back.document.Root.Group, stream); err != nil {
+		return nil, err
+	}
+	return back, nil
+}
+
+// unprotect : decrypt the protected values in document order, entries before subgroups
+func (back *readBack) unprotect(group xmlGroup, stream *chacha20.Cipher) error {
+	for _, entry := range group.Entries {
+		values := map[string]string{}
+		for _, s := range entry.Strings {
+			value := s.Value.Text
+			if s.Value.Protected == "True" {
+				masked, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					return err
+				}
+				plain := make([]byte, len(masked))
+				stream.XORKeyStream(plain, masked)
+				value = string(plain)
+			}
+			values[s.Key] = value
+		}
+		back.strings[group.Name+"/"+values["Title"]] = values
+	}
+	for _, child := range group.Groups {
+		if err := back.unprotect(child, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readField(r *bytes.Reader) (byte, []byte, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return id, data, nil
+}
+
+func readVariantDictionary(data []byte) (map[string][]byte, error) {
+	r := bytes.NewReader(data)
+	var version uint16
+	binary.Read(r, binary.LittleEndian, &version)
+	if version>>8 != 1 {
+		return nil, errors.New("unexpected variant dictionary version")
+	}
+	values := map[string][]byte{}
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0 {
+			return values, nil
+		}
+		var size uint32
+		binary.Read(r, binary.LittleEndian, &size)
+		name := make([]byte, size)
+		io.ReadFull(r, name)
+		binary.Read(r, binary.LittleEndian, &size)
+		value := make([]byte, size)
+		io.ReadFull(r, value)
+		values[string(name)] = value
+	}
+}
+
+func testDatabase() *Database {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &Database{
+		Name: "Enpass",
+		Root: Group{
+			UUID: NewUUID(),
+			Name: "Enpass",
+			Groups: []Group{
+				{
+					UUID: NewUUID(),
+					Name: "Login",
+					Entries: []Entry{
+						{
+							UUID:     NewUUID(),
+							Created:  created,
+							Modified: created.Add(time.Hour),
+							Accessed: created.Add(2 * time.Hour),
+							Tags:     "favorite",
+							Strings: []String{
+								{Key: "Title", Value: "GitHub"},
+								{Key: "UserName", Value: "octocat"},
+								{Key: "Password", Value: "gh-s3cret!", Protected: true},
+								{Key: "otp", Value: "otpauth://totp/GitHub?secret=JBSWY3DP", Protected: true},
+							},
+							Attachments: []Attachment{{Name: "codes.txt", Data: []byte("1111-2222\n")}},
+						},
+						{
+							UUID:    NewUUID(),
+							Strings: []String{{Key: "Title", Value: "Bank"}, {Key: "Password", Value: "b4nk", Protected: true}},
+						},
+					},
+				},
+				{
+					UUID:    NewUUID(),
+					Name:    "Note",
+					Entries: []Entry{{UUID: NewUUID(), Strings: []String{{Key: "Title", Value: "Door"}, {Key: "PIN", Value: "0000", Protected: true}}}},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteReadBack(t *testing.T) {
+	keyFileKey := bytes.Repeat([]byte{7}, 32)
+	compositeKey, err := CompositeKey("correct horse", keyFileKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := testDatabase()
+	var out bytes.Buffer
+	if err := Write(&out, db, compositeKey, testKDF); err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := read(out.Bytes(), compositeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]string{
+		"Login/GitHub": {"Title": "GitHub", "UserName": "octocat", "Password": "gh-s3cret!", "otp": "otpauth://totp/GitHub?secret=JBSWY3DP"},
+		"Login/Bank":   {"Title": "Bank", "Password": "b4nk"},
+		"Note/Door":    {"Title": "Door", "PIN": "0000"},
+	}
+	if !reflect.DeepEqual(back.strings, want) {
+		t.Errorf("strings\n got %v\nwant %v", back.strings, want)
+	}
+	if want := [][]byte{[]byte("1111-2222\n")}; !reflect.DeepEqual(back.binaries, want) {
+		t.Errorf("binaries: got %q, want %q", back.binaries, want)
+	}
+
+	document := back.document
+	if document.Meta.DatabaseName != "Enpass" || document.Root.Group.Name != "Enpass" || len(document.Root.Group.Groups) != 2 {
+		t.Fatalf("unexpected document structure: %+v", document)
+	}
+	entry := document.Root.Group.Groups[0].Entries[0]
+	source := db.Root.Groups[0].Entries[0]
+	if entry.UUID != base64.StdEncoding.EncodeToString(source.UUID[:]) {
+		t.Errorf("uuid: got %s", entry.UUID)
+	}
+	if entry.Tags != "favorite" || len(entry.Binaries) != 1 || entry.Binaries[0].Key != "codes.txt" || entry.Binaries[0].Value.Ref != 0 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	// 2024-01-02T03:04:05Z is 63839761445 seconds after 0001-01-01
+	if got, want := entry.Times.CreationTime, base64.StdEncoding.EncodeToString(uint64Bytes(63839761445)); got != want {
+		t.Errorf("creation time: got %s, want %s", got, want)
+	}
+	for _, s := range entry.Strings {
+		if s.Key == "Password" && (s.Value.Protected != "True" || s.Value.Text == "gh-s3cret!") {
+			t.Errorf("the password is not protected: %+v", s)
+		}
+	}
+}
+
+func TestWriteWrongKey(t *testing.T) {
+	compositeKey, _ := CompositeKey("correct horse", nil)
+	var out bytes.Buffer
+	if err := Write(&out, testDatabase(), compositeKey, testKDF); err != nil {
+		t.Fatal(err)
+	}
+
+	wrongKey, _ := CompositeKey("wrong horse", nil)
+	if _, err := read(out.Bytes(), wrongKey); err == nil || err.Error() != "header HMAC mismatch, wrong key" {
+		t.Errorf("got %v, want a header HMAC mismatch", err)
+	}
+
+	tampered := append([]byte{}, out.Bytes()...)
+	tampered[len(tampered)-40] ^= 1
+	if _, err := read(tampered, compositeKey); err == nil || err.Error() != "HMAC mismatch of block 0" {
+		t.Errorf("got %v, want a block HMAC mismatch", err)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	tests := []struct {
+		password   string
+		keyFileKey []byte
+		want       string
+	}{
+		// SHA-256(SHA-256(password))
+		{password: "password", want: "73641c99f7719f57d8f4beb11a303afcd190243a51ced8782ca6d3dbe014d146"},
+		// SHA-256(SHA-256(password) || key file key)
+		{password: "password", keyFileKey: bytesRange(32), want: "38527710e7683da8248471e4fa3a3087a3e5156bcf784ad7bc45582a025742e7"},
+		// SHA-256(key file key)
+		{keyFileKey: bytesRange(32), want: "630dcd2966c4336691125448bbb25b4ff412a49c732db2c8abc1b8581bd710dd"},
+	}
+
+	for _, test := range tests {
+		key, err := CompositeKey(test.password, test.keyFileKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(key); got != test.want {
+			t.Errorf("CompositeKey(%q, %x): got %s, want %s", test.password, test.keyFileKey, got, test.want)
+		}
+	}
+
+	if _, err := CompositeKey("", nil); err == nil {
+		t.Error("an empty composite key was accepted")
+	}
+}
+
+// bytesRange : 0, 1, ..., n-1
+func bytesRange(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
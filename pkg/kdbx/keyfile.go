@@ -0,0 +1,66 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type xmlKeyFile struct {
+	Meta struct {
+		Version string `xml:"Version"`
+	} `xml:"Meta"`
+	Key struct {
+		Data struct {
+			Hash string `xml:"Hash,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"Data"`
+	} `xml:"Key"`
+}
+
+// KeyFileKey : the key of a KeePass key file. XML key files (version 1 and 2), 32 byte binary files and 64
+// character hex files are read like KeePass does, any other file is hashed.
+func KeyFileKey(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<KeyFile")) {
+		var keyFile xmlKeyFile
+		if err := xml.Unmarshal(trimmed, &keyFile); err != nil {
+			return nil, fmt.Errorf("failed to parse the XML key file: %s", err)
+		}
+		text := strings.Join(strings.Fields(keyFile.Key.Data.Text), "")
+		if strings.HasPrefix(keyFile.Meta.Version, "2.") {
+			key, err := hex.DecodeString(text)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode the XML key file: %s", err)
+			}
+			if keyFile.Key.Data.Hash != "" {
+				hash := sha256.Sum256(key)
+				if !strings.EqualFold(hex.EncodeToString(hash[:4]), keyFile.Key.Data.Hash) {
+					return nil, fmt.Errorf("the XML key file is corrupted, its hash does not match")
+				}
+			}
+			return key, nil
+		}
+		key, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the XML key file: %s", err)
+		}
+		return key, nil
+	}
+
+	if len(data) == 32 {
+		return data, nil
+	}
+	if len(data) == 64 {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return key, nil
+		}
+	}
+
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
@@ -0,0 +1,89 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeyFileKey(t *testing.T) {
+	key := "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "XML version 2 with its hash",
+			data: []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile>
+	<Meta><Version>2.0</Version></Meta>
+	<Key>
+		<Data Hash="AE216C2E">
+			01020304 05060708 090A0B0C 0D0E0F10
+			11121314 15161718 191A1B1C 1D1E1F20
+		</Data>
+	</Key>
+</KeyFile>`),
+			want: key,
+		},
+		{
+			name: "XML version 1",
+			data: []byte(`<KeyFile><Meta><Version>1.00</Version></Meta><Key><Data>AQIDBAUGBwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyA=</Data></Key></KeyFile>`),
+			want: key,
+		},
+		{
+			name: "32 bytes",
+			data: mustHex(key),
+			want: key,
+		},
+		{
+			name: "64 hex characters",
+			data: []byte(strings.ToUpper(key)),
+			want: key,
+		},
+		{
+			name: "any other file is hashed",
+			data: []byte("not a key file"),
+			want: "4d7fa97f5bdfbafea776be8a782734150b1f1454dc8c2d8490771b4bfd347b0b",
+		},
+		{
+			name: "64 characters that are not hex are hashed",
+			data: bytes.Repeat([]byte("z"), 64),
+			want: "72996563049cc84daa2c3f31fd5c3d10770e69d6ebbb8da5b6d76db303dbae43",
+		},
+	}
+
+	for _, test := range tests {
+		got, err := KeyFileKey(test.data)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if hex.EncodeToString(got) != test.want {
+			t.Errorf("%s: got %x, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestKeyFileKeyErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"hash mismatch": `<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="00000000">0102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F20</Data></Key></KeyFile>`,
+		"bad hex":       `<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data>zz</Data></Key></KeyFile>`,
+		"bad base64":    `<KeyFile><Meta><Version>1.00</Version></Meta><Key><Data>!!!</Data></Key></KeyFile>`,
+		"broken XML":    `<KeyFile><Meta>`,
+	} {
+		if _, err := KeyFileKey([]byte(data)); err == nil {
+			t.Errorf("%s: the key file was accepted", name)
+		}
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
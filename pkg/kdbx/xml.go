@@ -0,0 +1,185 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/xml"
+	"time"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+type xmlFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    xmlMeta  `xml:"Meta"`
+	Root    xmlRoot  `xml:"Root"`
+}
+
+type xmlMeta struct {
+	Generator              string              `xml:"Generator"`
+	DatabaseName           string              `xml:"DatabaseName"`
+	DatabaseNameChanged    string              `xml:"DatabaseNameChanged"`
+	MaintenanceHistoryDays int                 `xml:"MaintenanceHistoryDays"`
+	MemoryProtection       xmlMemoryProtection `xml:"MemoryProtection"`
+	RecycleBinEnabled      string              `xml:"RecycleBinEnabled"`
+	HistoryMaxItems        int                 `xml:"HistoryMaxItems"`
+	HistoryMaxSize         int                 `xml:"HistoryMaxSize"`
+}
+
+type xmlMemoryProtection struct {
+	ProtectTitle    string `xml:"ProtectTitle"`
+	ProtectUserName string `xml:"ProtectUserName"`
+	ProtectPassword string `xml:"ProtectPassword"`
+	ProtectURL      string `xml:"ProtectURL"`
+	ProtectNotes    string `xml:"ProtectNotes"`
+}
+
+type xmlRoot struct {
+	Group          xmlGroup `xml:"Group"`
+	DeletedObjects struct{} `xml:"DeletedObjects"`
+}
+
+type xmlGroup struct {
+	UUID       string     `xml:"UUID"`
+	Name       string     `xml:"Name"`
+	Times      xmlTimes   `xml:"Times"`
+	IsExpanded string     `xml:"IsExpanded"`
+	Entries    []xmlEntry `xml:"Entry"`
+	Groups     []xmlGroup `xml:"Group"`
+}
+
+type xmlTimes struct {
+	CreationTime         string `xml:"CreationTime"`
+	LastModificationTime string `xml:"LastModificationTime"`
+	LastAccessTime       string `xml:"LastAccessTime"`
+	ExpiryTime           string `xml:"ExpiryTime"`
+	Expires              string `xml:"Expires"`
+	UsageCount           int    `xml:"UsageCount"`
+	LocationChanged      string `xml:"LocationChanged"`
+}
+
+type xmlEntry struct {
+	UUID     string      `xml:"UUID"`
+	Times    xmlTimes    `xml:"Times"`
+	Tags     string      `xml:"Tags,omitempty"`
+	Strings  []xmlString `xml:"String"`
+	Binaries []xmlBinary `xml:"Binary"`
+}
+
+type xmlString struct {
+	Key   string   `xml:"Key"`
+	Value xmlValue `xml:"Value"`
+}
+
+type xmlValue struct {
+	Protected string `xml:"Protected,attr,omitempty"`
+	Text      string `xml:",chardata"`
+}
+
+type xmlBinary struct {
+	Key   string       `xml:"Key"`
+	Value xmlBinaryRef `xml:"Value"`
+}
+
+type xmlBinaryRef struct {
+	Ref int `xml:"Ref,attr"`
+}
+
+// documentBuilder : protected values are XOR-ed with the inner stream in document order, so the document is
+// built in the order it is written
+type documentBuilder struct {
+	stream   *chacha20.Cipher
+	binaries [][]byte
+}
+
+// marshalDocument : render the XML document and collect the attachments for the inner header
+func marshalDocument(db *Database, streamKey []byte) ([]byte, [][]byte, error) {
+	streamHash := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(streamHash[:32], streamHash[32:44])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	b := &documentBuilder{stream: stream}
+	file := xmlFile{
+		Meta: xmlMeta{
+			Generator:              "enpass",
+			DatabaseName:           db.Name,
+			DatabaseNameChanged:    encodeTime(now),
+			MaintenanceHistoryDays: 365,
+			MemoryProtection: xmlMemoryProtection{
+				ProtectTitle:    "False",
+				ProtectUserName: "False",
+				ProtectPassword: "True",
+				ProtectURL:      "False",
+				ProtectNotes:    "False",
+			},
+			RecycleBinEnabled: "False",
+			HistoryMaxItems:   10,
+			HistoryMaxSize:    6 * 1024 * 1024,
+		},
+		Root: xmlRoot{Group: b.group(db.Root, now)},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>` + "\n")
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(file); err != nil {
+		return nil, nil, err
+	}
+
+	return buf.Bytes(), b.binaries, nil
+}
+
+func (b *documentBuilder) group(group Group, now time.Time) xmlGroup {
+	g := xmlGroup{
+		UUID:       base64.StdEncoding.EncodeToString(group.UUID[:]),
+		Name:       group.Name,
+		Times:      times(now, now, now),
+		IsExpanded: "True",
+	}
+	for _, entry := range group.Entries {
+		g.Entries = append(g.Entries, b.entry(entry))
+	}
+	for _, child := range group.Groups {
+		g.Groups = append(g.Groups, b.group(child, now))
+	}
+	return g
+}
+
+func (b *documentBuilder) entry(entry Entry) xmlEntry {
+	e := xmlEntry{
+		UUID:  base64.StdEncoding.EncodeToString(entry.UUID[:]),
+		Times: times(entry.Created, entry.Modified, entry.Accessed),
+		Tags:  entry.Tags,
+	}
+	for _, s := range entry.Strings {
+		value := xmlValue{Text: s.Value}
+		if s.Protected {
+			masked := make([]byte, len(s.Value))
+			b.stream.XORKeyStream(masked, []byte(s.Value))
+			value = xmlValue{Protected: "True", Text: base64.StdEncoding.EncodeToString(masked)}
+		}
+		e.Strings = append(e.Strings, xmlString{Key: s.Key, Value: value})
+	}
+	for _, attachment := range entry.Attachments {
+		e.Binaries = append(e.Binaries, xmlBinary{Key: attachment.Name, Value: xmlBinaryRef{Ref: len(b.binaries)}})
+		b.binaries = append(b.binaries, attachment.Data)
+	}
+	return e
+}
+
+func times(created, modified, accessed time.Time) xmlTimes {
+	return xmlTimes{
+		CreationTime:         encodeTime(created),
+		LastModificationTime: encodeTime(modified),
+		LastAccessTime:       encodeTime(accessed),
+		ExpiryTime:           encodeTime(created),
+		Expires:              "False",
+		UsageCount:           0,
+		LocationChanged:      encodeTime(modified),
+	}
+}
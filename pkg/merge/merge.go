@@ -0,0 +1,224 @@
+// Package merge : plan the merge of the items of a vault into another one
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/diff"
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// The conflict strategies, applied to the items both vaults hold with different content
+const (
+	NewestWins  = "newest-wins"
+	KeepBoth    = "keep-both"
+	Interactive = "interactive"
+)
+
+// Strategies : the valid conflict strategies
+var Strategies = []string{NewestWins, KeepBoth, Interactive}
+
+// The actions of a plan
+const (
+	// Add : the item is new to the destination
+	Add = "add"
+	// Replace : the item of the source replaces the one of the destination
+	Replace = "replace"
+	// Copy : the item of the source is added next to the one of the destination, with a new uuid
+	Copy = "copy"
+	// Keep : the item of the destination is kept, the one of the source is ignored
+	Keep = "keep"
+	// Same : both vaults hold the same item
+	Same = "same"
+)
+
+// CopySuffix : appended to the title of the copies of keep-both, to tell them from the items of the destination
+const CopySuffix = " (merged copy)"
+
+// Resolver : decide the action, Replace, Copy or Keep, of a conflict
+type Resolver func(source, destination enpass.Item) (string, error)
+
+// Action : what happens to an item of the source
+type Action struct {
+	Action string `json:"action" yaml:"action"`
+	UUID   string `json:"uuid" yaml:"uuid"`
+	Title  string `json:"title" yaml:"title"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// Item is the item written to the destination, with its folders mapped to the destination
+	Item enpass.Item `json:"-" yaml:"-"`
+}
+
+// Plan : the folders to create and the actions of a merge
+type Plan struct {
+	Folders []enpass.Folder
+	Actions []Action
+}
+
+// NewPlan : plan the merge of the items of source into destination. Items are matched by uuid, those both vaults
+// hold with different content are conflicts settled by the strategy, resolve decides them with Interactive. Folders
+// are matched by uuid, then by path, the missing ones are created.
+func NewPlan(source, destination diff.Vault, strategy string, resolve Resolver) (*Plan, error) {
+	if strategy == Interactive && resolve == nil {
+		return nil, fmt.Errorf("the %s strategy needs a resolver", Interactive)
+	}
+
+	plan := &Plan{Folders: []enpass.Folder{}, Actions: []Action{}}
+	folders := plan.mapFolders(source.Folders, destination.Folders)
+
+	existing := map[string]enpass.Item{}
+	for _, item := range destination.Items {
+		existing[item.UUID] = item
+	}
+
+	for _, item := range source.Items {
+		mapped := item
+		// Attachments are not merged, NoteAttachments reports them
+		mapped.Attachments = nil
+		mapped.Folders = []string{}
+		for _, uuid := range item.Folders {
+			if target, ok := folders[uuid]; ok {
+				mapped.Folders = append(mapped.Folders, target)
+			}
+		}
+
+		current, ok := existing[item.UUID]
+		if !ok {
+			plan.Actions = append(plan.Actions, Action{Action: Add, UUID: item.UUID, Title: item.Title, Item: mapped})
+			continue
+		}
+		// Compare the item as it would be written, its folders are those of the destination by then
+		before := diff.Vault{Items: []enpass.Item{current}, Folders: destination.Folders}
+		after := diff.Vault{Items: []enpass.Item{mapped}, Folders: append(append([]enpass.Folder{}, destination.Folders...), plan.Folders...)}
+		if len(diff.Compare(before, after, false)) == 0 {
+			plan.Actions = append(plan.Actions, Action{Action: Same, UUID: item.UUID, Title: item.Title})
+			continue
+		}
+
+		action := Action{UUID: item.UUID, Title: item.Title, Item: mapped}
+		switch strategy {
+		case NewestWins:
+			if item.Updated > current.Updated {
+				action.Action, action.Reason = Replace, "newer in the source"
+			} else {
+				action.Action, action.Reason = Keep, "newer in the destination"
+			}
+		case KeepBoth:
+			action.Action, action.Reason = Copy, "conflict"
+		case Interactive:
+			choice, err := resolve(item, current)
+			if err != nil {
+				return nil, err
+			}
+			if choice != Replace && choice != Copy && choice != Keep {
+				return nil, fmt.Errorf("invalid action %q for %q", choice, item.Title)
+			}
+			action.Action, action.Reason = choice, "chosen"
+		default:
+			return nil, fmt.Errorf("invalid strategy %q, valid: %s", strategy, strings.Join(Strategies, ", "))
+		}
+		if action.Action == Copy {
+			action.Item.UUID = enpass.NewUUID()
+			action.Item.Title += CopySuffix
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+
+	return plan, nil
+}
+
+// mapFolders : map every folder of the source to a folder of the destination, adding the folders to create to the
+// plan. Parents are created before their children.
+func (p *Plan) mapFolders(source, destination []enpass.Folder) map[string]string {
+	existing := map[string]bool{}
+	byPath := map[string]string{}
+	for uuid, path := range enpass.FolderPaths(destination) {
+		existing[uuid] = true
+		if _, ok := byPath[path]; !ok {
+			byPath[path] = uuid
+		}
+	}
+
+	byUUID := map[string]enpass.Folder{}
+	for _, folder := range source {
+		byUUID[folder.UUID] = folder
+	}
+	paths := enpass.FolderPaths(source)
+
+	mapped := map[string]string{}
+	var resolve func(uuid string, seen map[string]bool) string
+	resolve = func(uuid string, seen map[string]bool) string {
+		if target, ok := mapped[uuid]; ok {
+			return target
+		}
+		folder, ok := byUUID[uuid]
+		if !ok || seen[uuid] {
+			return ""
+		}
+		seen[uuid] = true
+
+		target := ""
+		switch {
+		case existing[uuid]:
+			target = uuid
+		case byPath[paths[uuid]] != "":
+			target = byPath[paths[uuid]]
+		default:
+			folder.ParentUUID = resolve(folder.ParentUUID, seen)
+			p.Folders = append(p.Folders, folder)
+			byPath[paths[uuid]] = uuid
+			target = uuid
+		}
+		mapped[uuid] = target
+		return target
+	}
+
+	uuids := []string{}
+	for uuid := range byUUID {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		resolve(uuid, map[string]bool{})
+	}
+	return mapped
+}
+
+// NoteAttachments : mark the actions writing items of the source that have attachments, which are not merged.
+// counts holds the number of attachments of the source items by uuid. It returns the number of actions marked.
+func (p *Plan) NoteAttachments(counts map[string]int) int {
+	marked := 0
+	for i, action := range p.Actions {
+		if counts[action.UUID] <= 0 || (action.Action != Add && action.Action != Replace && action.Action != Copy) {
+			continue
+		}
+		note := fmt.Sprintf("attachments not merged: %d", counts[action.UUID])
+		if action.Reason != "" {
+			note = action.Reason + ", " + note
+		}
+		p.Actions[i].Reason = note
+		marked++
+	}
+	return marked
+}
+
+// Items : the items to write, those added, replaced and copied
+func (p *Plan) Items() []enpass.Item {
+	items := []enpass.Item{}
+	for _, action := range p.Actions {
+		if action.Action == Add || action.Action == Replace || action.Action == Copy {
+			items = append(items, action.Item)
+		}
+	}
+	return items
+}
+
+// Count : the number of actions of each kind
+func (p *Plan) Count() map[string]int {
+	counts := map[string]int{}
+	for _, action := range p.Actions {
+		counts[action.Action]++
+	}
+	return counts
+}
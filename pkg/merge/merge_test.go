@@ -0,0 +1,225 @@
+package merge
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/diff"
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func item(uuid, title string, updated int64, folders ...string) enpass.Item {
+	return enpass.Item{
+		UUID: uuid, Title: title, Category: "login", Updated: updated, Folders: folders,
+		Fields: []enpass.ItemField{{UID: 1, Label: "Password", Type: "password", Value: "p-" + title}},
+	}
+}
+
+func actions(plan *Plan) map[string]string {
+	byUUID := map[string]string{}
+	for _, action := range plan.Actions {
+		byUUID[action.UUID] = action.Action
+	}
+	return byUUID
+}
+
+func TestNewPlan(t *testing.T) {
+	// The source files its items in a folder of its own that has the path of a folder of the destination
+	source := diff.Vault{
+		Folders: []enpass.Folder{{UUID: "src-work", Title: "Work"}},
+		Items: []enpass.Item{
+			item("new", "New", 10, "src-work"),
+			item("same", "Same", 10, "src-work"),
+			item("newer", "Newer", 20),
+			item("older", "Older", 10),
+		},
+	}
+	destination := diff.Vault{
+		Folders: []enpass.Folder{{UUID: "dst-work", Title: "Work"}},
+		Items: []enpass.Item{
+			item("same", "Same", 10, "dst-work"),
+			item("newer", "Newer (old)", 10),
+			item("older", "Older (new)", 20),
+		},
+	}
+
+	plan, err := NewPlan(source, destination, NewestWins, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"new": Add, "same": Same, "newer": Replace, "older": Keep}
+	if got := actions(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(plan.Folders) != 0 {
+		t.Errorf("folders were created: %+v", plan.Folders)
+	}
+	if got := plan.Actions[0].Item.Folders; !reflect.DeepEqual(got, []string{"dst-work"}) {
+		t.Errorf("the folders of the new item were not mapped: %v", got)
+	}
+	titles := []string{}
+	for _, written := range plan.Items() {
+		titles = append(titles, written.Title)
+	}
+	if !reflect.DeepEqual(titles, []string{"New", "Newer"}) {
+		t.Errorf("writes %v", titles)
+	}
+	if counts := plan.Count(); counts[Add] != 1 || counts[Same] != 1 || counts[Replace] != 1 || counts[Keep] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestNewPlanMovedItem(t *testing.T) {
+	// Same content, but filed in another folder of the destination: not the same item
+	source := diff.Vault{Folders: []enpass.Folder{{UUID: "home", Title: "Home"}}, Items: []enpass.Item{item("a", "A", 20, "home")}}
+	destination := diff.Vault{Folders: []enpass.Folder{{UUID: "work", Title: "Work"}}, Items: []enpass.Item{item("a", "A", 10, "work")}}
+
+	plan, err := NewPlan(source, destination, NewestWins, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Actions[0].Action != Replace {
+		t.Errorf("got %s, want %s", plan.Actions[0].Action, Replace)
+	}
+	if len(plan.Folders) != 1 || plan.Folders[0].UUID != "home" {
+		t.Errorf("the folder Home was not created: %+v", plan.Folders)
+	}
+}
+
+func TestNewPlanKeepBoth(t *testing.T) {
+	source := diff.Vault{Items: []enpass.Item{item("a", "A", 10)}}
+	destination := diff.Vault{Items: []enpass.Item{item("a", "B", 20)}}
+
+	plan, err := NewPlan(source, destination, KeepBoth, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := plan.Actions[0]
+	if action.Action != Copy || action.UUID != "a" {
+		t.Fatalf("unexpected action %+v", action)
+	}
+	if action.Item.UUID == "a" || action.Item.UUID == "" {
+		t.Errorf("the copy kept the uuid %q", action.Item.UUID)
+	}
+	if action.Item.Title != "A"+CopySuffix {
+		t.Errorf("the copy is titled %q", action.Item.Title)
+	}
+}
+
+func TestNewPlanInteractive(t *testing.T) {
+	source := diff.Vault{Items: []enpass.Item{item("a", "A", 10), item("b", "B", 10)}}
+	destination := diff.Vault{Items: []enpass.Item{item("a", "A2", 10), item("b", "B2", 10)}}
+
+	if _, err := NewPlan(source, destination, Interactive, nil); err == nil {
+		t.Error("the interactive strategy was accepted without a resolver")
+	}
+
+	choices := map[string]string{"a": Keep, "b": Replace}
+	plan, err := NewPlan(source, destination, Interactive, func(source, destination enpass.Item) (string, error) {
+		if source.UUID != destination.UUID {
+			t.Errorf("asked about %s and %s", source.UUID, destination.UUID)
+		}
+		return choices[source.UUID], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(plan); !reflect.DeepEqual(got, choices) {
+		t.Errorf("got %v, want %v", got, choices)
+	}
+
+	if _, err := NewPlan(source, destination, Interactive, func(enpass.Item, enpass.Item) (string, error) { return Same, nil }); err == nil {
+		t.Error("an invalid choice was accepted")
+	}
+	if _, err := NewPlan(source, destination, Interactive, func(enpass.Item, enpass.Item) (string, error) { return "", errors.New("interrupted") }); err == nil {
+		t.Error("the resolver error was dropped")
+	}
+	if _, err := NewPlan(source, destination, "oldest-wins", nil); err == nil || !strings.Contains(err.Error(), "invalid strategy") {
+		t.Errorf("an invalid strategy was accepted: %v", err)
+	}
+}
+
+func TestMapFolders(t *testing.T) {
+	source := []enpass.Folder{
+		{UUID: "shared", Title: "Shared"},
+		{UUID: "s-work", Title: "Work"},
+		{UUID: "s-git", Title: "Git", ParentUUID: "s-work"},
+		{UUID: "s-deep", Title: "Deep", ParentUUID: "s-git"},
+		{UUID: "s-home", Title: "Home"},
+		{UUID: "loop-a", Title: "A", ParentUUID: "loop-b"},
+		{UUID: "loop-b", Title: "B", ParentUUID: "loop-a"},
+	}
+	destination := []enpass.Folder{
+		{UUID: "shared", Title: "Renamed"},
+		{UUID: "d-work", Title: "Work"},
+		{UUID: "d-git", Title: "Git", ParentUUID: "d-work"},
+	}
+
+	plan := &Plan{}
+	mapped := plan.mapFolders(source, destination)
+
+	for uuid, want := range map[string]string{
+		"shared": "shared", "s-work": "d-work", "s-git": "d-git", "s-deep": "s-deep", "s-home": "s-home",
+		"loop-a": "loop-a", "loop-b": "loop-b",
+	} {
+		if mapped[uuid] != want {
+			t.Errorf("%s is mapped to %q, want %q", uuid, mapped[uuid], want)
+		}
+	}
+
+	created := map[string]enpass.Folder{}
+	position := map[string]int{}
+	for i, folder := range plan.Folders {
+		created[folder.UUID] = folder
+		position[folder.UUID] = i
+	}
+	if len(plan.Folders) != 4 {
+		t.Errorf("created %+v", plan.Folders)
+	}
+	if created["s-deep"].ParentUUID != "d-git" {
+		t.Errorf("Deep is created under %q, want the Git folder of the destination", created["s-deep"].ParentUUID)
+	}
+	if created["s-home"].ParentUUID != "" {
+		t.Errorf("Home is created under %q", created["s-home"].ParentUUID)
+	}
+	// A parent is created before its child, a loop is cut where it was entered
+	if created["loop-a"].ParentUUID != "" && created["loop-b"].ParentUUID != "" {
+		t.Errorf("the loop was not cut: %+v %+v", created["loop-a"], created["loop-b"])
+	}
+	for _, folder := range plan.Folders {
+		if parent, ok := position[folder.ParentUUID]; ok && parent > position[folder.UUID] {
+			t.Errorf("%s is created before its parent %s", folder.UUID, folder.ParentUUID)
+		}
+	}
+
+	// Mapping the destination into itself creates nothing
+	again := &Plan{}
+	again.mapFolders(destination, destination)
+	if len(again.Folders) != 0 {
+		t.Errorf("created %+v", again.Folders)
+	}
+}
+
+func TestNoteAttachments(t *testing.T) {
+	plan := &Plan{Actions: []Action{
+		{Action: Add, UUID: "a"},
+		{Action: Copy, UUID: "b", Reason: "conflict"},
+		{Action: Keep, UUID: "c", Reason: "newer in the destination"},
+		{Action: Same, UUID: "d"},
+		{Action: Add, UUID: "e"},
+	}}
+	marked := plan.NoteAttachments(map[string]int{"a": 2, "b": 1, "c": 1, "d": 1})
+	if marked != 2 {
+		t.Errorf("marked %d actions, want 2", marked)
+	}
+	reasons := []string{}
+	for _, action := range plan.Actions {
+		reasons = append(reasons, action.Reason)
+	}
+	want := []string{"attachments not merged: 2", "conflict, attachments not merged: 1", "newer in the destination", "", ""}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got %q, want %q", reasons, want)
+	}
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// csvFormatter : one row per item with every field flattened into columns, see WriteCSV
+type csvFormatter struct {
+	comma rune
+}
+
+func (f csvFormatter) Format(w io.Writer, cards []enpass.Card, opts Options) error {
+	return errors.New("the csv and tsv formats render whole items, use RenderItems")
+}
+
+func (f csvFormatter) FormatItems(w io.Writer, items []enpass.Item, opts Options) error {
+	return WriteCSV(w, items, f.comma, opts.IncludeSecrets)
+}
+
+// WriteCSV : write one row per item. The columns are title, username, password, url, totp, note and category,
+// followed by one column per custom field label in order of appearance. Without includeSecrets the password and
+// totp columns and the sensitive custom fields are left out entirely.
+func WriteCSV(w io.Writer, items []enpass.Item, comma rune, includeSecrets bool) error {
+	header := []string{"title", "username"}
+	if includeSecrets {
+		header = append(header, "password")
+	}
+	header = append(header, "url")
+	if includeSecrets {
+		header = append(header, "totp")
+	}
+	header = append(header, "note", "category")
+
+	rows := make([]map[string]string, len(items))
+	customColumns := []string{}
+	knownColumns := map[string]bool{}
+	for _, name := range header {
+		knownColumns[name] = true
+	}
+
+	for i, item := range items {
+		row := map[string]string{
+			"title":    item.Title,
+			"note":     item.Note,
+			"category": item.Category,
+		}
+		used := map[int]bool{}
+
+		for _, column := range []struct {
+			name   string
+			types  []string
+			secret bool
+		}{
+			{"username", []string{"username", "email"}, false},
+			{"password", []string{"password"}, true},
+			{"url", []string{"url"}, false},
+			{"totp", []string{"totp"}, true},
+		} {
+			for _, fieldType := range column.types {
+				if index := item.FieldIndex(fieldType, used); index >= 0 {
+					used[index] = true
+					if !column.secret || includeSecrets {
+						row[column.name] = item.Fields[index].Value
+					}
+					break
+				}
+			}
+		}
+		if row["username"] == "" {
+			row["username"] = item.Subtitle
+		}
+
+		for index, field := range item.Fields {
+			if used[index] || field.Type == "section" || field.Value == "" || (field.IsSecret() && !includeSecrets) {
+				continue
+			}
+			name := customColumnName(field, row)
+			row[name] = field.Value
+			if !knownColumns[name] {
+				knownColumns[name] = true
+				customColumns = append(customColumns, name)
+			}
+		}
+		rows[i] = row
+	}
+
+	header = append(header, customColumns...)
+
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for j, name := range header {
+			record[j] = row[name]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// customColumnName : the column of a custom field, its label or type. A label repeated within the item or
+// clashing with a standard column gets a number.
+func customColumnName(field enpass.ItemField, row map[string]string) string {
+	name := field.Label
+	if name == "" {
+		name = field.Type
+	}
+	candidate := name
+	for n := 2; ; n++ {
+		if _, taken := row[candidate]; !taken && !isStandardColumn(candidate) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s %d", name, n)
+	}
+}
+
+func isStandardColumn(name string) bool {
+	switch name {
+	case "title", "username", "password", "url", "totp", "note", "category":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+func TestWriteCSV(t *testing.T) {
+	items := []enpass.Item{
+		{
+			Title: "GitHub", Subtitle: "octocat", Category: "login", Note: "work",
+			Fields: []enpass.ItemField{
+				{Label: "E-mail", Type: "email", Value: "octocat@example.com"},
+				{Label: "Password", Type: "password", Value: "s3cret"},
+				{Label: "Website", Type: "url", Value: "https://github.com"},
+				{Label: "Section", Type: "section"},
+				{Label: "title", Type: "text", Value: "clashes with a column"},
+				{Label: "Code", Type: "text", Value: "1"},
+				{Label: "Code", Type: "text", Value: "2"},
+				{Label: "Recovery", Type: "text", Value: "r3c0very", Sensitive: true},
+			},
+		},
+		{Title: "Door", Subtitle: "front", Category: "note"},
+	}
+
+	tests := []struct {
+		includeSecrets bool
+		want           string
+	}{
+		{
+			want: "title,username,url,note,category,title 2,Code,Code 2\n" +
+				"GitHub,octocat@example.com,https://github.com,work,login,clashes with a column,1,2\n" +
+				"Door,front,,,note,,,\n",
+		},
+		{
+			includeSecrets: true,
+			want: "title,username,password,url,totp,note,category,title 2,Code,Code 2,Recovery\n" +
+				"GitHub,octocat@example.com,s3cret,https://github.com,,work,login,clashes with a column,1,2,r3c0very\n" +
+				"Door,front,,,,,note,,,,\n",
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := WriteCSV(&out, items, ',', test.includeSecrets); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("includeSecrets %v:\n got %q\nwant %q", test.includeSecrets, out.String(), test.want)
+		}
+	}
+}
@@ -2,44 +2,59 @@ package output
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/gdanko/enpass/pkg/enpass"
 )
 
-func doDefaultOutput(cards []enpass.Card, cmdType string, noolorString bool) {
+func formatDefault(w io.Writer, cards []enpass.Card, opts Options) error {
+	if len(cards) <= 0 {
+		return writeNoRecords(w)
+	}
+
 	var title string
 	for i, cardItem := range cards {
-		if cmdType == "list" {
-			if noolorString {
-				title = fmt.Sprintf("[%05d] >", i+1)
-			} else {
-				c := color.New(color.FgCyan)
-				title = c.Sprintf("[%05d] >", i+1)
+		if opts.NoColor {
+			title = fmt.Sprintf("[%05d] >", i+1)
+		} else if opts.CmdType == "show" {
+			title = color.New(color.FgRed).Sprintf("[%05d] >", i+1)
+		} else {
+			title = color.New(color.FgCyan).Sprintf("[%05d] >", i+1)
+		}
+
+		var err error
+		if len(opts.Fields) > 0 {
+			values := make([]string, len(opts.Fields))
+			for j, field := range opts.Fields {
+				values[j] = fmt.Sprintf("%s: %s", field, fieldValue(cardItem, field))
 			}
-			fmt.Printf(
-				"%s title: %s, login: %s, category: %s\n",
+			_, err = fmt.Fprintf(w, "%s %s\n", title, strings.Join(values, ", "))
+		} else if opts.CmdType == "show" {
+			_, err = fmt.Fprintf(
+				w,
+				"%s title: %s, login: %s, category: %s, %s: %s\n",
 				title,
 				cardItem.Title,
 				cardItem.Subtitle,
 				cardItem.Category,
+				cardItem.Type,
+				cardItem.DecryptedValue,
 			)
-		} else if cmdType == "show" {
-			if noolorString {
-				title = fmt.Sprintf("[%05d] >", i+1)
-			} else {
-				c := color.New(color.FgRed)
-				title = c.Sprintf("[%05d] >", i+1)
-			}
-			fmt.Printf(
-				"%s title: %s, login: %s, category: %s, %s: %s\n",
+		} else {
+			_, err = fmt.Fprintf(
+				w,
+				"%s title: %s, login: %s, category: %s\n",
 				title,
 				cardItem.Title,
 				cardItem.Subtitle,
 				cardItem.Category,
-				cardItem.Type,
-				cardItem.DecryptedValue,
 			)
 		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
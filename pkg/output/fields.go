@@ -0,0 +1,158 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/goccy/go-yaml"
+)
+
+// ValidFields : the fields usable with --fields, in the order of the list output
+var ValidFields = []string{"uuid", "created", "updated", "card_type", "title", "subtitle", "login", "note", "category", "label", "last_used", "sensitive", "trashed", "icon", "decrypted_value"}
+
+// FieldCommands : the commands whose output fields can be configured
+var FieldCommands = []string{"list", "show"}
+
+// ValidFieldsFor : the fields usable with the command, decrypted_value is only shown by show
+func ValidFieldsFor(cmdType string) []string {
+	valid := []string{}
+	for _, field := range ValidFields {
+		if field == "decrypted_value" && cmdType != "show" {
+			continue
+		}
+		valid = append(valid, field)
+	}
+	return valid
+}
+
+// ValidateFields : reject unknown or duplicated field names, listing the valid set
+func ValidateFields(cmdType string, fields []string) error {
+	valid := ValidFieldsFor(cmdType)
+	seen := map[string]bool{}
+	for _, field := range fields {
+		known := false
+		for _, name := range valid {
+			if field == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown field %q for %s, valid: %s", field, cmdType, strings.Join(valid, ", "))
+		}
+		if seen[field] {
+			return fmt.Errorf("the field %q is given more than once", field)
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+// fieldValue : the value of the field as displayed by the list, table and default outputs
+func fieldValue(card enpass.Card, field string) string {
+	switch field {
+	case "uuid":
+		return card.UUID
+	case "created":
+		return card.Created
+	case "updated":
+		return card.Updated
+	case "card_type":
+		return card.Type
+	case "title":
+		return card.Title
+	case "subtitle", "login":
+		return card.Subtitle
+	case "note":
+		return card.Note
+	case "category":
+		return card.Category
+	case "label":
+		return card.Label
+	case "last_used":
+		return card.LastUsed
+	case "sensitive":
+		return fmt.Sprintf("%v", card.Sensitive)
+	case "trashed":
+		return fmt.Sprintf("%v", card.IsTrashed())
+	case "icon":
+		return card.Icon
+	case "decrypted_value":
+		return card.DecryptedValue
+	}
+	return ""
+}
+
+// fieldKind : how the field is colorized, one of bool, number or string
+func fieldKind(field string) string {
+	switch field {
+	case "sensitive", "trashed":
+		return "bool"
+	case "created", "updated", "last_used":
+		return "number"
+	}
+	return "string"
+}
+
+// yamlFields : the selected fields of every card, in the requested order
+func yamlFields(cards []enpass.Card, fields []string) []yaml.MapSlice {
+	documents := []yaml.MapSlice{}
+	for _, cardItem := range cards {
+		document := yaml.MapSlice{}
+		for _, field := range fields {
+			var value interface{} = fieldValue(cardItem, field)
+			switch field {
+			case "sensitive":
+				value = cardItem.Sensitive
+			case "trashed":
+				value = cardItem.IsTrashed()
+			}
+			document = append(document, yaml.MapItem{Key: field, Value: value})
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// jsonFields : the selected fields of the JSON representation, in the requested order. schema_version is kept
+// for JSON Lines.
+func jsonFields(jsonCard JSONCard, fields []string) ([]byte, error) {
+	encoded, err := json.Marshal(jsonCard)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, err
+	}
+
+	keys := fields
+	if jsonCard.SchemaVersion > 0 {
+		keys = append([]string{"schema_version"}, fields...)
+	}
+
+	var out strings.Builder
+	out.WriteString("{")
+	for i, field := range keys {
+		source := field
+		if field == "login" {
+			source = "subtitle"
+		}
+		value, ok := values[source]
+		if !ok {
+			value = json.RawMessage("null")
+		}
+		if i > 0 {
+			out.WriteString(",")
+		}
+		key, _ := json.Marshal(field)
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
+	}
+	out.WriteString("}")
+
+	return []byte(out.String()), nil
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// DefaultFormat : the formatter used when neither a flag nor output_style selects one
+const DefaultFormat = "default"
+
+// noRecords : written by the human-readable formatters when there is nothing to render
+const noRecords = "No records found matching the specified criteria"
+
+// Options : the settings shared by every formatter
+type Options struct {
+	// CmdType is "list" or "show", only show renders decrypted values
+	CmdType string
+	// IncludeSecrets keeps the passwords, one-time codes and sensitive fields of the item formatters
+	IncludeSecrets bool
+	// Fields selects and orders the displayed fields, empty keeps the defaults of the formatter
+	Fields []string
+	// NoColor disables colorized output
+	NoColor bool
+	// Template renders each card instead of the named formatter when set, see ParseTemplate
+	Template *template.Template
+	// Trashed keeps the trashed cards
+	Trashed bool
+}
+
+// Formatter : renders cards to a writer
+type Formatter interface {
+	Format(w io.Writer, cards []enpass.Card, opts Options) error
+}
+
+// ItemFormatter : a formatter rendering whole items instead of cards, render it with RenderItems
+type ItemFormatter interface {
+	Formatter
+	FormatItems(w io.Writer, items []enpass.Item, opts Options) error
+}
+
+// FormatterFunc : adapts a function to the Formatter interface
+type FormatterFunc func(w io.Writer, cards []enpass.Card, opts Options) error
+
+func (f FormatterFunc) Format(w io.Writer, cards []enpass.Card, opts Options) error {
+	return f(w, cards, opts)
+}
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]Formatter{
+		DefaultFormat: FormatterFunc(formatDefault),
+		"csv":         csvFormatter{comma: ','},
+		"json":        FormatterFunc(formatJson),
+		"jsonl":       FormatterFunc(formatJsonl),
+		"list":        FormatterFunc(formatList),
+		"table":       FormatterFunc(formatTable),
+		"tsv":         csvFormatter{comma: '\t'},
+		"yaml":        FormatterFunc(formatYaml),
+	}
+)
+
+// Register : make a formatter available under name, replacing any formatter of the same name
+func Register(name string, formatter Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+	formatters[name] = formatter
+}
+
+// Lookup : return the formatter registered under name
+func Lookup(name string) (Formatter, error) {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	formatter, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q, valid: %s", name, strings.Join(namesLocked(), ", "))
+	}
+	return formatter, nil
+}
+
+// Names : the names of the registered formatters, sorted
+func Names() []string {
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render : prepare the cards and render them with the named formatter, or with opts.Template when set. The
+// cards of the caller are not modified.
+func Render(w io.Writer, name string, cards []enpass.Card, opts Options) error {
+	var formatter Formatter
+	if opts.Template != nil {
+		formatter = FormatterFunc(formatTemplate)
+	} else {
+		var err error
+		if formatter, err = Lookup(name); err != nil {
+			return err
+		}
+	}
+
+	return formatter.Format(w, PrepareCards(cards, opts), opts)
+}
+
+// RendersItems : report whether the named formatter renders whole items, load them with Vault.GetItemsByUUID
+// and render them with RenderItems
+func RendersItems(name string) bool {
+	formatter, err := Lookup(name)
+	if err != nil {
+		return false
+	}
+	_, ok := formatter.(ItemFormatter)
+	return ok
+}
+
+// RenderItems : render the items with the named item formatter. The trashed items are dropped unless opts.Trashed
+// is set.
+func RenderItems(w io.Writer, name string, items []enpass.Item, opts Options) error {
+	formatter, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	itemFormatter, ok := formatter.(ItemFormatter)
+	if !ok {
+		return fmt.Errorf("the %s format renders cards, use Render", name)
+	}
+
+	kept := []enpass.Item{}
+	for _, item := range items {
+		if item.Trashed && !opts.Trashed {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return itemFormatter.FormatItems(w, kept, opts)
+}
+
+// PrepareCards : drop the trashed cards unless opts.Trashed is set, clear the decrypted values unless the command
+// is show, and clear the keys
+func PrepareCards(cards []enpass.Card, opts Options) []enpass.Card {
+	prepared := []enpass.Card{}
+	for _, cardItem := range cards {
+		if cardItem.IsTrashed() && !opts.Trashed {
+			continue
+		}
+		if opts.CmdType != "show" {
+			cardItem.DecryptedValue = ""
+		}
+		cardItem.Key = []byte{}
+		prepared = append(prepared, cardItem)
+	}
+	return prepared
+}
+
+// writeNoRecords : the message of the human-readable formatters when the list is empty
+func writeNoRecords(w io.Writer) error {
+	_, err := fmt.Fprintln(w, noRecords)
+	return err
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// JSONSchemaVersion : bumped whenever a field of JSONCard is renamed, removed or changes meaning
+const JSONSchemaVersion = 1
+
+// JSONCard : the stable JSON representation of a card. Timestamps are RFC 3339 in UTC, or null when unknown.
+// decrypted_value is only present for show.
+type JSONCard struct {
+	SchemaVersion  int     `json:"schema_version,omitempty"`
+	UUID           string  `json:"uuid"`
+	Title          string  `json:"title"`
+	Subtitle       string  `json:"subtitle"`
+	Category       string  `json:"category"`
+	Type           string  `json:"card_type"`
+	Label          string  `json:"label"`
+	Note           string  `json:"note"`
+	Sensitive      bool    `json:"sensitive"`
+	Trashed        bool    `json:"trashed"`
+	Icon           string  `json:"icon"`
+	Created        *string `json:"created"`
+	Updated        *string `json:"updated"`
+	LastUsed       *string `json:"last_used"`
+	DecryptedValue *string `json:"decrypted_value,omitempty"`
+}
+
+// JSONDocument : the document written by --json
+type JSONDocument struct {
+	SchemaVersion int        `json:"schema_version"`
+	Items         []JSONCard `json:"items"`
+}
+
+// NewJSONCard : convert a card to its JSON representation
+func NewJSONCard(card enpass.Card, cmdType string) JSONCard {
+	jsonCard := JSONCard{
+		UUID:      card.UUID,
+		Title:     card.Title,
+		Subtitle:  card.Subtitle,
+		Category:  card.Category,
+		Type:      card.Type,
+		Label:     card.Label,
+		Note:      card.Note,
+		Sensitive: card.Sensitive,
+		Trashed:   card.IsTrashed(),
+		Icon:      card.Icon,
+		Created:   rfc3339(card.CreatedAt),
+		Updated:   rfc3339(card.UpdatedAt),
+		LastUsed:  rfc3339(card.LastUsedAt),
+	}
+	if cmdType == "show" {
+		value := card.DecryptedValue
+		jsonCard.DecryptedValue = &value
+	}
+
+	return jsonCard
+}
+
+func rfc3339(timestamp int64) *string {
+	if timestamp <= 0 {
+		return nil
+	}
+	formatted := time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+func formatJson(w io.Writer, cards []enpass.Card, opts Options) error {
+	var document interface{}
+	if len(opts.Fields) > 0 {
+		items := []json.RawMessage{}
+		for _, cardItem := range cards {
+			item, err := jsonFields(NewJSONCard(cardItem, opts.CmdType), opts.Fields)
+			if err != nil {
+				return fmt.Errorf("failed to parse the output to JSON, %s", err)
+			}
+			items = append(items, item)
+		}
+		document = struct {
+			SchemaVersion int               `json:"schema_version"`
+			Items         []json.RawMessage `json:"items"`
+		}{JSONSchemaVersion, items}
+	} else {
+		jsonDocument := JSONDocument{
+			SchemaVersion: JSONSchemaVersion,
+			Items:         []JSONCard{},
+		}
+		for _, cardItem := range cards {
+			jsonDocument.Items = append(jsonDocument.Items, NewJSONCard(cardItem, opts.CmdType))
+		}
+		document = jsonDocument
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to parse the output to JSON, %s", err)
+	}
+	return nil
+}
+
+// formatJsonl : one object per line, nothing at all when there are no cards
+func formatJsonl(w io.Writer, cards []enpass.Card, opts Options) error {
+	for _, cardItem := range cards {
+		jsonCard := NewJSONCard(cardItem, opts.CmdType)
+		jsonCard.SchemaVersion = JSONSchemaVersion
+		var (
+			line []byte
+			err  error
+		)
+		if len(opts.Fields) > 0 {
+			line, err = jsonFields(jsonCard, opts.Fields)
+		} else {
+			line, err = json.Marshal(jsonCard)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse the output to JSON, %s", err)
+		}
+		if _, err = fmt.Fprintln(w, string(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
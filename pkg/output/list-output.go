@@ -1,32 +1,41 @@
 package output
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 
 	"github.com/fatih/color"
 	"github.com/gdanko/enpass/globals"
 	"github.com/gdanko/enpass/pkg/enpass"
 )
 
-func doListOutput(cards []enpass.Card, cmdType string, flagNoColor bool) {
+func formatList(w io.Writer, cards []enpass.Card, opts Options) error {
+	if len(cards) <= 0 {
+		return writeNoRecords(w)
+	}
+
+	var buf bytes.Buffer
 	for i, cardItem := range cards {
-		if flagNoColor {
-			fmt.Printf("%s = %s\n", "           uuid", cardItem.UUID)
-			fmt.Printf("%s = %s\n", "        created", cardItem.Created)
-			fmt.Printf("%s = %s\n", "        updated", cardItem.Updated)
-			fmt.Printf("%s = %s\n", "      card_type", cardItem.Type)
-			fmt.Printf("%s = %s\n", "          title", cardItem.Title)
-			fmt.Printf("%s = %s\n", "          login", cardItem.Subtitle)
+		if len(opts.Fields) > 0 {
+			listFields(&buf, cardItem, opts.Fields, opts.NoColor)
+		} else if opts.NoColor {
+			fmt.Fprintf(&buf, "%s = %s\n", "           uuid", cardItem.UUID)
+			fmt.Fprintf(&buf, "%s = %s\n", "        created", cardItem.Created)
+			fmt.Fprintf(&buf, "%s = %s\n", "        updated", cardItem.Updated)
+			fmt.Fprintf(&buf, "%s = %s\n", "      card_type", cardItem.Type)
+			fmt.Fprintf(&buf, "%s = %s\n", "          title", cardItem.Title)
+			fmt.Fprintf(&buf, "%s = %s\n", "          login", cardItem.Subtitle)
 			if cardItem.Note != "" {
-				fmt.Printf("%s = %s\n", "           note", cardItem.Note)
+				fmt.Fprintf(&buf, "%s = %s\n", "           note", cardItem.Note)
 			}
-			fmt.Printf("%s = %s\n", "       category", cardItem.Category)
-			fmt.Printf("%s = %s\n", "          label", cardItem.Label)
-			fmt.Printf("%s = %s\n", "      last_used", cardItem.LastUsed)
-			fmt.Printf("%s = %v\n", "      sensitive", cardItem.Sensitive)
-			fmt.Printf("%s = %v\n", "           icon", cardItem.Icon)
-			if cmdType == "show" {
-				fmt.Printf("%s = %s: %s\n", "decrypted_value", cardItem.Type, cardItem.DecryptedValue)
+			fmt.Fprintf(&buf, "%s = %s\n", "       category", cardItem.Category)
+			fmt.Fprintf(&buf, "%s = %s\n", "          label", cardItem.Label)
+			fmt.Fprintf(&buf, "%s = %s\n", "      last_used", cardItem.LastUsed)
+			fmt.Fprintf(&buf, "%s = %v\n", "      sensitive", cardItem.Sensitive)
+			fmt.Fprintf(&buf, "%s = %v\n", "           icon", cardItem.Icon)
+			if opts.CmdType == "show" {
+				fmt.Fprintf(&buf, "%s = %s: %s\n", "decrypted_value", cardItem.Type, cardItem.DecryptedValue)
 			}
 		} else {
 			var (
@@ -35,26 +44,56 @@ func doListOutput(cards []enpass.Card, cmdType string, flagNoColor bool) {
 				numberColor = color.New(colorMap[globals.GetConfig().Colors.NumberColor]).SprintFunc()
 				stringColor = color.New(colorMap[globals.GetConfig().Colors.StringColor]).SprintFunc()
 			)
-			fmt.Printf("%s = %s\n", keyColor("           uuid"), stringColor(cardItem.UUID))
-			fmt.Printf("%s = %s\n", keyColor("        created"), numberColor(cardItem.Created))
-			fmt.Printf("%s = %s\n", keyColor("        updated"), numberColor(cardItem.Updated))
-			fmt.Printf("%s = %s\n", keyColor("      card_type"), stringColor(cardItem.Type))
-			fmt.Printf("%s = %s\n", keyColor("          title"), stringColor(cardItem.Title))
-			fmt.Printf("%s = %s\n", keyColor("       subtitle"), stringColor(cardItem.Subtitle))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("           uuid"), stringColor(cardItem.UUID))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("        created"), numberColor(cardItem.Created))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("        updated"), numberColor(cardItem.Updated))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("      card_type"), stringColor(cardItem.Type))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("          title"), stringColor(cardItem.Title))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("       subtitle"), stringColor(cardItem.Subtitle))
 			if cardItem.Note != "" {
-				fmt.Printf("%s = %s\n", keyColor("           note"), stringColor(cardItem.Note))
+				fmt.Fprintf(&buf, "%s = %s\n", keyColor("           note"), stringColor(cardItem.Note))
 			}
-			fmt.Printf("%s = %s\n", keyColor("       category"), stringColor(cardItem.Category))
-			fmt.Printf("%s = %s\n", keyColor("          label"), stringColor(cardItem.Label))
-			fmt.Printf("%s = %s\n", keyColor("      last_used"), numberColor(cardItem.LastUsed))
-			fmt.Printf("%s = %s\n", keyColor("      sensitive"), boolColor(cardItem.Sensitive))
-			fmt.Printf("%s = %s\n", keyColor("           icon"), stringColor(cardItem.Icon))
-			if cmdType == "show" {
-				fmt.Printf("%s = %s\n", keyColor("decrypted_value"), stringColor(cardItem.DecryptedValue))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("       category"), stringColor(cardItem.Category))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("          label"), stringColor(cardItem.Label))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("      last_used"), numberColor(cardItem.LastUsed))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("      sensitive"), boolColor(cardItem.Sensitive))
+			fmt.Fprintf(&buf, "%s = %s\n", keyColor("           icon"), stringColor(cardItem.Icon))
+			if opts.CmdType == "show" {
+				fmt.Fprintf(&buf, "%s = %s\n", keyColor("decrypted_value"), stringColor(cardItem.DecryptedValue))
 			}
 		}
 		if i < len(cards)-1 {
-			fmt.Println()
+			fmt.Fprintln(&buf)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// listFields : write the selected fields of the card, keys are right-aligned like the full list output
+func listFields(buf *bytes.Buffer, cardItem enpass.Card, fields []string, flagNoColor bool) {
+	var (
+		boolColor   = color.New(colorMap[globals.GetConfig().Colors.BoolColor]).SprintFunc()
+		keyColor    = color.New(colorMap[globals.GetConfig().Colors.KeyColor]).SprintFunc()
+		numberColor = color.New(colorMap[globals.GetConfig().Colors.NumberColor]).SprintFunc()
+		stringColor = color.New(colorMap[globals.GetConfig().Colors.StringColor]).SprintFunc()
+	)
+	for _, field := range fields {
+		key := fmt.Sprintf("%15s", field)
+		value := fieldValue(cardItem, field)
+		if flagNoColor {
+			fmt.Fprintf(buf, "%s = %s\n", key, value)
+			continue
+		}
+		switch fieldKind(field) {
+		case "bool":
+			value = boolColor(value)
+		case "number":
+			value = numberColor(value)
+		default:
+			value = stringColor(value)
 		}
+		fmt.Fprintf(buf, "%s = %s\n", keyColor(key), value)
 	}
 }
@@ -1,13 +1,9 @@
 package output
 
 import (
-	"fmt"
-	"os"
+	"sort"
 
 	"github.com/fatih/color"
-	"github.com/gdanko/enpass/globals"
-	"github.com/gdanko/enpass/pkg/enpass"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -31,54 +27,18 @@ var (
 	}
 )
 
-func GenerateOutput(logger *logrus.Logger, cmdType string, flagList, flagTable, flagTrashed, flagYaml, flagNoColor bool, cards *[]enpass.Card) {
-	if len(*cards) <= 0 {
-		fmt.Println("No records found matching the specified criteria")
-		os.Exit(0)
+// ColorNames : the color names usable in the colors section of the configuration
+func ColorNames() []string {
+	names := make([]string, 0, len(colorMap))
+	for name := range colorMap {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Loop through all of the cards and exclude trashed items unless we specify --trashed
-	cardsPruned := []enpass.Card{}
-	for _, cardItem := range *cards {
-		if cardItem.IsTrashed() {
-			if flagTrashed {
-				cardsPruned = append(cardsPruned, cardItem)
-			}
-		} else {
-			cardsPruned = append(cardsPruned, cardItem)
-		}
-	}
-
-	// If it's a list operation, DecryptedValue should be empty
-	for i := range cardsPruned {
-		if cmdType == "list" {
-			(cardsPruned)[i].DecryptedValue = ""
-		}
-	}
-
-	for i := range cardsPruned {
-		(cardsPruned)[i].Key = []byte{}
-	}
-
-	cards = &cardsPruned
-
-	if flagList {
-		doListOutput(*cards, cmdType, flagNoColor)
-	} else if flagTable {
-		doTableOutput(*cards, cmdType)
-	} else if flagYaml {
-		doYamlOutput(logger, *cards, flagNoColor)
-	} else {
-		outputStyle := globals.GetConfig().OutputStyle
-		switch outputStyle {
-		case "list":
-			doListOutput(*cards, cmdType, flagNoColor)
-		case "table":
-			doTableOutput(*cards, cmdType)
-		case "yaml":
-			doYamlOutput(logger, *cards, flagNoColor)
-		default:
-			doDefaultOutput(*cards, cmdType, flagNoColor)
-		}
-	}
+// IsValidColor : report whether name is a known color
+func IsValidColor(name string) bool {
+	_, ok := colorMap[name]
+	return ok
 }
@@ -2,18 +2,36 @@ package output
 
 import (
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/gdanko/enpass/pkg/enpass"
 	"github.com/markkurossi/tabulate"
 )
 
-func doTableOutput(cards []enpass.Card, cmdType string) {
+func formatTable(w io.Writer, cards []enpass.Card, opts Options) error {
+	if len(cards) <= 0 {
+		return writeNoRecords(w)
+	}
+
 	tab := tabulate.New(tabulate.Simple)
+	if len(opts.Fields) > 0 {
+		for _, field := range opts.Fields {
+			tab.Header(field).SetAlign(tabulate.ML)
+		}
+		for _, cardItem := range cards {
+			row := tab.Row()
+			for _, field := range opts.Fields {
+				row.Column(fieldValue(cardItem, field))
+			}
+		}
+		tab.Print(w)
+		return nil
+	}
+
 	tab.Header("title").SetAlign(tabulate.ML)
 	tab.Header("login").SetAlign(tabulate.ML)
 	tab.Header("category").SetAlign(tabulate.ML)
-	if cmdType == "show" {
+	if opts.CmdType == "show" {
 		tab.Header("decrypted").SetAlign(tabulate.ML)
 	}
 	for _, cardItem := range cards {
@@ -21,10 +39,11 @@ func doTableOutput(cards []enpass.Card, cmdType string) {
 		row.Column(cardItem.Title)
 		row.Column(cardItem.Subtitle)
 		row.Column(cardItem.Category)
-		if cmdType == "show" {
+		if opts.CmdType == "show" {
 			password := fmt.Sprintf("%s: %s", cardItem.Type, cardItem.DecryptedValue)
 			row.Column(password)
 		}
 	}
-	tab.Print(os.Stdout)
+	tab.Print(w)
+	return nil
 }
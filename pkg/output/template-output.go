@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+)
+
+// templateFuncs : the helpers available to --format templates
+var templateFuncs = template.FuncMap{
+	"pad":     padRight,
+	"padLeft": padLeft,
+	"trunc":   truncate,
+	"date":    formatDate,
+	"json":    jsonQuote,
+	"mask":    mask,
+}
+
+// ParseTemplate : parse a --format template. The escapes \t, \n and \\ are interpreted so the template can be
+// given in single quotes on the command line. A newline is appended when the template does not end with one.
+func ParseTemplate(text string) (*template.Template, error) {
+	text = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(text)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %s", err)
+	}
+	return tmpl, nil
+}
+
+func formatTemplate(w io.Writer, cards []enpass.Card, opts Options) error {
+	for _, cardItem := range cards {
+		if err := opts.Template.Execute(w, cardItem); err != nil {
+			return fmt.Errorf("failed to render the --format template: %s", err)
+		}
+	}
+	return nil
+}
+
+func padRight(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	if n := utf8.RuneCountInString(s); n < width {
+		s += strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func padLeft(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	if n := utf8.RuneCountInString(s); n < width {
+		s = strings.Repeat(" ", width-n) + s
+	}
+	return s
+}
+
+// truncate : shorten the value to width characters, the last three being "..." when it was cut
+func truncate(width int, value interface{}) string {
+	runes := []rune(fmt.Sprint(value))
+	if len(runes) <= width {
+		return string(runes)
+	}
+	if width <= 3 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+// formatDate : format a unix timestamp (.CreatedAt, .UpdatedAt, .LastUsedAt) with a Go layout, e.g. "2006-01-02"
+func formatDate(layout string, value interface{}) (string, error) {
+	var timestamp int64
+	switch v := value.(type) {
+	case int64:
+		timestamp = v
+	case int:
+		timestamp = int64(v)
+	case time.Time:
+		return v.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date expects a unix timestamp such as .UpdatedAt, got %T", value)
+	}
+	if timestamp <= 0 {
+		return "", nil
+	}
+	return time.Unix(timestamp, 0).Format(layout), nil
+}
+
+func jsonQuote(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// mask : hide a value without revealing its length
+func mask(value interface{}) string {
+	if fmt.Sprint(value) == "" {
+		return ""
+	}
+	return "********"
+}
@@ -0,0 +1,80 @@
+package picker
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+const (
+	keyCtrlC     = 3
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyBackspace = 8
+	keyDelete    = 127
+	keyEnter     = 13
+	keyNewline   = 10
+	keyEscape    = 27
+
+	minHeight = 8
+)
+
+var (
+	// ErrCancelled is returned when the user leaves the picker without selecting anything
+	ErrCancelled = errors.New("selection cancelled")
+)
+
+// Item : one selectable entry. Fields are searched and displayed, Preview is shown for the highlighted item.
+type Item struct {
+	Fields  []string
+	Preview []string
+}
+
+// IsTerminal : report whether the file is attached to a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Pick : run an incremental fuzzy finder over items on the controlling terminal and return the index of the selected item
+func Pick(items []Item, prompt string) (int, error) {
+	if len(items) <= 0 {
+		return -1, errors.New("nothing to select from")
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return -1, errors.Wrap(err, "could not open the terminal")
+	}
+	defer tty.Close()
+
+	oldState, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return -1, errors.Wrap(err, "could not put the terminal in raw mode")
+	}
+	defer term.Restore(int(tty.Fd()), oldState)
+
+	// Use the alternate screen so the picker leaves no trace behind
+	fmt.Fprint(tty, "\x1b[?1049h")
+	defer fmt.Fprint(tty, "\x1b[?1049l")
+
+	s := newState(items)
+	buf := make([]byte, 64)
+	for {
+		width, height := 80, 24
+		if w, h, err := term.GetSize(int(tty.Fd())); err == nil {
+			width, height = w, h
+		}
+		fmt.Fprint(tty, s.frame(prompt, width, height))
+
+		n, err := tty.Read(buf)
+		if err != nil {
+			return -1, errors.Wrap(err, "could not read from the terminal")
+		}
+		if index, done, err := s.handle(buf[:n]); done {
+			return index, err
+		}
+	}
+}
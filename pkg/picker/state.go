@@ -0,0 +1,153 @@
+package picker
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdanko/enpass/pkg/fuzzy"
+)
+
+type match struct {
+	index int
+	score int
+}
+
+// state : the query, the matches and the cursor of the picker, kept apart from the terminal
+type state struct {
+	items   []Item
+	query   []rune
+	matches []match
+	cursor  int
+	offset  int
+}
+
+func newState(items []Item) *state {
+	s := &state{items: items}
+	s.filter()
+	return s
+}
+
+// handle : apply the input read from the terminal. done is set once the picker ends, with the index of the
+// selected item or ErrCancelled.
+func (s *state) handle(input []byte) (index int, done bool, err error) {
+	if len(input) <= 0 {
+		return -1, false, nil
+	}
+
+	switch {
+	case bytes.Equal(input, []byte("\x1b[A")), bytes.Equal(input, []byte("\x1bOA")), len(input) == 1 && input[0] == keyCtrlP:
+		s.move(-1)
+	case bytes.Equal(input, []byte("\x1b[B")), bytes.Equal(input, []byte("\x1bOB")), len(input) == 1 && input[0] == keyCtrlN:
+		s.move(1)
+	case input[0] == keyEscape && len(input) > 1:
+		// Ignore the escape sequences we do not handle
+	case input[0] == keyEscape, input[0] == keyCtrlC:
+		return -1, true, ErrCancelled
+	case input[0] == keyEnter, input[0] == keyNewline:
+		if len(s.matches) > 0 {
+			return s.matches[s.cursor].index, true, nil
+		}
+	case input[0] == keyBackspace, input[0] == keyDelete:
+		if len(s.query) > 0 {
+			s.query = s.query[:len(s.query)-1]
+			s.filter()
+		}
+	case input[0] == keyCtrlU:
+		s.query = s.query[:0]
+		s.filter()
+	default:
+		for len(input) > 0 {
+			r, size := utf8.DecodeRune(input)
+			if r >= 32 && r != utf8.RuneError {
+				s.query = append(s.query, r)
+			}
+			input = input[size:]
+		}
+		s.filter()
+	}
+	return -1, false, nil
+}
+
+// filter : rank the items against the current query, best score first and original order for ties
+func (s *state) filter() {
+	query := string(s.query)
+	s.matches = s.matches[:0]
+	for i, item := range s.items {
+		if score, ok := fuzzy.Score(query, strings.Join(item.Fields, " "), false); ok {
+			s.matches = append(s.matches, match{index: i, score: score})
+		}
+	}
+	sort.SliceStable(s.matches, func(i, j int) bool {
+		return s.matches[i].score > s.matches[j].score
+	})
+	s.cursor = 0
+	s.offset = 0
+}
+
+// move : move the cursor by delta, it stays on the matches
+func (s *state) move(delta int) {
+	s.cursor += delta
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+	if s.cursor > len(s.matches)-1 {
+		s.cursor = max(len(s.matches)-1, 0)
+	}
+}
+
+// frame : the screen for a terminal of width by height, the count, the matches scrolled to keep the cursor in
+// view, the preview of the highlighted item and the prompt on the last line
+func (s *state) frame(prompt string, width, height int) string {
+	height = max(height, minHeight)
+
+	var (
+		preview   []string
+		out       strings.Builder
+		listLines = (height - 3) / 2
+	)
+	if len(s.matches) > 0 {
+		preview = s.items[s.matches[s.cursor].index].Preview
+	}
+
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	} else if s.cursor >= s.offset+listLines {
+		s.offset = s.cursor - listLines + 1
+	}
+
+	out.WriteString("\x1b[H\x1b[2J")
+	out.WriteString(line(width, fmt.Sprintf("  %d/%d", len(s.matches), len(s.items))))
+	for i := s.offset; i < s.offset+listLines; i++ {
+		if i >= len(s.matches) {
+			out.WriteString("\r\n")
+			continue
+		}
+		text := strings.Join(s.items[s.matches[i].index].Fields, "  ")
+		if i == s.cursor {
+			out.WriteString("\x1b[7m" + line(width, "> "+text) + "\x1b[0m")
+		} else {
+			out.WriteString(line(width, "  "+text))
+		}
+	}
+	out.WriteString(line(width, strings.Repeat("─", width-1)))
+	for i := 0; i < height-listLines-3 && i < len(preview); i++ {
+		out.WriteString(line(width, "  "+preview[i]))
+	}
+
+	// Leave the cursor at the end of the prompt on the last line
+	fmt.Fprintf(&out, "\x1b[%d;1H%s%s", height, prompt, string(s.query))
+	return out.String()
+}
+
+// line : truncate text to the terminal width and terminate it for raw mode
+func line(width int, text string) string {
+	// Stay one column short of the edge so the terminal never wraps on its own
+	runes := []rune(text)
+	if len(runes) > width-1 {
+		runes = runes[:width-1]
+	}
+	return string(runes) + "\x1b[K\r\n"
+}
@@ -0,0 +1,177 @@
+package picker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testItems = []Item{
+	{Fields: []string{"Mail", "me@example.com"}, Preview: []string{"mail preview"}},
+	{Fields: []string{"GitHub", "octocat"}, Preview: []string{"github preview"}},
+	{Fields: []string{"GitLab", "octocat"}},
+	{Fields: []string{"My Bank", "jdoe"}},
+}
+
+// order : the indexes of the matched items, best first
+func order(s *state) []int {
+	indexes := []int{}
+	for _, m := range s.matches {
+		indexes = append(indexes, m.index)
+	}
+	return indexes
+}
+
+// typeText : feed text to the state as the terminal would, one key at a time
+func typeText(s *state, text string) {
+	for _, r := range text {
+		s.handle([]byte(string(r)))
+	}
+}
+
+func TestStateFilter(t *testing.T) {
+	s := newState(testItems)
+	if got := order(s); !reflect.DeepEqual(got, []int{0, 1, 2, 3}) {
+		t.Errorf("an empty query keeps the original order, got %v", got)
+	}
+
+	// GitHub and GitLab score the same, they keep their original order
+	typeText(s, "git")
+	if got := order(s); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("git: got %v", got)
+	}
+	typeText(s, "l")
+	if got := order(s); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("gitl: got %v", got)
+	}
+
+	s.handle([]byte{keyBackspace})
+	if string(s.query) != "git" || len(s.matches) != 2 {
+		t.Errorf("backspace: query %q, %d matches", string(s.query), len(s.matches))
+	}
+	s.handle([]byte{keyCtrlU})
+	if len(s.query) != 0 || len(s.matches) != len(testItems) {
+		t.Errorf("ctrl-u: query %q, %d matches", string(s.query), len(s.matches))
+	}
+
+	typeText(s, "octo")
+	if got := order(s); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("the second field is not searched: %v", got)
+	}
+	s.handle([]byte{keyCtrlU})
+	typeText(s, "mb")
+	if got := order(s); got[0] != 3 {
+		t.Errorf("the word starts of My Bank do not rank first: %v", got)
+	}
+}
+
+func TestStateInput(t *testing.T) {
+	s := newState(testItems)
+	// Control characters are dropped, multibyte runes are kept whole
+	s.handle([]byte("é\x01x"))
+	if string(s.query) != "éx" {
+		t.Errorf("got the query %q", string(s.query))
+	}
+	s.handle([]byte("\x1b[C"))
+	if string(s.query) != "éx" {
+		t.Errorf("an escape sequence changed the query to %q", string(s.query))
+	}
+	if _, done, _ := s.handle(nil); done {
+		t.Error("an empty read ended the picker")
+	}
+}
+
+func TestStateCursor(t *testing.T) {
+	s := newState(testItems)
+	for _, step := range []struct {
+		input  string
+		cursor int
+	}{
+		{"\x1b[A", 0},
+		{"\x1b[B", 1},
+		{"\x0e", 2},
+		{"\x1bOB", 3},
+		{"\x1b[B", 3},
+		{"\x10", 2},
+		{"\x1bOA", 1},
+	} {
+		s.handle([]byte(step.input))
+		if s.cursor != step.cursor {
+			t.Errorf("%q: the cursor is at %d, want %d", step.input, s.cursor, step.cursor)
+		}
+	}
+
+	// Filtering puts the cursor back on the best match
+	typeText(s, "bank")
+	if s.cursor != 0 || s.offset != 0 {
+		t.Errorf("the cursor is at %d after filtering", s.cursor)
+	}
+	index, done, err := s.handle([]byte{keyEnter})
+	if !done || err != nil || index != 3 {
+		t.Errorf("enter selected %d, %v, %v", index, done, err)
+	}
+
+	typeText(s, "zzz")
+	s.handle([]byte("\x1b[B"))
+	if s.cursor != 0 {
+		t.Errorf("the cursor moved to %d without matches", s.cursor)
+	}
+	if _, done, _ := s.handle([]byte{keyNewline}); done {
+		t.Error("enter without matches ended the picker")
+	}
+}
+
+func TestStateCancel(t *testing.T) {
+	for _, key := range []byte{keyEscape, keyCtrlC} {
+		index, done, err := newState(testItems).handle([]byte{key})
+		if !done || err != ErrCancelled || index != -1 {
+			t.Errorf("%d: got %d, %v, %v", key, index, done, err)
+		}
+	}
+}
+
+func TestStateFrame(t *testing.T) {
+	items := []Item{}
+	for _, name := range []string{"a1", "a2", "a3", "a4", "a5", "a6"} {
+		items = append(items, Item{Fields: []string{name}, Preview: []string{"preview of " + name}})
+	}
+	s := newState(items)
+
+	// A 10 line terminal lists 3 matches
+	screen := s.frame("> ", 20, 10)
+	if !strings.Contains(screen, "  6/6") || !strings.Contains(screen, "\x1b[7m> a1") || !strings.Contains(screen, "preview of a1") {
+		t.Errorf("unexpected screen %q", screen)
+	}
+	if strings.Contains(screen, "a4") {
+		t.Errorf("more matches than fit were listed: %q", screen)
+	}
+
+	s.move(4)
+	screen = s.frame("> ", 20, 10)
+	if s.offset != 2 || !strings.Contains(screen, "\x1b[7m> a5") || strings.Contains(screen, "  a2") {
+		t.Errorf("the list did not scroll to the cursor, offset %d: %q", s.offset, screen)
+	}
+	s.move(-4)
+	s.frame("> ", 20, 10)
+	if s.offset != 0 {
+		t.Errorf("the list did not scroll back, offset %d", s.offset)
+	}
+
+	typeText(s, "a6")
+	if screen := s.frame("> ", 20, 10); !strings.HasSuffix(screen, "\x1b[10;1H> a6") {
+		t.Errorf("the prompt is not on the last line: %q", screen)
+	}
+	// A terminal smaller than the minimum is drawn at the minimum height
+	if screen := s.frame("> ", 20, 2); !strings.HasSuffix(screen, "\x1b[8;1H> a6") {
+		t.Errorf("the minimum height was not applied: %q", screen)
+	}
+}
+
+func TestLine(t *testing.T) {
+	if got := line(6, "abcdefgh"); got != "abcde\x1b[K\r\n" {
+		t.Errorf("got %q", got)
+	}
+	if got := line(6, "éé"); got != "éé\x1b[K\r\n" {
+		t.Errorf("got %q", got)
+	}
+}
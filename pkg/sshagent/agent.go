@@ -0,0 +1,174 @@
+package sshagent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// ErrReadOnly is returned for requests that would modify the keys served by the agent
+	ErrReadOnly = errors.New("the enpass agent is read-only, keys are managed in the vault")
+)
+
+// Agent : an ssh-agent serving private keys held in memory only. Keys are loaded once from the
+// vault and can never be added or removed by clients.
+type Agent struct {
+	logger  *logrus.Logger
+	keyring agent.ExtendedAgent
+	// confirm asks whether the key described may sign, nil signs without asking
+	confirm func(description string) (bool, error)
+	mu      sync.Mutex
+}
+
+// NewAgent : create an empty agent, set confirm to ask on the TTY before every signature
+func NewAgent(logger *logrus.Logger, confirm bool) *Agent {
+	a := &Agent{
+		logger:  logger,
+		keyring: agent.NewKeyring().(agent.ExtendedAgent),
+	}
+	if confirm {
+		a.confirm = confirmOnTTY
+	}
+	return a
+}
+
+// AddKey : parse a PEM/OpenSSH encoded private key and load it into the agent
+func (a *Agent) AddKey(pemBytes []byte, comment string) error {
+	privateKey, err := ssh.ParseRawPrivateKey(pemBytes)
+	if err != nil {
+		return errors.Wrap(err, "could not parse private key")
+	}
+
+	return a.keyring.Add(agent.AddedKey{
+		PrivateKey: privateKey,
+		Comment:    comment,
+	})
+}
+
+// Serve : accept connections on the listener until it is closed
+func (a *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return errors.Wrap(err, "could not accept agent connection")
+		}
+		a.logger.Debug("accepted agent connection")
+		go func() {
+			defer conn.Close()
+			if err := agent.ServeAgent(a, conn); err != nil && !errors.Is(err, io.EOF) {
+				a.logger.WithError(err).Debug("agent connection closed")
+			}
+		}()
+	}
+}
+
+// List : return the identities known to the agent
+func (a *Agent) List() ([]*agent.Key, error) {
+	return a.keyring.List()
+}
+
+// Sign : sign data with the given key, asking for confirmation first if enabled
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags : sign data with the given key and signature flags, asking for confirmation first if enabled
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if a.confirm != nil {
+		allowed, err := a.askConfirmation(key)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			a.logger.Infof("refused signature with %s", ssh.FingerprintSHA256(key))
+			return nil, errors.New("signature refused by the user")
+		}
+	}
+
+	a.logger.Debugf("signing with %s", ssh.FingerprintSHA256(key))
+	return a.keyring.SignWithFlags(key, data, flags)
+}
+
+// Signers : return signers for all the known keys
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return a.keyring.Signers()
+}
+
+// Add : refused, keys come from the vault
+func (a *Agent) Add(key agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove : refused, keys come from the vault
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+// RemoveAll : refused, keys come from the vault
+func (a *Agent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+// Lock : refused, the agent only lists keys and signs, stop it to stop serving the keys
+func (a *Agent) Lock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+// Unlock : refused, the agent cannot be locked
+func (a *Agent) Unlock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+// Extension : no extensions are supported
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// askConfirmation : ask whether the key may be used, one prompt at a time
+func (a *Agent) askConfirmation(key ssh.PublicKey) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	description := ssh.FingerprintSHA256(key)
+	if keys, err := a.keyring.List(); err == nil {
+		for _, k := range keys {
+			if string(k.Marshal()) == string(key.Marshal()) {
+				description = fmt.Sprintf("%s (%s)", k.Comment, description)
+				break
+			}
+		}
+	}
+
+	return a.confirm(description)
+}
+
+// confirmOnTTY : ask on the controlling terminal whether the key described may be used
+func confirmOnTTY(description string) (bool, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, errors.Wrap(err, "could not open the terminal to confirm the signature")
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Allow use of key %s? [y/N] ", description)
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return false, errors.Wrap(err, "could not read the confirmation")
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,122 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"github.com/gdanko/enpass/pkg/enpass"
+	"github.com/gdanko/enpass/pkg/enpass/enpasstest"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// newClient : an agent loaded with an ed25519 key read from an item of a fixture vault, and a client talking to
+// it over a pipe
+func newClient(t *testing.T) (*Agent, agent.ExtendedAgent, ssh.PublicKey) {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := enpasstest.New(t, enpasstest.Options{Items: []enpass.Item{{
+		UUID: enpasstest.ItemSSHKey, Title: "deploy key", Category: "computer",
+		Fields: []enpass.ItemField{{Label: "Private key", Type: "multiline", Value: string(pem.EncodeToMemory(block)), Sensitive: true}},
+	}}})
+	items, err := fixture.Open(t).GetItemsByUUID([]string{enpasstest.ItemSSHKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	a := NewAgent(logger, false)
+	if err := a.AddKey([]byte(items[0].Fields[0].Value), items[0].Title); err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := net.Pipe()
+	go agent.ServeAgent(a, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	publicKey, err := ssh.NewPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, agent.NewClient(client), publicKey
+}
+
+func TestAgentListSign(t *testing.T) {
+	_, client, publicKey := newClient(t)
+
+	keys, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Comment != "deploy key" || string(keys[0].Marshal()) != string(publicKey.Marshal()) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	data := []byte("session data")
+	signature, err := client.Sign(publicKey, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Verify(data, signature); err != nil {
+		t.Errorf("the signature does not verify: %s", err)
+	}
+}
+
+func TestAgentReadOnly(t *testing.T) {
+	_, client, publicKey := newClient(t)
+
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	if err := client.Add(agent.AddedKey{PrivateKey: other}); err == nil {
+		t.Error("a key was added")
+	}
+	if err := client.Remove(publicKey); err == nil {
+		t.Error("the key was removed")
+	}
+	if err := client.RemoveAll(); err == nil {
+		t.Error("the keys were removed")
+	}
+	if err := client.Lock([]byte("passphrase")); err == nil {
+		t.Error("the agent was locked")
+	}
+	if keys, err := client.List(); err != nil || len(keys) != 1 {
+		t.Errorf("the keys changed: %v, %v", keys, err)
+	}
+}
+
+func TestAgentConfirm(t *testing.T) {
+	a, client, publicKey := newClient(t)
+
+	asked := []string{}
+	allow := false
+	a.confirm = func(description string) (bool, error) {
+		asked = append(asked, description)
+		return allow, nil
+	}
+
+	if _, err := client.Sign(publicKey, []byte("data")); err == nil {
+		t.Error("the denied signature was made")
+	}
+	allow = true
+	if _, err := client.Sign(publicKey, []byte("data")); err != nil {
+		t.Errorf("the allowed signature failed: %s", err)
+	}
+	want := "deploy key (" + ssh.FingerprintSHA256(publicKey) + ")"
+	if len(asked) != 2 || asked[0] != want {
+		t.Errorf("asked %q, want %q twice", asked, want)
+	}
+}
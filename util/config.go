@@ -0,0 +1,311 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdanko/enpass/globals"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrConfigNotFound   = errors.New("does not exist")
+	ErrConfigUnreadable = errors.New("failed to read")
+	ErrConfigInvalid    = errors.New("failed to parse")
+)
+
+// ConfigError : a problem loading the config file, test the cause with errors.Is and the ErrConfig* values
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	switch {
+	case errors.Is(e.Err, ErrConfigNotFound):
+		return fmt.Sprintf("the config file %s does not exist", e.Path)
+	case errors.Is(e.Err, ErrConfigUnreadable):
+		return fmt.Sprintf("failed to read the config file %s: %s", e.Path, e.cause())
+	}
+	return fmt.Sprintf("failed to parse the config file %s: %s", e.Path, e.cause())
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// cause : the underlying error, without the ErrConfig* marker
+func (e *ConfigError) cause() error {
+	if joined, ok := e.Err.(interface{ Unwrap() []error }); ok && len(joined.Unwrap()) > 1 {
+		return joined.Unwrap()[1]
+	}
+	return e.Err
+}
+
+// ConfigPath : Return the config file to use. $ENPASS_CONFIG wins, then $XDG_CONFIG_HOME/enpass/config.yml
+// (~/.config/enpass/config.yml) and the legacy ~/.enpass.yml, whichever exists first. When neither exists the XDG
+// path is returned so new files are created there.
+func ConfigPath() string {
+	if path := os.Getenv("ENPASS_CONFIG"); path != "" {
+		return ExpandPath(path)
+	}
+
+	xdgConfigHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(globals.GetHomeDirectory(), ".config")
+	}
+	xdgPath := filepath.Join(ExpandPath(xdgConfigHome), "enpass", "config.yml")
+	legacyPath := filepath.Join(globals.GetHomeDirectory(), ".enpass.yml")
+
+	for _, path := range []string{xdgPath, legacyPath} {
+		if exists, _ := FileOrDirectoryExists(path); exists {
+			return path
+		}
+	}
+
+	return xdgPath
+}
+
+// UnknownConfigKeys : Return the keys of the config file that are not part of the configuration
+func UnknownConfigKeys(data []byte) []string {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil || len(document.Content) <= 0 {
+		return nil
+	}
+
+	unknown := []string{}
+	walkConfigNode(document.Content[0], reflect.TypeOf(globals.EnpassConfig{}), "", &unknown)
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+func walkConfigNode(node *yaml.Node, t reflect.Type, prefix string, unknown *[]string) {
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, ok := configField(t, key)
+			if !ok {
+				*unknown = append(*unknown, prefix+key)
+				continue
+			}
+			walkConfigNode(node.Content[i+1], field.Type, prefix+key+".", unknown)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkConfigNode(node.Content[i+1], t.Elem(), prefix+node.Content[i].Value+".", unknown)
+		}
+	}
+}
+
+// configField : find the struct field carrying the yaml key
+func configField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("yaml"), ",")[0] == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// EnvOverrideName : Return the environment variable overriding a dotted config key, e.g. colors.key_color
+// is overridden by ENPASS_COLORS_KEY_COLOR
+func EnvOverrideName(key string) string {
+	return "ENPASS_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ApplyEnvOverrides : Override every string, number and list key with its ENPASS_* environment variable when set.
+// Lists are comma-separated. Maps (profiles, searches) cannot be overridden. It returns the variables that were used.
+func ApplyEnvOverrides(enpassConfig *globals.EnpassConfig) []string {
+	applied := []string{}
+	for _, key := range ConfigKeys() {
+		name := EnvOverrideName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setConfigValue(reflect.ValueOf(enpassConfig).Elem(), strings.Split(key, "."), value); err == nil {
+			applied = append(applied, name)
+		}
+	}
+
+	return applied
+}
+
+// ConfigKeys : Return the dotted names of every string, number and list key outside of maps
+func ConfigKeys() []string {
+	keys := []string{}
+	collectConfigKeys(reflect.TypeOf(globals.EnpassConfig{}), "", &keys)
+	sort.Strings(keys)
+
+	return keys
+}
+
+func collectConfigKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			collectConfigKeys(field.Type, key+".", keys)
+		case reflect.String, reflect.Int, reflect.Slice:
+			*keys = append(*keys, key)
+		}
+	}
+}
+
+func setConfigValue(v reflect.Value, path []string, value string) error {
+	if v.Kind() != reflect.Struct || len(path) <= 0 {
+		return fmt.Errorf("%s is not a configuration value", strings.Join(path, "."))
+	}
+
+	field, ok := configField(v.Type(), path[0])
+	if !ok {
+		return fmt.Errorf("unknown key %s", path[0])
+	}
+	target := v.FieldByIndex(field.Index)
+
+	if len(path) > 1 {
+		return setConfigValue(target, path[1:], value)
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int:
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s must be a number", path[0])
+		}
+		target.SetInt(int64(number))
+	case reflect.Slice:
+		target.Set(reflect.ValueOf(SplitList(value)))
+	default:
+		return fmt.Errorf("%s cannot be set from a string", path[0])
+	}
+
+	return nil
+}
+
+// SplitList : Split a comma-separated list, ignoring blanks around the items and empty items
+func SplitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SetConfigKey : Set a dotted key in the YAML document, creating the parent mappings as needed. Comments and the
+// order of the other keys are preserved. Keys inside maps (profiles.work.vault_path) are allowed.
+func SetConfigKey(data []byte, key, value string) ([]byte, error) {
+	path := strings.Split(key, ".")
+	kind, err := configKeyKind(reflect.TypeOf(globals.EnpassConfig{}), path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, err
+		}
+	}
+	if len(document.Content) <= 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	node := document.Content[0]
+	for _, segment := range path[:len(path)-1] {
+		node = mappingChild(node, segment, true)
+	}
+
+	leaf := mappingChild(node, path[len(path)-1], false)
+	switch kind {
+	case reflect.Slice:
+		*leaf = yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range SplitList(value) {
+			leaf.Content = append(leaf.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+	case reflect.Int:
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q must be a number", key)
+		}
+		*leaf = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(number)}
+	default:
+		*leaf = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// configKeyKind : check that the dotted path names a string, number or list key and return its kind
+func configKeyKind(t reflect.Type, path []string) (reflect.Kind, error) {
+	full := strings.Join(path, ".")
+	for i := 0; i < len(path); i++ {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := configField(t, path[i])
+			if !ok {
+				return reflect.Invalid, fmt.Errorf("unknown key %q", full)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return reflect.Invalid, fmt.Errorf("unknown key %q", full)
+		}
+	}
+
+	if t.Kind() != reflect.String && t.Kind() != reflect.Int && t.Kind() != reflect.Slice {
+		return reflect.Invalid, fmt.Errorf("%q is a section, set one of its keys instead", full)
+	}
+
+	return t.Kind(), nil
+}
+
+// mappingChild : return the value node of key in the mapping, adding it when missing
+func mappingChild(node *yaml.Node, key string, mapping bool) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			child := node.Content[i+1]
+			if mapping && child.Kind != yaml.MappingNode {
+				*child = yaml.Node{Kind: yaml.MappingNode}
+			}
+			return child
+		}
+	}
+
+	child := &yaml.Node{Kind: yaml.ScalarNode}
+	if mapping {
+		child.Kind = yaml.MappingNode
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+
+	return child
+}
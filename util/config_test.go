@@ -0,0 +1,156 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdanko/enpass/globals"
+)
+
+func TestSetConfigKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "empty file",
+			key:   "vault_path",
+			value: "~/vault",
+			want:  "vault_path: ~/vault\n",
+		},
+		{
+			name:  "replace and keep comments and order",
+			data:  "# my vault\nvault_path: /old\noutput_style: table # default\n",
+			key:   "vault_path",
+			value: "/new",
+			want:  "# my vault\nvault_path: /new\noutput_style: table # default\n",
+		},
+		{
+			name:  "nested key creates the section",
+			data:  "vault_path: /v\n",
+			key:   "colors.key_color",
+			value: "red",
+			want:  "vault_path: /v\ncolors:\n  key_color: red\n",
+		},
+		{
+			name:  "list",
+			key:   "default_labels",
+			value: " Password, ,PIN ",
+			want:  "default_labels:\n  - Password\n  - PIN\n",
+		},
+		{
+			name:  "number",
+			key:   "backup.keep_daily",
+			value: " 10 ",
+			want:  "backup:\n  keep_daily: 10\n",
+		},
+		{
+			name:  "key inside a map",
+			data:  "profiles:\n  work:\n    keyfile: /k\n",
+			key:   "profiles.work.vault_path",
+			value: "/w",
+			want:  "profiles:\n  work:\n    keyfile: /k\n    vault_path: /w\n",
+		},
+		{
+			name:  "numbers stay strings for string keys",
+			key:   "vault_password",
+			value: "1234",
+			want:  "vault_password: \"1234\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		got, err := SetConfigKey([]byte(test.data), test.key, test.value)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\n got %q\nwant %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSetConfigKeyErrors(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		err   string
+	}{
+		{key: "nope", value: "x", err: `unknown key "nope"`},
+		{key: "colors.nope", value: "x", err: `unknown key "colors.nope"`},
+		{key: "vault_path.deeper", value: "x", err: `unknown key "vault_path.deeper"`},
+		{key: "colors", value: "x", err: `"colors" is a section, set one of its keys instead`},
+		{key: "profiles.work", value: "x", err: `"profiles.work" is a section, set one of its keys instead`},
+		{key: "backup.keep_last", value: "many", err: `"backup.keep_last" must be a number`},
+	}
+
+	for _, test := range tests {
+		_, err := SetConfigKey(nil, test.key, test.value)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("SetConfigKey(%q): got %v, want %q", test.key, err, test.err)
+		}
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	data := strings.Join([]string{
+		"vault_path: /v",
+		"vault_pasword: typo",
+		"colors:",
+		"  key_color: red",
+		"  keycolor: red",
+		"profiles:",
+		"  work:",
+		"    vault_path: /w",
+		"    bogus: 1",
+		"searches:",
+		"  mine:",
+		"    query: title:x",
+		"    querry: title:x",
+		"fields:",
+		"  login: [Username]",
+		"ssh_agent:",
+		"  socket: /s",
+	}, "\n")
+
+	want := []string{"colors.keycolor", "profiles.work.bogus", "searches.mine.querry", "vault_pasword"}
+	if got := UnknownConfigKeys([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := UnknownConfigKeys([]byte(": not yaml: [")); got != nil {
+		t.Errorf("invalid YAML: got %v, want nothing", got)
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("ENPASS_VAULT_PATH", "/from/env")
+	t.Setenv("ENPASS_COLORS_KEY_COLOR", "red")
+	t.Setenv("ENPASS_DEFAULT_LABELS", "Password, PIN")
+	t.Setenv("ENPASS_BACKUP_KEEP_LAST", "3")
+	t.Setenv("ENPASS_BACKUP_KEEP_DAILY", "lots")
+	t.Setenv("ENPASS_PROFILES", "ignored")
+
+	config := globals.EnpassConfig{VaultPath: "/from/file", OutputStyle: "table", Backup: globals.Backup{KeepDaily: 7}}
+	applied := ApplyEnvOverrides(&config)
+
+	wantApplied := []string{"ENPASS_BACKUP_KEEP_LAST", "ENPASS_COLORS_KEY_COLOR", "ENPASS_DEFAULT_LABELS", "ENPASS_VAULT_PATH"}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("applied %v, want %v", applied, wantApplied)
+	}
+	want := globals.EnpassConfig{
+		VaultPath:     "/from/env",
+		OutputStyle:   "table",
+		DefaultLabels: []string{"Password", "PIN"},
+		Colors:        globals.Colors{KeyColor: "red"},
+		Backup:        globals.Backup{KeepDaily: 7, KeepLast: 3},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v\nwant %+v", config, want)
+	}
+}
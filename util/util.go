@@ -1,8 +1,8 @@
 package util
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -75,22 +75,80 @@ func ExpandPath(path string) (expanded string) {
 	return expanded
 }
 
-// ParseConfig : Read the config file and return it as an EnpassConfig object
+// ParseConfig : Read the config file and return it as an EnpassConfig object. Keys that are not part of the
+// configuration are ignored here, see UnknownConfigKeys.
 func ParseConfig(path string) (enpassConfig globals.EnpassConfig, err error) {
 	expanded := ExpandPath(path)
-	exists, err := FileOrDirectoryExists(ExpandPath(expanded))
+	exists, err := FileOrDirectoryExists(expanded)
 	if !exists && err != nil {
-		return globals.EnpassConfig{}, fmt.Errorf("the config file %s does not exist", expanded)
+		return globals.EnpassConfig{}, &ConfigError{Path: expanded, Err: ErrConfigNotFound}
 	}
 
-	data, err := ioutil.ReadFile(expanded)
+	data, err := os.ReadFile(expanded)
 	if err != nil {
-		return globals.EnpassConfig{}, fmt.Errorf("failed to read the config file %s: %s", expanded, err)
+		return globals.EnpassConfig{}, &ConfigError{Path: expanded, Err: errors.Join(ErrConfigUnreadable, err)}
 	}
 
 	err = yaml.Unmarshal(data, &enpassConfig)
 	if err != nil {
-		return globals.EnpassConfig{}, fmt.Errorf("failed to parse the config file %s: %s", expanded, err)
+		return globals.EnpassConfig{}, &ConfigError{Path: expanded, Err: errors.Join(ErrConfigInvalid, err)}
+	}
+
+	return enpassConfig, nil
+}
+
+// ApplyProfile : Merge the named profile over the top-level configuration. Colors are merged one by one, the
+// credential settings (vault_password, password_command, password_env) replace each other as a group.
+func ApplyProfile(enpassConfig globals.EnpassConfig, name string) (globals.EnpassConfig, error) {
+	profile, ok := enpassConfig.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(enpassConfig.Profiles))
+		for profileName := range enpassConfig.Profiles {
+			names = append(names, profileName)
+		}
+		sort.Strings(names)
+		if len(names) <= 0 {
+			return enpassConfig, fmt.Errorf("the profile %q does not exist, no profiles are defined", name)
+		}
+		return enpassConfig, fmt.Errorf("the profile %q does not exist, defined profiles: %s", name, strings.Join(names, ", "))
+	}
+
+	if profile.VaultPath != "" {
+		enpassConfig.VaultPath = profile.VaultPath
+	}
+	if profile.KeyFile != "" {
+		enpassConfig.KeyFile = profile.KeyFile
+	}
+	if profile.VaultPassword != "" || profile.PasswordCommand != "" || profile.PasswordEnv != "" {
+		enpassConfig.VaultPassword = profile.VaultPassword
+		enpassConfig.PasswordCommand = profile.PasswordCommand
+		enpassConfig.PasswordEnv = profile.PasswordEnv
+	}
+	if len(profile.DefaultLabels) > 0 {
+		enpassConfig.DefaultLabels = profile.DefaultLabels
+	}
+	if len(profile.OrderBy) > 0 {
+		enpassConfig.OrderBy = profile.OrderBy
+	}
+	if profile.OutputStyle != "" {
+		enpassConfig.OutputStyle = profile.OutputStyle
+	}
+
+	for _, color := range []struct {
+		profile string
+		config  *string
+	}{
+		{profile.Colors.AliasColor, &enpassConfig.Colors.AliasColor},
+		{profile.Colors.AnchorColor, &enpassConfig.Colors.AnchorColor},
+		{profile.Colors.BoolColor, &enpassConfig.Colors.BoolColor},
+		{profile.Colors.KeyColor, &enpassConfig.Colors.KeyColor},
+		{profile.Colors.NullColor, &enpassConfig.Colors.NullColor},
+		{profile.Colors.NumberColor, &enpassConfig.Colors.NumberColor},
+		{profile.Colors.StringColor, &enpassConfig.Colors.StringColor},
+	} {
+		if color.profile != "" {
+			*color.config = color.profile
+		}
 	}
 
 	return enpassConfig, nil